@@ -0,0 +1,82 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	// DpuNodePairPeerReachable is true while the daemon on the other side of
+	// the pair is reachable and authenticated on the OPI channel.
+	DpuNodePairPeerReachable = "PeerReachable"
+)
+
+// DpuNodePairSpec identifies a host node and the node running on the DPU
+// plugged into it. Host and DPU are in separate clusters, each of which has
+// its own DpuNodePair named after its local node.
+type DpuNodePairSpec struct {
+	// HostNodeName is the name of the node in the host cluster
+	HostNodeName string `json:"hostNodeName,omitempty"`
+	// DpuNodeName is the name of the node in the DPU cluster
+	DpuNodeName string `json:"dpuNodeName,omitempty"`
+}
+
+// DpuNodePairStatus defines the observed state of DpuNodePair
+type DpuNodePairStatus struct {
+	// SerialNumber of the DPU card as reported by the VSP
+	SerialNumber string `json:"serialNumber,omitempty"`
+	// PciAddress of the DPU card on the host as reported by the VSP
+	PciAddress string `json:"pciAddress,omitempty"`
+	// Endpoint is the address of the OPI channel served by the DPU daemon
+	Endpoint string `json:"endpoint,omitempty"`
+	// LastPeerContact is when the daemon on the other side was last seen
+	LastPeerContact *metav1.Time `json:"lastPeerContact,omitempty"`
+	// Conditions of the pair, see DpuNodePairPeerReachable
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=dnp
+//+kubebuilder:printcolumn:name="Host",type=string,JSONPath=`.spec.hostNodeName`
+//+kubebuilder:printcolumn:name="DPU",type=string,JSONPath=`.spec.dpuNodeName`
+//+kubebuilder:printcolumn:name="Serial",type=string,JSONPath=`.status.serialNumber`
+//+kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.endpoint`
+//+kubebuilder:printcolumn:name="Reachable",type=string,JSONPath=`.status.conditions[?(@.type=="PeerReachable")].status`
+
+// DpuNodePair is the Schema for the dpunodepairs API
+type DpuNodePair struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   DpuNodePairSpec   `json:"spec,omitempty"`
+	Status DpuNodePairStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// DpuNodePairList contains a list of DpuNodePair
+type DpuNodePairList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []DpuNodePair `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&DpuNodePair{}, &DpuNodePairList{})
+}
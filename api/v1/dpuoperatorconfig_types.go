@@ -31,6 +31,11 @@ type DpuOperatorConfigSpec struct {
 
 	// Set log level of the operator. Edit dpuoperatorconfig_types.go to remove/update
 	LogLevel int `json:"logLevel,omitempty"`
+
+	// NetworkFunctionSubnets are the subnets the addresses of the network
+	// function interfaces on the host are allocated from, at most one IPv4
+	// and one IPv6 subnet for dual-stack. Defaults to 10.56.217.0/24
+	NetworkFunctionSubnets []string `json:"networkFunctionSubnets,omitempty"`
 }
 
 // DpuOperatorConfigStatus defines the observed state of DpuOperatorConfig
@@ -0,0 +1,191 @@
+// cniharness runs the DPU CNI server on its own, without the daemon, so the
+// dpu-cni shim can be exercised on a development machine. The server answers
+// the shim on the same socket as the daemon does, with one of these handler
+// sets:
+//
+//	mock-vsp   bridge ports are created on an in-process mock VSP, no
+//	           devices are touched
+//	sriov      VFs are moved into the pod as on a host, without bridge ports
+//	networkfn  interfaces are moved into the pod as for a network function
+//	           on a DPU, without network functions
+//
+// Flags:
+//
+//	-handlers      handler set to run, see above (default mock-vsp)
+//	-root          directory the socket and cache paths are relative to
+//	               (default /, which is where the shim looks for the socket)
+//	-result-cache  cache the results of ADD like the daemon does
+//	-timeout       time after which a request is answered with "try again
+//	               later" (default 2m)
+//	-audit-log     file to record every request in
+//
+// For example, with the harness running as root:
+//
+//	CNI_COMMAND=ADD CNI_CONTAINERID=c1 CNI_NETNS=/var/run/netns/c1 \
+//	CNI_IFNAME=net1 CNI_PATH=/opt/cni/bin \
+//	CNI_ARGS="K8S_POD_NAMESPACE=default;K8S_POD_NAME=p1" \
+//	dpu-cni < netconf.json
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cniserver"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/networkfn"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriov"
+	mockvsp "github.com/openshift/dpu-operator/internal/daemon/vendor-specific-plugins/mock-vsp"
+	"github.com/openshift/dpu-operator/internal/utils"
+	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
+	"k8s.io/klog/v2"
+)
+
+type handlerFunc = func(req *cnitypes.PodRequest) (*cni100.Result, error)
+
+// handlers are the CNI handlers of a handler set, nil ones are left out.
+type handlers struct {
+	add    handlerFunc
+	del    handlerFunc
+	check  handlerFunc
+	status handlerFunc
+	gc     handlerFunc
+}
+
+// bridgePortName names the bridge port of an attachment on the mock VSP.
+func bridgePortName(req *cnitypes.PodRequest) string {
+	return fmt.Sprintf("%s-%s", req.ContainerId, req.IfName)
+}
+
+func mockVspHandlers() handlers {
+	var vsp opi.BridgePortServiceServer = mockvsp.NewMockVsp()
+	return handlers{
+		add: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			_, err := vsp.CreateBridgePort(req.Ctx, &opi.CreateBridgePortRequest{
+				BridgePort: &opi.BridgePort{Name: bridgePortName(req)},
+			})
+			if err != nil {
+				return nil, err
+			}
+			return &cni100.Result{
+				CNIVersion: req.CNIConf.CNIVersion,
+				Interfaces: []*cni100.Interface{{Name: req.IfName, Sandbox: req.Netns}},
+			}, nil
+		},
+		del: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			_, err := vsp.DeleteBridgePort(req.Ctx, &opi.DeleteBridgePortRequest{Name: bridgePortName(req)})
+			return nil, err
+		},
+		check: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			_, err := vsp.GetBridgePort(req.Ctx, &opi.GetBridgePortRequest{Name: bridgePortName(req)})
+			return nil, err
+		},
+	}
+}
+
+func sriovHandlers() handlers {
+	sm := sriov.NewSriovManager()
+	return handlers{
+		add: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return sm.CmdAdd(req)
+		},
+		del: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return nil, sm.CmdDel(req)
+		},
+		check: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return nil, sm.CmdCheck(req)
+		},
+		gc: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			stale, err := sm.StaleAttachments(req)
+			if err != nil {
+				return nil, err
+			}
+			var errs []error
+			for _, r := range stale {
+				if err := sm.CmdDel(r); err != nil {
+					errs = append(errs, err)
+				}
+			}
+			if err := sm.CmdGC(req); err != nil {
+				errs = append(errs, err)
+			}
+			return nil, errors.Join(errs...)
+		},
+	}
+}
+
+func networkfnHandlers() handlers {
+	return handlers{
+		add: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return networkfn.CmdAdd(req)
+		},
+		del: func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return nil, networkfn.CmdDel(req)
+		},
+	}
+}
+
+func main() {
+	var (
+		handlerSet  string
+		rootDir     string
+		resultCache bool
+		timeout     time.Duration
+		auditLog    string
+	)
+	flag.StringVar(&handlerSet, "handlers", "mock-vsp", "Handlers of the CNI requests: mock-vsp, sriov or networkfn")
+	flag.StringVar(&rootDir, "root", "/", "Directory the socket and cache paths are relative to, the shim only finds the socket with /")
+	flag.BoolVar(&resultCache, "result-cache", false, "Cache the results of ADD like the daemon does")
+	flag.DurationVar(&timeout, "timeout", 2*time.Minute, "Time after which a request is answered with try again later")
+	flag.StringVar(&auditLog, "audit-log", "", "File to record every CNI request in")
+	klog.InitFlags(nil)
+	flag.Parse()
+
+	var h handlers
+	switch handlerSet {
+	case "mock-vsp":
+		h = mockVspHandlers()
+	case "sriov":
+		h = sriovHandlers()
+	case "networkfn":
+		h = networkfnHandlers()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown handlers %q\n", handlerSet)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	pathManager := utils.NewPathManager(rootDir)
+	options := []func(*cniserver.Server){
+		cniserver.WithPathManager(*pathManager),
+		cniserver.WithRequestTimeout(timeout),
+	}
+	if resultCache {
+		options = append(options, cniserver.WithResultCacheDir(pathManager.CNIResultCacheDir()))
+	}
+	if auditLog != "" {
+		w := cniserver.NewAuditLogFile(auditLog, 100, 5)
+		defer w.Close()
+		options = append(options, cniserver.WithAuditLog(w))
+	}
+	if h.check != nil {
+		options = append(options, cniserver.WithCheckHandler(h.check))
+	}
+	if h.status != nil {
+		options = append(options, cniserver.WithStatusHandler(h.status))
+	}
+	if h.gc != nil {
+		options = append(options, cniserver.WithGCHandler(h.gc))
+	}
+
+	klog.Infof("Running the CNI server with the %s handlers", handlerSet)
+	server := cniserver.NewCNIServer(h.add, h.del, options...)
+	if err := server.ListenAndServe(); err != nil {
+		klog.Errorf("CNI server failed: %v", err)
+		os.Exit(1)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 
+	configv1 "github.com/openshift/dpu-operator/api/v1"
 	daemon "github.com/openshift/dpu-operator/internal/daemon"
 	"go.uber.org/zap/zapcore"
 
@@ -16,7 +17,9 @@ import (
 
 func main() {
 	var mode string
+	var cniAuditLog string
 	flag.StringVar(&mode, "mode", "", "Mode for the daemon, can be either host or dpu")
+	flag.StringVar(&cniAuditLog, "cni-audit-log", "", "File to record every CNI request in, rotated at 100MB. Requests are only logged if empty")
 	opts := zap.Options{
 		Development: true,
 		Level:       zapcore.DebugLevel,
@@ -26,6 +29,7 @@ func main() {
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
 	v1.AddToScheme(scheme.Scheme)
+	configv1.AddToScheme(scheme.Scheme)
 	log := ctrl.Log.WithName("Daemon Init")
 	log.Info("Daemon init")
 	config := ctrl.GetConfigOrDie()
@@ -40,7 +44,7 @@ func main() {
 
 	vspImages := plugin.CreateVspImagesMap(true, log)
 
-	d := daemon.NewDaemon(mode, client, scheme.Scheme, vspImages, config)
+	d := daemon.NewDaemon(mode, client, scheme.Scheme, vspImages, config, daemon.WithCNIAuditLogFile(cniAuditLog))
 	if err := d.Run(); err != nil {
 		log.Error(err, "Failed to run daemon")
 		panic(err)
@@ -19,7 +19,10 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	LifeCycleService_Init_FullMethodName = "/Vendor.LifeCycleService/Init"
+	LifeCycleService_Init_FullMethodName                = "/Vendor.LifeCycleService/Init"
+	LifeCycleService_GetIdentity_FullMethodName         = "/Vendor.LifeCycleService/GetIdentity"
+	LifeCycleService_GetHostCapabilities_FullMethodName = "/Vendor.LifeCycleService/GetHostCapabilities"
+	LifeCycleService_GetLinkLimits_FullMethodName       = "/Vendor.LifeCycleService/GetLinkLimits"
 )
 
 // LifeCycleServiceClient is the client API for LifeCycleService service.
@@ -27,6 +30,15 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type LifeCycleServiceClient interface {
 	Init(ctx context.Context, in *InitRequest, opts ...grpc.CallOption) (*IpPort, error)
+	// GetIdentity returns the identity of the DPU card managed by the VSP so
+	// that the host node can be paired with the node running on the DPU.
+	GetIdentity(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DpuIdentity, error)
+	// GetHostCapabilities returns the VF settings that the host may change
+	// through the PF with netlink.
+	GetHostCapabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*HostCapabilities, error)
+	// GetLinkLimits returns the limits of the data path for the interfaces
+	// that the CNI configures in pods.
+	GetLinkLimits(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*LinkLimits, error)
 }
 
 type lifeCycleServiceClient struct {
@@ -46,11 +58,47 @@ func (c *lifeCycleServiceClient) Init(ctx context.Context, in *InitRequest, opts
 	return out, nil
 }
 
+func (c *lifeCycleServiceClient) GetIdentity(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*DpuIdentity, error) {
+	out := new(DpuIdentity)
+	err := c.cc.Invoke(ctx, LifeCycleService_GetIdentity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lifeCycleServiceClient) GetHostCapabilities(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*HostCapabilities, error) {
+	out := new(HostCapabilities)
+	err := c.cc.Invoke(ctx, LifeCycleService_GetHostCapabilities_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lifeCycleServiceClient) GetLinkLimits(ctx context.Context, in *Empty, opts ...grpc.CallOption) (*LinkLimits, error) {
+	out := new(LinkLimits)
+	err := c.cc.Invoke(ctx, LifeCycleService_GetLinkLimits_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // LifeCycleServiceServer is the server API for LifeCycleService service.
 // All implementations must embed UnimplementedLifeCycleServiceServer
 // for forward compatibility
 type LifeCycleServiceServer interface {
 	Init(context.Context, *InitRequest) (*IpPort, error)
+	// GetIdentity returns the identity of the DPU card managed by the VSP so
+	// that the host node can be paired with the node running on the DPU.
+	GetIdentity(context.Context, *Empty) (*DpuIdentity, error)
+	// GetHostCapabilities returns the VF settings that the host may change
+	// through the PF with netlink.
+	GetHostCapabilities(context.Context, *Empty) (*HostCapabilities, error)
+	// GetLinkLimits returns the limits of the data path for the interfaces
+	// that the CNI configures in pods.
+	GetLinkLimits(context.Context, *Empty) (*LinkLimits, error)
 	mustEmbedUnimplementedLifeCycleServiceServer()
 }
 
@@ -61,6 +109,15 @@ type UnimplementedLifeCycleServiceServer struct {
 func (UnimplementedLifeCycleServiceServer) Init(context.Context, *InitRequest) (*IpPort, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Init not implemented")
 }
+func (UnimplementedLifeCycleServiceServer) GetIdentity(context.Context, *Empty) (*DpuIdentity, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetIdentity not implemented")
+}
+func (UnimplementedLifeCycleServiceServer) GetHostCapabilities(context.Context, *Empty) (*HostCapabilities, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHostCapabilities not implemented")
+}
+func (UnimplementedLifeCycleServiceServer) GetLinkLimits(context.Context, *Empty) (*LinkLimits, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkLimits not implemented")
+}
 func (UnimplementedLifeCycleServiceServer) mustEmbedUnimplementedLifeCycleServiceServer() {}
 
 // UnsafeLifeCycleServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -92,6 +149,60 @@ func _LifeCycleService_Init_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
+func _LifeCycleService_GetIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LifeCycleServiceServer).GetIdentity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LifeCycleService_GetIdentity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LifeCycleServiceServer).GetIdentity(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LifeCycleService_GetHostCapabilities_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LifeCycleServiceServer).GetHostCapabilities(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LifeCycleService_GetHostCapabilities_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LifeCycleServiceServer).GetHostCapabilities(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _LifeCycleService_GetLinkLimits_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LifeCycleServiceServer).GetLinkLimits(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: LifeCycleService_GetLinkLimits_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LifeCycleServiceServer).GetLinkLimits(ctx, req.(*Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // LifeCycleService_ServiceDesc is the grpc.ServiceDesc for LifeCycleService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -103,6 +214,18 @@ var LifeCycleService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Init",
 			Handler:    _LifeCycleService_Init_Handler,
 		},
+		{
+			MethodName: "GetIdentity",
+			Handler:    _LifeCycleService_GetIdentity_Handler,
+		},
+		{
+			MethodName: "GetHostCapabilities",
+			Handler:    _LifeCycleService_GetHostCapabilities_Handler,
+		},
+		{
+			MethodName: "GetLinkLimits",
+			Handler:    _LifeCycleService_GetLinkLimits_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
@@ -111,6 +234,7 @@ var LifeCycleService_ServiceDesc = grpc.ServiceDesc{
 const (
 	NetworkFunctionService_CreateNetworkFunction_FullMethodName = "/Vendor.NetworkFunctionService/CreateNetworkFunction"
 	NetworkFunctionService_DeleteNetworkFunction_FullMethodName = "/Vendor.NetworkFunctionService/DeleteNetworkFunction"
+	NetworkFunctionService_GetNetworkFunction_FullMethodName    = "/Vendor.NetworkFunctionService/GetNetworkFunction"
 )
 
 // NetworkFunctionServiceClient is the client API for NetworkFunctionService service.
@@ -119,6 +243,9 @@ const (
 type NetworkFunctionServiceClient interface {
 	CreateNetworkFunction(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*Empty, error)
 	DeleteNetworkFunction(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*Empty, error)
+	// GetNetworkFunction confirms that the network function between the
+	// input and output is in place, it fails with NotFound if it is not.
+	GetNetworkFunction(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type networkFunctionServiceClient struct {
@@ -147,12 +274,24 @@ func (c *networkFunctionServiceClient) DeleteNetworkFunction(ctx context.Context
 	return out, nil
 }
 
+func (c *networkFunctionServiceClient) GetNetworkFunction(ctx context.Context, in *NFRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, NetworkFunctionService_GetNetworkFunction_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // NetworkFunctionServiceServer is the server API for NetworkFunctionService service.
 // All implementations must embed UnimplementedNetworkFunctionServiceServer
 // for forward compatibility
 type NetworkFunctionServiceServer interface {
 	CreateNetworkFunction(context.Context, *NFRequest) (*Empty, error)
 	DeleteNetworkFunction(context.Context, *NFRequest) (*Empty, error)
+	// GetNetworkFunction confirms that the network function between the
+	// input and output is in place, it fails with NotFound if it is not.
+	GetNetworkFunction(context.Context, *NFRequest) (*Empty, error)
 	mustEmbedUnimplementedNetworkFunctionServiceServer()
 }
 
@@ -166,6 +305,9 @@ func (UnimplementedNetworkFunctionServiceServer) CreateNetworkFunction(context.C
 func (UnimplementedNetworkFunctionServiceServer) DeleteNetworkFunction(context.Context, *NFRequest) (*Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteNetworkFunction not implemented")
 }
+func (UnimplementedNetworkFunctionServiceServer) GetNetworkFunction(context.Context, *NFRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNetworkFunction not implemented")
+}
 func (UnimplementedNetworkFunctionServiceServer) mustEmbedUnimplementedNetworkFunctionServiceServer() {
 }
 
@@ -216,6 +358,24 @@ func _NetworkFunctionService_DeleteNetworkFunction_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _NetworkFunctionService_GetNetworkFunction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NFRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(NetworkFunctionServiceServer).GetNetworkFunction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: NetworkFunctionService_GetNetworkFunction_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(NetworkFunctionServiceServer).GetNetworkFunction(ctx, req.(*NFRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // NetworkFunctionService_ServiceDesc is the grpc.ServiceDesc for NetworkFunctionService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -231,6 +391,101 @@ var NetworkFunctionService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteNetworkFunction",
 			Handler:    _NetworkFunctionService_DeleteNetworkFunction_Handler,
 		},
+		{
+			MethodName: "GetNetworkFunction",
+			Handler:    _NetworkFunctionService_GetNetworkFunction_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api.proto",
+}
+
+const (
+	BridgePortPolicyService_SetBridgePortPolicy_FullMethodName = "/Vendor.BridgePortPolicyService/SetBridgePortPolicy"
+)
+
+// BridgePortPolicyServiceClient is the client API for BridgePortPolicyService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type BridgePortPolicyServiceClient interface {
+	SetBridgePortPolicy(ctx context.Context, in *BridgePortPolicy, opts ...grpc.CallOption) (*Empty, error)
+}
+
+type bridgePortPolicyServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBridgePortPolicyServiceClient(cc grpc.ClientConnInterface) BridgePortPolicyServiceClient {
+	return &bridgePortPolicyServiceClient{cc}
+}
+
+func (c *bridgePortPolicyServiceClient) SetBridgePortPolicy(ctx context.Context, in *BridgePortPolicy, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, BridgePortPolicyService_SetBridgePortPolicy_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BridgePortPolicyServiceServer is the server API for BridgePortPolicyService service.
+// All implementations must embed UnimplementedBridgePortPolicyServiceServer
+// for forward compatibility
+type BridgePortPolicyServiceServer interface {
+	SetBridgePortPolicy(context.Context, *BridgePortPolicy) (*Empty, error)
+	mustEmbedUnimplementedBridgePortPolicyServiceServer()
+}
+
+// UnimplementedBridgePortPolicyServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedBridgePortPolicyServiceServer struct {
+}
+
+func (UnimplementedBridgePortPolicyServiceServer) SetBridgePortPolicy(context.Context, *BridgePortPolicy) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetBridgePortPolicy not implemented")
+}
+func (UnimplementedBridgePortPolicyServiceServer) mustEmbedUnimplementedBridgePortPolicyServiceServer() {
+}
+
+// UnsafeBridgePortPolicyServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to BridgePortPolicyServiceServer will
+// result in compilation errors.
+type UnsafeBridgePortPolicyServiceServer interface {
+	mustEmbedUnimplementedBridgePortPolicyServiceServer()
+}
+
+func RegisterBridgePortPolicyServiceServer(s grpc.ServiceRegistrar, srv BridgePortPolicyServiceServer) {
+	s.RegisterService(&BridgePortPolicyService_ServiceDesc, srv)
+}
+
+func _BridgePortPolicyService_SetBridgePortPolicy_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BridgePortPolicy)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BridgePortPolicyServiceServer).SetBridgePortPolicy(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BridgePortPolicyService_SetBridgePortPolicy_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BridgePortPolicyServiceServer).SetBridgePortPolicy(ctx, req.(*BridgePortPolicy))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// BridgePortPolicyService_ServiceDesc is the grpc.ServiceDesc for BridgePortPolicyService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var BridgePortPolicyService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Vendor.BridgePortPolicyService",
+	HandlerType: (*BridgePortPolicyServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SetBridgePortPolicy",
+			Handler:    _BridgePortPolicyService_SetBridgePortPolicy_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "api.proto",
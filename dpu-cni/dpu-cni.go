@@ -22,10 +22,14 @@ func main() {
 
 	p := cni.NewCNIPlugin()
 	c.Action = func(ctx *cli.Context) error {
-		skel.PluginMain(
-			p.CmdAdd,
-			p.CmdCheck,
-			p.CmdDel,
+		skel.PluginMainFuncs(
+			skel.CNIFuncs{
+				Add:    p.CmdAdd,
+				Del:    p.CmdDel,
+				Check:  p.CmdCheck,
+				GC:     p.CmdGC,
+				Status: p.CmdStatus,
+			},
 			version.All,
 			bv.BuildString(cniName))
 		return nil
@@ -3,10 +3,13 @@ package cni
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/containernetworking/cni/pkg/skel"
 	"github.com/containernetworking/cni/pkg/types"
@@ -41,7 +44,8 @@ func (p *Plugin) PostRequest(args *skel.CmdArgs) (*cnitypes.Response, string, er
 	var body []byte
 	body, err = p.doCNI("http://dummy/cni", cniRequest)
 	if err != nil {
-		return nil, conf.CNIVersion, fmt.Errorf("%s: StdinData: %s", err.Error(), string(args.StdinData))
+		cnilogging.Error("CNI request failed", "error", err, "args.StdinData", string(args.StdinData))
+		return nil, conf.CNIVersion, err
 	}
 
 	response := &cnitypes.Response{}
@@ -72,7 +76,8 @@ func (p *Plugin) doCNI(url string, req interface{}) ([]byte, error) {
 
 	resp, err := client.Post(url, "application/json", bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to send CNI request: %v", err)
+		// The daemon is not running yet or restarting
+		return nil, types.NewError(types.ErrTryAgainLater, "failed to send CNI request to the DPU daemon", err.Error())
 	}
 	defer resp.Body.Close()
 
@@ -81,8 +86,13 @@ func (p *Plugin) doCNI(url string, req interface{}) ([]byte, error) {
 		return nil, fmt.Errorf("failed to read CNI result: %v", err)
 	}
 
-	if resp.StatusCode != 200 {
-		return nil, fmt.Errorf("CNI request failed with status %v: '%s'", resp.StatusCode, string(body))
+	if resp.StatusCode != http.StatusOK {
+		// The server answers failed requests with the CNI error to return
+		cniErr := &types.Error{}
+		if err := json.Unmarshal(body, cniErr); err == nil && cniErr.Msg != "" {
+			return nil, cniErr
+		}
+		return nil, types.NewError(types.ErrInternal, fmt.Sprintf("CNI request failed with status %v", resp.StatusCode), string(body))
 	}
 
 	return body, nil
@@ -111,12 +121,49 @@ func (p *Plugin) CmdAdd(args *skel.CmdArgs) error {
 
 	resp, cniVersion, err := p.PostRequest(args)
 	if err != nil {
-		return fmt.Errorf("failed to post request for cmdAdd: %v", err)
+		return err
+	}
+
+	if resp.DeviceInfo != nil {
+		// The pod works without its device-info, so do not fail the ADD
+		if err := saveDeviceInfo(args.StdinData, resp.DeviceInfo); err != nil {
+			cnilogging.Error("Failed to save the device-info", "error", err)
+		}
 	}
 
 	return types.PrintResult(resp.Result, cniVersion)
 }
 
+// deviceInfoFile returns the path Multus reads the device-info of the
+// attachment from, or "" if it did not ask for one
+func deviceInfoFile(stdinData []byte) string {
+	n := &cnitypes.NetConf{}
+	if err := json.Unmarshal(stdinData, n); err != nil {
+		return ""
+	}
+	return n.RuntimeConfig.CNIDeviceInfoFile
+}
+
+// saveDeviceInfo writes the device-info to the file of the CNIDeviceInfoFile
+// capability, from which Multus adds it to the network-status annotation
+func saveDeviceInfo(stdinData []byte, info *cnitypes.DeviceInfo) error {
+	path := deviceInfoFile(stdinData)
+	if path == "" {
+		return nil
+	}
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode the device-info: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create the device-info directory of %s: %v", path, err)
+	}
+	if err := os.WriteFile(path, data, 0444); err != nil {
+		return fmt.Errorf("failed to write the device-info file %s: %v", path, err)
+	}
+	return nil
+}
+
 func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 	if err := SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
 		return err
@@ -128,12 +175,71 @@ func (p *Plugin) CmdDel(args *skel.CmdArgs) error {
 
 	_, _, err := p.PostRequest(args)
 	if err != nil {
-		return fmt.Errorf("failed to post request for cmdAdd: %v", err)
+		return err
+	}
+
+	if path := deviceInfoFile(args.StdinData); path != "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			cnilogging.Error("Failed to remove the device-info", "error", err, "path", path)
+		}
 	}
 
 	return nil
 }
 
-func (p *Plugin) CmdCheck(_ *skel.CmdArgs) error {
+func (p *Plugin) CmdCheck(args *skel.CmdArgs) error {
+	if err := SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
+		return err
+	}
+
+	cnilogging.Info("function called",
+		"func", "cmdCheck",
+		"args.Path", args.Path, "args.StdinData", string(args.StdinData), "args.Args", args.Args)
+
+	_, _, err := p.PostRequest(args)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (p *Plugin) CmdStatus(args *skel.CmdArgs) error {
+	if err := SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
+		return err
+	}
+
+	cnilogging.Info("function called",
+		"func", "cmdStatus",
+		"args.Path", args.Path, "args.StdinData", string(args.StdinData))
+
+	_, _, err := p.PostRequest(args)
+	if err != nil {
+		// Errors other than the daemon reporting it is not available mean
+		// it cannot take ADD requests either
+		var cniErr *types.Error
+		if errors.As(err, &cniErr) && cniErr.Code == cnitypes.ErrPluginNotAvailable {
+			return cniErr
+		}
+		return types.NewError(cnitypes.ErrPluginNotAvailable, "DPU daemon is not ready", err.Error())
+	}
+
+	return nil
+}
+
+func (p *Plugin) CmdGC(args *skel.CmdArgs) error {
+	if err := SetLogging(args.StdinData, args.ContainerID, args.Netns, args.IfName); err != nil {
+		return err
+	}
+
+	cnilogging.Info("function called",
+		"func", "cmdGC",
+		"args.Path", args.Path, "args.StdinData", string(args.StdinData))
+
+	_, _, err := p.PostRequest(args)
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
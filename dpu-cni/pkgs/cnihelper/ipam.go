@@ -0,0 +1,70 @@
+package cnihelper
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/invoke"
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+)
+
+// ipamArgs builds the arguments for delegating command to an IPAM plugin
+// from the request itself. Unlike ipam.ExecAdd and ipam.ExecDel this does not
+// read the CNI_* variables from the process environment, which is shared by
+// all requests the daemon handles concurrently.
+func ipamArgs(req *cnitypes.PodRequest, command string) *invoke.Args {
+	return &invoke.Args{
+		Command:       command,
+		ContainerID:   req.ContainerId,
+		NetNS:         req.Netns,
+		IfName:        req.IfName,
+		PluginArgsStr: req.CNIReq.Env["CNI_ARGS"],
+		Path:          req.Path,
+	}
+}
+
+func findIpamPlugin(req *cnitypes.PodRequest, plugin string) (string, error) {
+	pluginPath, err := invoke.FindInPath(plugin, filepath.SplitList(req.Path))
+	if err != nil {
+		return "", fmt.Errorf("failed to find IPAM plugin %q: %v", plugin, err)
+	}
+	return pluginPath, nil
+}
+
+// IpamExecAdd runs the ADD command of the IPAM plugin for req.
+func IpamExecAdd(req *cnitypes.PodRequest, plugin string) (types.Result, error) {
+	pluginPath, err := findIpamPlugin(req, plugin)
+	if err != nil {
+		return nil, err
+	}
+	return invoke.ExecPluginWithResult(req.Ctx, pluginPath, req.CNIReq.Config, ipamArgs(req, cnitypes.CNIAdd), nil)
+}
+
+// IpamExecDel runs the DEL command of the IPAM plugin for req.
+func IpamExecDel(req *cnitypes.PodRequest, plugin string) error {
+	pluginPath, err := findIpamPlugin(req, plugin)
+	if err != nil {
+		return err
+	}
+	return invoke.ExecPluginWithoutResult(req.Ctx, pluginPath, req.CNIReq.Config, ipamArgs(req, cnitypes.CNIDel), nil)
+}
+
+// IpamExecCheck runs the CHECK command of the IPAM plugin for req.
+func IpamExecCheck(req *cnitypes.PodRequest, plugin string) error {
+	pluginPath, err := findIpamPlugin(req, plugin)
+	if err != nil {
+		return err
+	}
+	return invoke.ExecPluginWithoutResult(req.Ctx, pluginPath, req.CNIReq.Config, ipamArgs(req, cnitypes.CNICheck), nil)
+}
+
+// IpamExecGC runs the GC command of the IPAM plugin for req, the valid
+// attachments are part of the configuration.
+func IpamExecGC(req *cnitypes.PodRequest, plugin string) error {
+	pluginPath, err := findIpamPlugin(req, plugin)
+	if err != nil {
+		return err
+	}
+	return invoke.ExecPluginWithoutResult(req.Ctx, pluginPath, req.CNIReq.Config, ipamArgs(req, cnitypes.CNIGC), nil)
+}
@@ -0,0 +1,98 @@
+package cniserver
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"gopkg.in/natefinch/lumberjack.v2"
+	"k8s.io/klog/v2"
+)
+
+// auditRecord describes a CNI request and how it ended.
+type auditRecord struct {
+	Time         time.Time `json:"time"`
+	RequestID    string    `json:"requestID"`
+	Command      string    `json:"command"`
+	PodNamespace string    `json:"podNamespace,omitempty"`
+	PodName      string    `json:"podName,omitempty"`
+	PodUID       string    `json:"podUID,omitempty"`
+	ContainerID  string    `json:"containerID,omitempty"`
+	Netns        string    `json:"netns,omitempty"`
+	IfName       string    `json:"ifName,omitempty"`
+	Network      string    `json:"network,omitempty"`
+	DeviceID     string    `json:"deviceID,omitempty"`
+	Duration     string    `json:"duration"`
+	Result       string    `json:"result"`
+	ErrorCode    uint      `json:"errorCode,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// auditLog records every request handled by the server in the log and, if
+// w is set, as a line of JSON in w. A nil auditLog only logs.
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (a *auditLog) record(req *cnitypes.PodRequest, err error) {
+	r := auditRecord{
+		Time:         time.Now(),
+		RequestID:    req.ID,
+		Command:      req.Command,
+		PodNamespace: req.PodNamespace,
+		PodName:      req.PodName,
+		PodUID:       req.PodUID,
+		ContainerID:  req.ContainerId,
+		Netns:        req.Netns,
+		IfName:       req.IfName,
+		Network:      req.NetName,
+		Duration:     time.Since(req.Timestamp).String(),
+		Result:       "success",
+	}
+	if req.CNIConf != nil {
+		r.DeviceID = req.CNIConf.DeviceID
+	}
+	if err != nil {
+		r.Result = "error"
+		r.Error = err.Error()
+		var cniErr *types.Error
+		if errors.As(err, &cniErr) {
+			r.ErrorCode = cniErr.Code
+		}
+	}
+
+	klog.InfoS("CNI request done", "requestID", r.RequestID, "command", r.Command,
+		"pod", r.PodNamespace+"/"+r.PodName, "netns", r.Netns, "ifName", r.IfName,
+		"deviceID", r.DeviceID, "duration", r.Duration, "result", r.Result, "error", r.Error)
+
+	if a == nil || a.w == nil {
+		return
+	}
+	data, mErr := json.Marshal(r)
+	if mErr != nil {
+		klog.Errorf("Failed to serialize audit record: %v", mErr)
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, wErr := a.w.Write(append(data, '\n')); wErr != nil {
+		klog.Errorf("Failed to write audit record: %v", wErr)
+	}
+}
+
+// NewAuditLogFile returns a writer for WithAuditLog appending to path, which
+// is rotated once it reaches maxSizeMB megabytes. maxBackups rotated files
+// are kept.
+func NewAuditLogFile(path string, maxSizeMB int, maxBackups int) io.WriteCloser {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    maxSizeMB,
+		MaxBackups: maxBackups,
+		Compress:   true,
+	}
+}
@@ -3,6 +3,7 @@ package cniserver
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
@@ -11,27 +12,45 @@ import (
 	"strings"
 	"time"
 
+	"github.com/containernetworking/cni/pkg/types"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/gorilla/mux"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnihelper"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
-	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriov"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovtypes"
 	"github.com/openshift/dpu-operator/internal/utils"
+	"k8s.io/apimachinery/pkg/util/uuid"
 	"k8s.io/klog/v2"
 )
 
-// This server implementations is only temporary for testing when the DPU Daemon
-// code has not been implemented.
-
 type processRequestFunc func(request *cnitypes.PodRequest) (*cni100.Result, error)
 
 type Server struct {
 	http.Server
 	cniCmdAddHandler processRequestFunc
 	cniCmdDelHandler processRequestFunc
-	pathManager      utils.PathManager
+	// cniCmdCheckHandler is optional, CHECK succeeds without it
+	cniCmdCheckHandler processRequestFunc
+	// cniCmdStatusHandler is optional, STATUS succeeds without it since the
+	// server answering is all the runtime can be told about
+	cniCmdStatusHandler processRequestFunc
+	// cniCmdGCHandler is optional, GC does nothing without it
+	cniCmdGCHandler processRequestFunc
+	pathManager     utils.PathManager
+	// podLocks serializes the requests for the same pod sandbox, requests
+	// for different pods are handled concurrently.
+	podLocks utils.KeyedMutex
+	// results is optional, without it every request reaches the handlers
+	results *resultCache
+	// requestTimeout bounds how long the runtime waits for a request
+	requestTimeout time.Duration
+	// audit receives a record of every request, in addition to the log
+	audit *auditLog
 }
 
+// defaultRequestTimeout matches the Kubelet default CRI operation timeout
+const defaultRequestTimeout = 2 * time.Minute
+
 // Listen creates a listener to a unix socket located in `socketPath`
 func (s *Server) Listen() (net.Listener, error) {
 	err := s.pathManager.EnsureSocketDirExists(s.pathManager.CNIServerPath())
@@ -50,31 +69,6 @@ func (s *Server) Listen() (net.Listener, error) {
 	return listener, nil
 }
 
-func processRequest(request *cnitypes.Request) (*cni100.Result, error) {
-	// FIXME: Do actual work here.
-	klog.Infof("DEBUG: %v", request)
-
-	req, err := cniRequestToPodRequest(request)
-	if err != nil {
-		return nil, err
-	}
-	defer req.Cancel()
-	defer cniRequestEnvCleanup()
-
-	var res *cni100.Result = nil
-	sm := sriov.NewSriovManager()
-	if req.Command == cnitypes.CNIAdd {
-		res, err = sm.CmdAdd(req)
-	} else if req.Command == cnitypes.CNIDel {
-		err = sm.CmdDel(req)
-	}
-	if err != nil {
-		return nil, err
-	}
-
-	return res, nil
-}
-
 // Split the "CNI_ARGS" environment variable's value into a map.  CNI_ARGS
 // contains arbitrary key/value pairs separated by ';' and is for runtime or
 // plugin specific uses.  Kubernetes passes the pod namespace and name in
@@ -96,44 +90,37 @@ func gatherCNIArgs(env map[string]string) (map[string]string, error) {
 	return mapArgs, nil
 }
 
-// cniRequestSetEnv sets the CNI environment variables. This is needed when delegating IPAM plugins.
-// Please see vendor/github.com/containernetworking/cni/pkg/invoke/delegate.go:delegateCommon()
-func cniRequestSetEnv(req *cnitypes.PodRequest) {
-	os.Setenv("CNI_COMMAND", req.Command)
-	os.Setenv("CNI_CONTAINERID", req.ContainerId)
-	os.Setenv("CNI_NETNS", req.Netns)
-	os.Setenv("CNI_IFNAME", req.IfName)
-	os.Setenv("CNI_PATH", req.Path)
-}
-
-// cniRequestEnvCleanup cleans up the CNI environment variables once delegating IPAM plugins is done.
-func cniRequestEnvCleanup() {
-	os.Unsetenv("CNI_COMMAND")
-	os.Unsetenv("CNI_CONTAINERID")
-	os.Unsetenv("CNI_NETNS")
-	os.Unsetenv("CNI_IFNAME")
-	os.Unsetenv("CNI_PATH")
-}
-
-// cniRequestToPodRequest
-func cniRequestToPodRequest(cr *cnitypes.Request) (*cnitypes.PodRequest, error) {
+// cniRequestToPodRequest parses cr into a request that times out after
+// timeout.
+func cniRequestToPodRequest(cr *cnitypes.Request, timeout time.Duration) (*cnitypes.PodRequest, error) {
 	cmd, ok := cr.Env["CNI_COMMAND"]
 	if !ok {
-		return nil, fmt.Errorf("missing CNI_COMMAND")
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing CNI_COMMAND", "")
 	}
 
 	req := &cnitypes.PodRequest{
 		Command: cmd,
 	}
 
+	req.Path, ok = cr.Env["CNI_PATH"]
+	if !ok {
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing CNI_PATH", "")
+	}
+
+	// STATUS and GC are not about a single attachment, the runtime only
+	// passes the network configuration
+	if cmd == cnitypes.CNIStatus || cmd == cnitypes.CNIGC {
+		return finishPodRequest(req, cr, timeout)
+	}
+
 	req.ContainerId, ok = cr.Env["CNI_CONTAINERID"]
 	if !ok {
-		return nil, fmt.Errorf("missing CNI_CONTAINERID")
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing CNI_CONTAINERID", "")
 	}
 
 	req.Netns, ok = cr.Env["CNI_NETNS"]
 	if !ok {
-		return nil, fmt.Errorf("missing CNI_NETNS")
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing CNI_NETNS", "")
 	}
 
 	req.IfName, ok = cr.Env["CNI_IFNAME"]
@@ -141,25 +128,18 @@ func cniRequestToPodRequest(cr *cnitypes.Request) (*cnitypes.PodRequest, error)
 		req.IfName = "eth0"
 	}
 
-	req.Path, ok = cr.Env["CNI_PATH"]
-	if !ok {
-		return nil, fmt.Errorf("missing CNI_PATH")
-	}
-
 	cniArgs, err := gatherCNIArgs(cr.Env)
 	if err != nil {
-		return nil, err
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, err.Error(), "")
 	}
 
-	cniRequestSetEnv(req)
-
 	req.PodNamespace, ok = cniArgs["K8S_POD_NAMESPACE"]
 	if !ok {
-		return nil, fmt.Errorf("missing K8S_POD_NAMESPACE")
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing K8S_POD_NAMESPACE in CNI_ARGS", "")
 	}
 	req.PodName, ok = cniArgs["K8S_POD_NAME"]
 	if !ok {
-		return nil, fmt.Errorf("missing K8S_POD_NAME")
+		return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "missing K8S_POD_NAME in CNI_ARGS", "")
 	}
 
 	// UID may not be passed by all runtimes yet. Will be passed
@@ -171,44 +151,44 @@ func cniRequestToPodRequest(cr *cnitypes.Request) (*cnitypes.PodRequest, error)
 	// containerd 1.5: https://github.com/containerd/containerd/pull/5643
 	req.PodUID = cniArgs["K8S_POD_UID"]
 
+	return finishPodRequest(req, cr, timeout)
+}
+
+// finishPodRequest adds the network configuration of cr to req.
+func finishPodRequest(req *cnitypes.PodRequest, cr *cnitypes.Request, timeout time.Duration) (*cnitypes.PodRequest, error) {
 	conf, err := cnihelper.ReadCNIConfig(cr.Config)
 	if err != nil {
-		return nil, fmt.Errorf("broken stdin args")
+		return nil, types.NewError(types.ErrDecodingFailure, "broken stdin args", err.Error())
 	}
 
 	req.NetName = conf.Name
 
-	if conf.DeviceID != "" {
-		// FIXME: Some DeviceIDs are formated differently between CNIs
-		// for instance the sriov CNI uses PCI address from the sriov device plugin
-		// and the nf CNI uses interface names from our internal device plugin
-		/*
-			if sriovtypes.IsPCIDeviceName(conf.DeviceID) {
-				// DeviceID is a PCI address
-			} else if sriovtypes.IsAuxDeviceName(conf.DeviceID) {
-				// DeviceID is an Auxiliary device name - <driver_name>.<kind_of_a_type>.<id>
-				chunks := strings.Split(conf.DeviceID, ".")
-				if chunks[1] != "sf" {
-					return nil, fmt.Errorf("only SF auxiliary devices are supported")
-				}
-			} else {
-				return nil, fmt.Errorf("expected PCI or Auxiliary device name, got - %s", conf.DeviceID)
-			}
-		*/
+	// DeviceIDs are PCI addresses of VFs from the sriov device plugin, names
+	// of interfaces from our internal device plugin or auxiliary device names
+	// (<driver_name>.<kind_of_a_type>.<id>), of which only SFs are supported
+	if sriovtypes.IsAuxDeviceName(conf.DeviceID) && !sriovtypes.IsSFDeviceName(conf.DeviceID) {
+		return nil, types.NewError(types.ErrInvalidNetworkConfig, "only SF auxiliary devices are supported", conf.DeviceID)
 	}
 
 	req.CNIConf = conf
-	req.DeviceInfo = cr.DeviceInfo
+	req.DeviceInfo = cnitypes.DeviceInfo{DeviceInfo: cr.DeviceInfo}
 	req.CNIReq = cr
 	req.Timestamp = time.Now()
-	// Match the Kubelet default CRI operation timeout of 2m
-	req.Ctx, req.Cancel = context.WithTimeout(context.Background(), 2*time.Minute)
-
-	fmt.Printf("%+v\n", req)
+	req.Ctx, req.Cancel = context.WithTimeout(context.Background(), timeout)
 	return req, nil
 }
 
-// handleCNIRequest will take the CNI request and delegate (TODO) work.
+// handlerResult is what a handler returned for a request.
+type handlerResult struct {
+	result *cni100.Result
+	// deviceInfo is what the add handler set in the request, if anything
+	deviceInfo *cnitypes.DeviceInfo
+	err        error
+}
+
+// handleCNIRequest parses the CNI request and passes it to the handler of its
+// command. The request is answered once the handler returns or its context
+// expires, whichever comes first.
 func (s *Server) handleCNIRequest(r *http.Request) ([]byte, error) {
 	var cniRq cnitypes.Request
 	b, err := io.ReadAll(r.Body)
@@ -216,47 +196,241 @@ func (s *Server) handleCNIRequest(r *http.Request) ([]byte, error) {
 		return nil, err
 	}
 	if err := json.Unmarshal(b, &cniRq); err != nil {
-		return nil, err
+		return nil, types.NewError(types.ErrDecodingFailure, "failed to parse CNI request", err.Error())
 	}
 
-	req, err := cniRequestToPodRequest(&cniRq)
+	req, err := cniRequestToPodRequest(&cniRq, s.requestTimeout)
 	if err != nil {
+		klog.ErrorS(err, "Invalid CNI request", "command", cniRq.Env["CNI_COMMAND"])
 		return nil, err
 	}
 	defer req.Cancel()
+	req.ID = string(uuid.NewUUID())
+	klog.InfoS("CNI request", "requestID", req.ID, "command", req.Command,
+		"pod", req.PodNamespace+"/"+req.PodName, "netns", req.Netns, "ifName", req.IfName)
+
+	// The handler keeps the pod locked until it returns, even if the request
+	// was answered because it timed out
+	done := make(chan handlerResult, 1)
+	go func() {
+		if req.ContainerId != "" {
+			unlock := s.podLocks.Lock(req.ContainerId)
+			defer unlock()
+		}
+		result, err := s.dispatch(req)
+		res := handlerResult{result: result, err: err}
+		if req.Command == cnitypes.CNIAdd && req.DeviceInfo.Type != "" {
+			res.deviceInfo = &req.DeviceInfo
+		}
+		done <- res
+	}()
 
-	var result *cni100.Result = nil
-	if req.Command == cnitypes.CNIAdd {
-		result, err = s.cniCmdAddHandler(req)
-	} else if req.Command == cnitypes.CNIDel {
-		result, err = s.cniCmdDelHandler(req)
+	var res handlerResult
+	select {
+	case res = <-done:
+	case <-req.Ctx.Done():
+		res.err = req.Ctx.Err()
 	}
+	if res.err != nil && req.Ctx.Err() == context.DeadlineExceeded {
+		// A handler that ran out of time did not fail for good
+		res.err = types.NewError(types.ErrTryAgainLater, "CNI request timed out", res.err.Error())
+	}
+	s.audit.record(req, res.err)
+	if res.err != nil {
+		klog.ErrorS(res.err, "Error occured in handler", "requestID", req.ID)
+		return nil, res.err
+	}
+
+	response := &cnitypes.Response{Result: res.result, DeviceInfo: res.deviceInfo}
+	return json.Marshal(&response)
+}
+
+// dispatch calls the handler of the command of req.
+func (s *Server) dispatch(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	switch req.Command {
+	case cnitypes.CNIAdd:
+		return s.cmdAdd(req)
+	case cnitypes.CNIDel:
+		return s.cmdDel(req)
+	case cnitypes.CNICheck:
+		// Without a check handler there is nothing to verify beyond the ADD
+		// that succeeded, the runtime still checks its own result
+		if s.cniCmdCheckHandler == nil {
+			return nil, nil
+		}
+		if err := s.restoreAttachment(req); err != nil {
+			return nil, err
+		}
+		return s.cniCmdCheckHandler(req)
+	case cnitypes.CNIStatus:
+		if s.cniCmdStatusHandler == nil {
+			return nil, types.NewError(types.ErrInternal, "no handler for CNI_COMMAND", req.Command)
+		}
+		return s.cniCmdStatusHandler(req)
+	case cnitypes.CNIGC:
+		if s.cniCmdGCHandler == nil {
+			return nil, types.NewError(types.ErrInternal, "no handler for CNI_COMMAND", req.Command)
+		}
+		return s.cmdGC(req)
+	}
+	return nil, types.NewError(types.ErrInvalidEnvironmentVariables, "unsupported CNI_COMMAND", req.Command)
+}
+
+// cmdAdd returns the cached result if the attachment was already added, so
+// that a repeated ADD is idempotent. Otherwise it calls the add handler and
+// caches its result along with the NetConf it filled in.
+func (s *Server) cmdAdd(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	if s.results == nil {
+		return s.cniCmdAddHandler(req)
+	}
+	cached, err := s.results.get(req.ContainerId, req.IfName)
 	if err != nil {
-		klog.Errorf("Error occured in handler: %v", err)
 		return nil, err
 	}
+	if cached != nil {
+		klog.Infof("Returning cached result of %s/%s", req.ContainerId, req.IfName)
+		if cached.DeviceInfo != nil {
+			req.DeviceInfo = *cached.DeviceInfo
+		}
+		return cached.Result, nil
+	}
 
-	response := &cnitypes.Response{Result: result}
-	return json.Marshal(&response)
+	result, err := s.cniCmdAddHandler(req)
+	if err != nil {
+		return nil, err
+	}
+	attachment := &cachedAttachment{
+		ContainerID: req.ContainerId,
+		IfName:      req.IfName,
+		NetName:     req.NetName,
+		NetConf:     req.CNIConf,
+		Result:      result,
+	}
+	if req.DeviceInfo.Type != "" {
+		attachment.DeviceInfo = &req.DeviceInfo
+	}
+	err = s.results.set(attachment)
+	if err != nil {
+		// Without the cache the attachment could not be released after a
+		// restart, so undo it and let the runtime retry
+		if _, delErr := s.cniCmdDelHandler(req); delErr != nil {
+			klog.Errorf("Failed to undo %s/%s: %v", req.ContainerId, req.IfName, delErr)
+		}
+		return nil, err
+	}
+	return result, nil
+}
+
+// cmdDel calls the del handler with the NetConf cached by ADD and forgets
+// the attachment once it is released. Attachments without a cache entry,
+// such as those added by an older daemon, still reach the handler.
+func (s *Server) cmdDel(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	if err := s.restoreAttachment(req); err != nil {
+		return nil, err
+	}
+	result, err := s.cniCmdDelHandler(req)
+	if err != nil {
+		return nil, err
+	}
+	if s.results != nil {
+		if err := s.results.remove(req.ContainerId, req.IfName); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// restoreAttachment replaces the NetConf of req with the one cached by ADD,
+// keeping the prevResult the runtime passed.
+func (s *Server) restoreAttachment(req *cnitypes.PodRequest) error {
+	if s.results == nil {
+		return nil
+	}
+	cached, err := s.results.get(req.ContainerId, req.IfName)
+	if err != nil || cached == nil || cached.NetConf == nil {
+		return err
+	}
+	cached.NetConf.RawPrevResult = req.CNIConf.RawPrevResult
+	cached.NetConf.PrevResult = req.CNIConf.PrevResult
+	req.CNIConf = cached.NetConf
+	return nil
+}
+
+// cmdGC calls the GC handler and then forgets the attachments of the network
+// that are not in the valid attachments.
+func (s *Server) cmdGC(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	result, err := s.cniCmdGCHandler(req)
+	if err != nil || s.results == nil {
+		return result, err
+	}
+	valid := map[string]bool{}
+	for _, a := range req.CNIConf.ValidAttachments {
+		valid[a.ContainerID+"/"+a.IfName] = true
+	}
+	cached, err := s.results.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range cached {
+		if a.NetName != req.NetName || valid[a.ContainerID+"/"+a.IfName] {
+			continue
+		}
+		if err := s.results.remove(a.ContainerID, a.IfName); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// cniError returns err as a CNI error, errors of the handlers that are not
+// one are internal errors.
+func cniError(err error) *types.Error {
+	var e *types.Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return types.NewError(types.ErrInternal, err.Error(), "")
 }
 
-// HttpCNIPost is a callback functions to handle "/cni" requests.
+// httpStatus returns the HTTP status of a failed request: 400 if the request
+// itself is wrong, 503 if the runtime should retry it later and 500
+// otherwise.
+func httpStatus(e *types.Error) int {
+	switch e.Code {
+	case types.ErrIncompatibleCNIVersion, types.ErrUnsupportedField, types.ErrUnknownContainer,
+		types.ErrInvalidEnvironmentVariables, types.ErrDecodingFailure, types.ErrInvalidNetworkConfig,
+		types.ErrInvalidNetNS:
+		return http.StatusBadRequest
+	case types.ErrTryAgainLater, cnitypes.ErrPluginNotAvailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// HttpCNIPost is a callback functions to handle "/cni" requests. Failed
+// requests are answered with the CNI error as JSON, which the shim passes
+// to the runtime.
 func (s *Server) HttpCNIPost(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
+	w.Header().Set("Content-Type", "application/json")
 	result, err := s.handleCNIRequest(r)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("%v", err), http.StatusBadRequest)
+		cniErr := cniError(err)
+		w.WriteHeader(httpStatus(cniErr))
+		if err := json.NewEncoder(w).Encode(cniErr); err != nil {
+			klog.Errorf("Error writing HTTP response: %v", err)
+		}
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
 
 	// Empty response JSON means success with no body
-	w.Header().Set("Content-Type", "application/json")
 	if _, err := w.Write(result); err != nil {
 		klog.Errorf("Error writing HTTP response: %v", err)
 	}
@@ -288,6 +462,7 @@ func NewCNIServer(addHandler processRequestFunc, delHandler processRequestFunc,
 		},
 		cniCmdAddHandler: addHandler,
 		cniCmdDelHandler: delHandler,
+		requestTimeout:   defaultRequestTimeout,
 	}
 
 	router.NotFoundHandler = http.HandlerFunc(http.NotFound)
@@ -305,3 +480,51 @@ func WithPathManager(pathManager utils.PathManager) func(*Server) {
 		s.pathManager = pathManager
 	}
 }
+
+// WithResultCacheDir makes the server persist the result of every ADD in
+// dataDir, return it for a repeated ADD and pass the NetConf of the ADD to
+// DEL and CHECK.
+func WithResultCacheDir(dataDir string) func(*Server) {
+	return func(s *Server) {
+		s.results = &resultCache{dataDir: dataDir}
+	}
+}
+
+// WithRequestTimeout sets how long a request may take before the runtime is
+// told to try again later.
+func WithRequestTimeout(timeout time.Duration) func(*Server) {
+	return func(s *Server) {
+		s.requestTimeout = timeout
+	}
+}
+
+// WithAuditLog makes the server write a JSON record of every request to w.
+func WithAuditLog(w io.Writer) func(*Server) {
+	return func(s *Server) {
+		s.audit = &auditLog{w: w}
+	}
+}
+
+// WithCheckHandler sets the handler verifying the attachments of a pod on
+// CNI CHECK.
+func WithCheckHandler(checkHandler processRequestFunc) func(*Server) {
+	return func(s *Server) {
+		s.cniCmdCheckHandler = checkHandler
+	}
+}
+
+// WithStatusHandler sets the handler reporting whether the daemon is ready
+// for ADD requests on CNI STATUS.
+func WithStatusHandler(statusHandler processRequestFunc) func(*Server) {
+	return func(s *Server) {
+		s.cniCmdStatusHandler = statusHandler
+	}
+}
+
+// WithGCHandler sets the handler releasing the attachments that are not in
+// the valid attachments of a CNI GC request.
+func WithGCHandler(gcHandler processRequestFunc) func(*Server) {
+	return func(s *Server) {
+		s.cniCmdGCHandler = gcHandler
+	}
+}
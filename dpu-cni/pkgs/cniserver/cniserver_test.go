@@ -1,10 +1,20 @@
 package cniserver_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
+	nadapi "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 	g "github.com/onsi/ginkgo/v2"
 	o "github.com/onsi/gomega"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cni"
@@ -119,3 +129,459 @@ var _ = g.Describe("Cniserver", func() {
 		})
 	})
 })
+
+func podRequest(command string, pod string) *cnitypes.Request {
+	return &cnitypes.Request{
+		Env: map[string]string{
+			"CNI_COMMAND":     command,
+			"CNI_ARGS":        "K8S_POD_NAMESPACE=x;K8S_POD_NAME=" + pod + ";K8S_POD_UID=z",
+			"CNI_CONTAINERID": pod,
+			"CNI_NETNS":       "/var/run/netns/" + pod,
+			"CNI_IFNAME":      "net1",
+			"CNI_PATH":        "fakepath",
+		},
+		Config: []byte("{\"cniVersion\": \"0.4.0\",\"name\": \"dpucni\",\"type\": \"dpucni\"}"),
+	}
+}
+
+func postRequest(server *cniserver.Server, req *cnitypes.Request) int {
+	body, err := json.Marshal(req)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	rec := httptest.NewRecorder()
+	server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/cni", bytes.NewReader(body)))
+	return rec.Code
+}
+
+var _ = g.Describe("Cniserver concurrency", func() {
+	var (
+		mu       sync.Mutex
+		inFlight map[string]int
+		maxPod   int
+		handled  map[string]int
+	)
+
+	g.BeforeEach(func() {
+		inFlight = map[string]int{}
+		handled = map[string]int{}
+		maxPod = 0
+	})
+
+	track := func(wait func()) func(*cnitypes.PodRequest) (*current.Result, error) {
+		return func(req *cnitypes.PodRequest) (*current.Result, error) {
+			mu.Lock()
+			inFlight[req.ContainerId]++
+			if inFlight[req.ContainerId] > maxPod {
+				maxPod = inFlight[req.ContainerId]
+			}
+			mu.Unlock()
+
+			wait()
+
+			mu.Lock()
+			inFlight[req.ContainerId]--
+			handled[req.ContainerId+"/"+req.Command]++
+			mu.Unlock()
+			return &current.Result{CNIVersion: req.CNIConf.CNIVersion}, nil
+		}
+	}
+
+	g.It("should serialize requests for the same pod", func() {
+		handler := track(func() { time.Sleep(time.Millisecond) })
+		server := cniserver.NewCNIServer(handler, handler)
+
+		var wg sync.WaitGroup
+		for i := 0; i < 20; i++ {
+			for _, command := range []string{cnitypes.CNIAdd, cnitypes.CNIDel} {
+				wg.Add(1)
+				go func(command string) {
+					defer g.GinkgoRecover()
+					defer wg.Done()
+					o.Expect(postRequest(server, podRequest(command, "pod1"))).To(o.Equal(http.StatusOK))
+				}(command)
+			}
+		}
+		wg.Wait()
+
+		o.Expect(maxPod).To(o.Equal(1))
+		o.Expect(handled).To(o.Equal(map[string]int{"pod1/ADD": 20, "pod1/DEL": 20}))
+	})
+
+	g.It("should handle requests for different pods concurrently", func() {
+		const pods = 8
+		var started sync.WaitGroup
+		started.Add(pods)
+		allStarted := make(chan struct{})
+		go func() {
+			started.Wait()
+			close(allStarted)
+		}()
+		handler := track(func() {
+			started.Done()
+			// Every handler waits for all others to start, this only
+			// completes if the requests are not serialized.
+			select {
+			case <-allStarted:
+			case <-time.After(10 * time.Second):
+			}
+		})
+		server := cniserver.NewCNIServer(handler, handler)
+
+		var wg sync.WaitGroup
+		for i := 0; i < pods; i++ {
+			wg.Add(1)
+			go func(pod string) {
+				defer g.GinkgoRecover()
+				defer wg.Done()
+				o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, pod))).To(o.Equal(http.StatusOK))
+			}(fmt.Sprintf("pod%d", i))
+		}
+		o.Eventually(allStarted, 5*time.Second).Should(o.BeClosed())
+		wg.Wait()
+
+		o.Expect(maxPod).To(o.Equal(1))
+		o.Expect(handled).To(o.HaveLen(pods))
+	})
+})
+
+var _ = g.Describe("Cniserver CHECK", func() {
+	noop := func(req *cnitypes.PodRequest) (*current.Result, error) {
+		return nil, nil
+	}
+
+	g.It("should succeed without a check handler", func() {
+		server := cniserver.NewCNIServer(noop, noop)
+		o.Expect(postRequest(server, podRequest(cnitypes.CNICheck, "pod1"))).To(o.Equal(http.StatusOK))
+	})
+
+	g.It("should return the error of the check handler", func() {
+		checked := false
+		check := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			checked = true
+			return nil, fmt.Errorf("interface %s not found", req.IfName)
+		}
+		server := cniserver.NewCNIServer(noop, noop, cniserver.WithCheckHandler(check))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNICheck, "pod1"))).NotTo(o.Equal(http.StatusOK))
+		o.Expect(checked).To(o.BeTrue())
+	})
+})
+
+var _ = g.Describe("Cniserver STATUS and GC", func() {
+	noop := func(req *cnitypes.PodRequest) (*current.Result, error) {
+		return nil, nil
+	}
+	// STATUS and GC are not about an attachment, the runtime only sets
+	// CNI_COMMAND and CNI_PATH
+	request := func(command string, config string) *cnitypes.Request {
+		return &cnitypes.Request{
+			Env: map[string]string{
+				"CNI_COMMAND": command,
+				"CNI_PATH":    "fakepath",
+			},
+			Config: []byte(config),
+		}
+	}
+
+	g.It("should report the status of the status handler", func() {
+		ready := fmt.Errorf("VSP is not ready")
+		status := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, ready
+		}
+		server := cniserver.NewCNIServer(noop, noop, cniserver.WithStatusHandler(status))
+		config := "{\"cniVersion\": \"1.1.0\",\"name\": \"dpucni\",\"type\": \"dpucni\"}"
+		o.Expect(postRequest(server, request(cnitypes.CNIStatus, config))).NotTo(o.Equal(http.StatusOK))
+		ready = nil
+		o.Expect(postRequest(server, request(cnitypes.CNIStatus, config))).To(o.Equal(http.StatusOK))
+	})
+
+	g.It("should pass the valid attachments to the GC handler", func() {
+		var attachments []types.GCAttachment
+		gc := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			attachments = req.CNIConf.ValidAttachments
+			return nil, nil
+		}
+		server := cniserver.NewCNIServer(noop, noop, cniserver.WithGCHandler(gc))
+		config := "{\"cniVersion\": \"1.1.0\",\"name\": \"dpucni\",\"type\": \"dpucni\"," +
+			"\"cni.dev/valid-attachments\": [{\"containerID\": \"pod1\", \"ifname\": \"net1\"}]}"
+		o.Expect(postRequest(server, request(cnitypes.CNIGC, config))).To(o.Equal(http.StatusOK))
+		o.Expect(attachments).To(o.Equal([]types.GCAttachment{{ContainerID: "pod1", IfName: "net1"}}))
+	})
+
+	g.It("should fail STATUS and GC without a handler", func() {
+		server := cniserver.NewCNIServer(noop, noop)
+		config := "{\"cniVersion\": \"1.1.0\",\"name\": \"dpucni\",\"type\": \"dpucni\"}"
+		o.Expect(postRequest(server, request(cnitypes.CNIStatus, config))).To(o.Equal(http.StatusInternalServerError))
+		o.Expect(postRequest(server, request(cnitypes.CNIGC, config))).To(o.Equal(http.StatusInternalServerError))
+	})
+
+	g.It("should reject unknown commands", func() {
+		server := cniserver.NewCNIServer(noop, noop)
+		o.Expect(postRequest(server, podRequest("FROB", "pod1"))).To(o.Equal(http.StatusBadRequest))
+	})
+})
+
+var _ = g.Describe("Cniserver result cache", func() {
+	var (
+		dir  string
+		adds int
+		vfID int
+	)
+
+	add := func(req *cnitypes.PodRequest) (*current.Result, error) {
+		adds++
+		req.CNIConf.VFID = 7
+		return &current.Result{CNIVersion: req.CNIConf.CNIVersion}, nil
+	}
+	del := func(req *cnitypes.PodRequest) (*current.Result, error) {
+		vfID = req.CNIConf.VFID
+		return nil, nil
+	}
+
+	g.BeforeEach(func() {
+		dir = g.GinkgoT().TempDir()
+		adds = 0
+		vfID = -1
+	})
+
+	g.It("should only call the add handler once for a repeated ADD", func() {
+		server := cniserver.NewCNIServer(add, del, cniserver.WithResultCacheDir(dir))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusOK))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusOK))
+		o.Expect(adds).To(o.Equal(1))
+	})
+
+	g.It("should pass the NetConf of the ADD to DEL after a restart", func() {
+		server := cniserver.NewCNIServer(add, del, cniserver.WithResultCacheDir(dir))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusOK))
+
+		restarted := cniserver.NewCNIServer(add, del, cniserver.WithResultCacheDir(dir))
+		o.Expect(postRequest(restarted, podRequest(cnitypes.CNIDel, "pod1"))).To(o.Equal(http.StatusOK))
+		o.Expect(vfID).To(o.Equal(7))
+
+		// The attachment is forgotten once it is deleted
+		o.Expect(postRequest(restarted, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusOK))
+		o.Expect(adds).To(o.Equal(2))
+	})
+})
+
+var _ = g.Describe("Cniserver device-info", func() {
+	var (
+		plugin   *cni.Plugin
+		listener net.Listener
+		adds     int
+		file     string
+	)
+
+	g.BeforeEach(func() {
+		adds = 0
+		add := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			adds++
+			vfID := 1
+			req.DeviceInfo = cnitypes.DeviceInfo{
+				DeviceInfo: nadapi.DeviceInfo{
+					Type:    nadapi.DeviceInfoTypePCI,
+					Version: nadapi.DeviceInfoVersion,
+					Pci:     &nadapi.PciDevice{PciAddress: "0000:01:00.2", PfPciAddress: "0000:01:00.0"},
+				},
+				Dpu: &cnitypes.DpuDevice{VfID: &vfID, BridgePort: "host0-1", LogicalBridge: "3"},
+			}
+			return &current.Result{CNIVersion: req.CNIConf.CNIVersion}, nil
+		}
+		del := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, nil
+		}
+		server := cniserver.NewCNIServer(add, del, cniserver.WithResultCacheDir(g.GinkgoT().TempDir()))
+		socketPath := g.GinkgoT().TempDir() + "/cni.sock"
+		var err error
+		listener, err = net.Listen("unix", socketPath)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		go server.Serve(listener)
+		plugin = &cni.Plugin{SocketPath: socketPath}
+		file = g.GinkgoT().TempDir() + "/devinfo/cni/fakecontainerid-net1-device-info.json"
+	})
+
+	g.AfterEach(func() {
+		listener.Close()
+	})
+
+	args := func(command string) *skel.CmdArgs {
+		a := PrepArgs("1.1.0", command)
+		a.StdinData = []byte(`{"cniVersion": "1.1.0","name": "dpucni","type": "dpucni","runtimeConfig": {"CNIDeviceInfoFile": "` + file + `"}}`)
+		return a
+	}
+
+	savedDeviceInfoData := func() []byte {
+		data, err := os.ReadFile(file)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		return data
+	}
+
+	// savedDeviceInfo reads the saved device-info the way Multus does
+	savedDeviceInfo := func() *nadapi.DeviceInfo {
+		info := &nadapi.DeviceInfo{}
+		o.Expect(json.Unmarshal(savedDeviceInfoData(), info)).To(o.Succeed())
+		return info
+	}
+
+	g.It("should save the device-info of an ADD for Multus and remove it on DEL", func() {
+		o.Expect(plugin.CmdAdd(args(cnitypes.CNIAdd))).To(o.Succeed())
+		info := savedDeviceInfo()
+		o.Expect(info.Type).To(o.Equal(nadapi.DeviceInfoTypePCI))
+		o.Expect(*info.Pci).To(o.Equal(nadapi.PciDevice{PciAddress: "0000:01:00.2", PfPciAddress: "0000:01:00.0"}))
+
+		// The DPU wiring is an extension to the spec, the file keeps both
+		ext := &cnitypes.DeviceInfo{}
+		o.Expect(json.Unmarshal(savedDeviceInfoData(), ext)).To(o.Succeed())
+		o.Expect(ext.Dpu).NotTo(o.BeNil())
+		o.Expect(*ext.Dpu.VfID).To(o.Equal(1))
+		o.Expect(ext.Dpu.BridgePort).To(o.Equal("host0-1"))
+		o.Expect(ext.Dpu.LogicalBridge).To(o.Equal("3"))
+
+		o.Expect(plugin.CmdDel(args(cnitypes.CNIDel))).To(o.Succeed())
+		_, err := os.Stat(file)
+		o.Expect(os.IsNotExist(err)).To(o.BeTrue())
+	})
+
+	g.It("should return the device-info of a repeated ADD from the cache", func() {
+		resp, _, err := plugin.PostRequest(args(cnitypes.CNIAdd))
+		o.Expect(err).NotTo(o.HaveOccurred())
+		first := resp.DeviceInfo
+		o.Expect(first).NotTo(o.BeNil())
+
+		resp, _, err = plugin.PostRequest(args(cnitypes.CNIAdd))
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(adds).To(o.Equal(1))
+		o.Expect(resp.DeviceInfo).To(o.Equal(first))
+	})
+})
+
+var _ = g.Describe("Cniserver errors", func() {
+	var (
+		handlerErr error
+		plugin     *cni.Plugin
+		listener   net.Listener
+	)
+
+	g.BeforeEach(func() {
+		handlerErr = nil
+		handler := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return &current.Result{CNIVersion: req.CNIConf.CNIVersion}, handlerErr
+		}
+		server := cniserver.NewCNIServer(handler, handler)
+		socketPath := g.GinkgoT().TempDir() + "/cni.sock"
+		var err error
+		listener, err = net.Listen("unix", socketPath)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		go server.Serve(listener)
+		plugin = &cni.Plugin{SocketPath: socketPath}
+	})
+
+	g.AfterEach(func() {
+		listener.Close()
+	})
+
+	postError := func() *types.Error {
+		_, _, err := plugin.PostRequest(PrepArgs("1.1.0", cnitypes.CNIAdd))
+		o.Expect(err).To(o.HaveOccurred())
+		cniErr, ok := err.(*types.Error)
+		o.Expect(ok).To(o.BeTrue(), "not a CNI error: %v", err)
+		return cniErr
+	}
+
+	g.It("should pass the CNI error of the handler to the shim", func() {
+		handlerErr = types.NewError(types.ErrTryAgainLater, "VSP is not connected", "dial failed")
+		o.Expect(postError()).To(o.Equal(handlerErr))
+	})
+
+	g.It("should report other errors of the handler as internal errors", func() {
+		handlerErr = fmt.Errorf("VF is gone")
+		cniErr := postError()
+		o.Expect(cniErr.Code).To(o.Equal(types.ErrInternal))
+		o.Expect(cniErr.Msg).To(o.Equal("VF is gone"))
+	})
+
+	g.It("should reject auxiliary devices other than sub functions", func() {
+		args := PrepArgs("1.1.0", cnitypes.CNIAdd)
+		args.StdinData = []byte("{\"cniVersion\": \"1.1.0\",\"name\": \"dpucni\",\"type\": \"dpucni\", \"deviceID\": \"mlx5_core.eth.1\"}")
+		_, _, err := plugin.PostRequest(args)
+		cniErr, ok := err.(*types.Error)
+		o.Expect(ok).To(o.BeTrue(), "not a CNI error: %v", err)
+		o.Expect(cniErr.Code).To(o.Equal(types.ErrInvalidNetworkConfig))
+		o.Expect(cniErr.Details).To(o.Equal("mlx5_core.eth.1"))
+	})
+
+	g.It("should ask to retry if the server is not running", func() {
+		listener.Close()
+		o.Expect(postError().Code).To(o.Equal(types.ErrTryAgainLater))
+	})
+
+	g.It("should distinguish client from server errors", func() {
+		noop := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, nil
+		}
+		req := podRequest(cnitypes.CNIAdd, "pod1")
+		delete(req.Env, "CNI_NETNS")
+		o.Expect(postRequest(cniserver.NewCNIServer(noop, noop), req)).To(o.Equal(http.StatusBadRequest))
+
+		fail := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, types.NewError(types.ErrTryAgainLater, "VSP is not connected", "")
+		}
+		o.Expect(postRequest(cniserver.NewCNIServer(fail, noop), podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusServiceUnavailable))
+
+		fail = func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, fmt.Errorf("VF is gone")
+		}
+		o.Expect(postRequest(cniserver.NewCNIServer(fail, noop), podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusInternalServerError))
+	})
+})
+
+var _ = g.Describe("Cniserver timeouts and audit log", func() {
+	noop := func(req *cnitypes.PodRequest) (*current.Result, error) {
+		return nil, nil
+	}
+
+	g.It("should answer when the request expires and abort the handler", func() {
+		aborted := make(chan error, 1)
+		block := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			<-req.Ctx.Done()
+			aborted <- req.Ctx.Err()
+			return nil, req.Ctx.Err()
+		}
+		server := cniserver.NewCNIServer(block, noop, cniserver.WithRequestTimeout(50*time.Millisecond))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusServiceUnavailable))
+		o.Eventually(aborted).Should(o.Receive(o.Equal(context.DeadlineExceeded)))
+	})
+
+	g.It("should record every request in the audit log", func() {
+		var audit bytes.Buffer
+		fail := func(req *cnitypes.PodRequest) (*current.Result, error) {
+			return nil, types.NewError(types.ErrTryAgainLater, "VSP is not connected", "")
+		}
+		server := cniserver.NewCNIServer(noop, fail, cniserver.WithAuditLog(&audit))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIAdd, "pod1"))).To(o.Equal(http.StatusOK))
+		o.Expect(postRequest(server, podRequest(cnitypes.CNIDel, "pod1"))).NotTo(o.Equal(http.StatusOK))
+
+		type record struct {
+			RequestID string `json:"requestID"`
+			Command   string `json:"command"`
+			PodName   string `json:"podName"`
+			IfName    string `json:"ifName"`
+			Result    string `json:"result"`
+			ErrorCode uint   `json:"errorCode"`
+		}
+		var records []record
+		decoder := json.NewDecoder(&audit)
+		for decoder.More() {
+			var r record
+			o.Expect(decoder.Decode(&r)).To(o.Succeed())
+			records = append(records, r)
+		}
+		o.Expect(records).To(o.HaveLen(2))
+		o.Expect(records[0].RequestID).NotTo(o.BeEmpty())
+		o.Expect(records[1].RequestID).NotTo(o.Equal(records[0].RequestID))
+		records[0].RequestID, records[1].RequestID = "", ""
+		o.Expect(records).To(o.Equal([]record{
+			{Command: cnitypes.CNIAdd, PodName: "pod1", IfName: "net1", Result: "success"},
+			{Command: cnitypes.CNIDel, PodName: "pod1", IfName: "net1", Result: "error", ErrorCode: types.ErrTryAgainLater},
+		}))
+	})
+})
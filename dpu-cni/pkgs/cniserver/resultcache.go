@@ -0,0 +1,106 @@
+package cniserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+)
+
+// cachedAttachment is what the server remembers of a successful ADD. NetConf
+// holds the fields the ADD handler filled in, such as the VF ID and its
+// original state, that DEL needs to undo the attachment.
+type cachedAttachment struct {
+	ContainerID string               `json:"containerID"`
+	IfName      string               `json:"ifName"`
+	NetName     string               `json:"netName"`
+	NetConf     *cnitypes.NetConf    `json:"netConf"`
+	Result      *cni100.Result       `json:"result,omitempty"`
+	DeviceInfo  *cnitypes.DeviceInfo `json:"deviceInfo,omitempty"`
+}
+
+// resultCache persists an attachment per container ID and interface name, in
+// the same layout sriov-cni uses for its cached NetConf, so that a repeated
+// ADD returns the same result and DEL works after a daemon restart.
+type resultCache struct {
+	dataDir string
+}
+
+func (c *resultCache) path(containerID string, ifName string) string {
+	return filepath.Join(c.dataDir, strings.Join([]string{containerID, ifName}, "-"))
+}
+
+// get returns the attachment cached for containerID and ifName, or nil if
+// there is none.
+func (c *resultCache) get(containerID string, ifName string) (*cachedAttachment, error) {
+	data, err := os.ReadFile(c.path(containerID, ifName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cached result of %s/%s: %v", containerID, ifName, err)
+	}
+	var a cachedAttachment
+	if err := json.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse cached result of %s/%s: %v", containerID, ifName, err)
+	}
+	return &a, nil
+}
+
+func (c *resultCache) set(a *cachedAttachment) error {
+	if err := os.MkdirAll(c.dataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the result cache directory(%q): %v", c.dataDir, err)
+	}
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to serialize result of %s/%s: %v", a.ContainerID, a.IfName, err)
+	}
+	path := c.path(a.ContainerID, a.IfName)
+	// Write to a temporary file first so a crash never leaves a partial entry
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write cached result %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename cached result %s: %v", tmp, err)
+	}
+	return nil
+}
+
+func (c *resultCache) remove(containerID string, ifName string) error {
+	if err := os.Remove(c.path(containerID, ifName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove cached result of %s/%s: %v", containerID, ifName, err)
+	}
+	return nil
+}
+
+// list returns all cached attachments.
+func (c *resultCache) list() ([]*cachedAttachment, error) {
+	files, err := os.ReadDir(c.dataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list result cache directory %s: %v", c.dataDir, err)
+	}
+	var attachments []*cachedAttachment
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.dataDir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached result %s: %v", f.Name(), err)
+		}
+		var a cachedAttachment
+		if err := json.Unmarshal(data, &a); err != nil {
+			return nil, fmt.Errorf("failed to parse cached result %s: %v", f.Name(), err)
+		}
+		attachments = append(attachments, &a)
+	}
+	return attachments, nil
+}
@@ -2,11 +2,13 @@ package cnitypes
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	nadapi "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovtypes"
 	"github.com/vishvananda/netlink"
 )
 
@@ -28,16 +30,65 @@ type Request struct {
 // Response sent to this DPU CNI plugin by the Server
 type Response struct {
 	Result *current.Result
+	// DeviceInfo describes the device an ADD attached to the pod, the CNI
+	// plugin saves it for Multus to add to the pod's network-status
+	DeviceInfo *DeviceInfo `json:"deviceInfo,omitempty"`
+}
+
+// DeviceInfoTypeAuxiliary is the device-info type of a sub function. The
+// device-info spec of the vendored nadapi has no type for auxiliary devices.
+const DeviceInfoTypeAuxiliary = "auxiliary"
+
+// DeviceInfo is the device-info of an attachment. It extends the device-info
+// spec with the auxiliary device of a sub function and with the wiring of the
+// function on the DPU, readers that only know the spec ignore both.
+type DeviceInfo struct {
+	nadapi.DeviceInfo
+	// Auxiliary is set for the DeviceInfoTypeAuxiliary type
+	Auxiliary *AuxiliaryDevice `json:"auxiliary,omitempty"`
+	// Dpu is set by the host daemon once the bridge port is created
+	Dpu *DpuDevice `json:"dpu,omitempty"`
+}
+
+// AuxiliaryDevice is the auxiliary device of a sub function
+type AuxiliaryDevice struct {
+	// DeviceID is the auxiliary device name, <driver_name>.sf.<id>
+	DeviceID     string `json:"device-id,omitempty"`
+	PfPciAddress string `json:"pf-pci-address,omitempty"`
+	SFNum        int    `json:"sfnum"`
+}
+
+// DpuDevice is how the DPU connects a host function to its network
+type DpuDevice struct {
+	// VfID is the VF ID of a VF, unset for a sub function
+	VfID *int `json:"vf-id,omitempty"`
+	// BridgePort is the bridge port of the function on the DPU
+	BridgePort string `json:"bridge-port,omitempty"`
+	// LogicalBridge is the logical bridge of the bridge port
+	LogicalBridge string `json:"logical-bridge,omitempty"`
 }
 
 const CNIAdd string = "ADD"
 const CNIUpdate string = "UPDATE"
 const CNIDel string = "DEL"
 const CNICheck string = "CHECK"
+const CNIStatus string = "STATUS"
+const CNIGC string = "GC"
+
+// ErrPluginNotAvailable is the error code of a STATUS result telling the
+// runtime not to send ADD requests, defined by the CNI 1.1 spec.
+const ErrPluginNotAvailable uint = 50
+
+// ErrCheckFailed is the error code of a CHECK that found the attachment
+// differing from what ADD set up. It is in the range the CNI spec leaves to
+// plugins.
+const ErrCheckFailed uint = 100
 
 // PodRequest structure built from Request which is passed to the
 // handler function given to the Server at creation time
 type PodRequest struct {
+	// ID identifies the request in the logs of the daemon
+	ID string
 	// The CNI command of the operation
 	Command string
 	// kubernetes namespace name
@@ -69,7 +120,7 @@ type PodRequest struct {
 	NetName string
 
 	// the DeviceInfo struct
-	DeviceInfo nadapi.DeviceInfo
+	DeviceInfo DeviceInfo
 }
 
 // FIXME: This file is copied from sriov-cni intentionally. We plan to trim this down once
@@ -95,6 +146,8 @@ type VfState struct {
 	MinTxRate    int
 	MaxTxRate    int
 	LinkState    uint32
+	// Link holds the original values of the link attributes cmdAdd changed
+	Link LinkConfig
 }
 
 // FillFromVfInfo - Fill attributes according to the provided netlink.VfInfo struct
@@ -110,11 +163,22 @@ func (vs *VfState) FillFromVfInfo(info *netlink.VfInfo) {
 	vs.Trust = info.Trust != 0
 }
 
+// VfCapabilities are the VF settings the host CNI may change through the PF
+// with netlink. Some vendors have issues with netlink, so they are only used
+// where they are known to work.
+type VfCapabilities struct {
+	// Vlan is setting the VLAN, QoS and VLAN protocol of the VF
+	Vlan bool
+	// State is saving the VF state on ADD and restoring it on DEL
+	State bool
+}
+
 // NetConf extends types.NetConf for dpu-sriov-cni
 type NetConf struct {
 	types.NetConf
-	OrigVfState   VfState // Stores the original VF state as it was prior to any operations done during cmdAdd flow
-	DPDKMode      bool    `json:"-"`
+	OrigVfState   VfState        // Stores the original VF state as it was prior to any operations done during cmdAdd flow
+	Capabilities  VfCapabilities // The VF capabilities used by cmdAdd, so that cmdDel undoes the same
+	DPDKMode      bool           `json:"-"`
 	Master        string
 	MAC           string
 	Vlan          *int    `json:"vlan"`
@@ -122,6 +186,7 @@ type NetConf struct {
 	VlanProto     *string `json:"vlanProto"` // 802.1ad|802.1q
 	DeviceID      string  `json:"deviceID"`  // PCI address of a VF in valid sysfs format
 	VFID          int
+	SFNum         int    // sfnum of a sub function DeviceID, see IsSF()
 	MinTxRate     *int   `json:"min_tx_rate"`          // Mbps, 0 = disable rate limiting
 	MaxTxRate     *int   `json:"max_tx_rate"`          // Mbps, 0 = disable rate limiting
 	SpoofChk      string `json:"spoofchk,omitempty"`   // on|off
@@ -129,7 +194,125 @@ type NetConf struct {
 	LinkState     string `json:"link_state,omitempty"` // auto|enable|disable
 	RuntimeConfig struct {
 		Mac string `json:"mac,omitempty"`
+		// CNIDeviceInfoFile is where Multus reads the device-info of the
+		// attachment from, set for the CNIDeviceInfoFile capability
+		CNIDeviceInfoFile string `json:"CNIDeviceInfoFile,omitempty"`
 	} `json:"runtimeConfig,omitempty"`
 	LogLevel string `json:"logLevel,omitempty"`
 	LogFile  string `json:"logFile,omitempty"`
+	// Attributes of the pod interface
+	LinkConfig
+	// Role of the interface in a network function pod, set in the NAD
+	NFArgs
+	// Args holds the "cni-args" of the pod's network selection annotation,
+	// which take precedence over the NAD
+	Args *struct {
+		CNI *NFArgs `json:"cni,omitempty"`
+	} `json:"args,omitempty"`
+}
+
+// minMTU is the smallest MTU of an IPv4 interface (RFC 791)
+const minMTU = 68
+
+// maxAliasLen is the longest alias the kernel stores (IFALIASZ - 1)
+const maxAliasLen = 255
+
+// LinkConfig are the attributes of the netdev of an interface, which are
+// applied in the pod netns. Unset attributes are left as they are.
+type LinkConfig struct {
+	MTU    int    `json:"mtu,omitempty"`
+	Alias  string `json:"alias,omitempty"`
+	TxQLen *int   `json:"txqueuelen,omitempty"`
+	// Offloads turns ethtool features on or off by their kernel name, e.g.
+	// "rx-gro" or "tx-tcp-segmentation"
+	Offloads map[string]bool `json:"offloads,omitempty"`
+}
+
+// IsSet reports whether any attribute is set.
+func (l LinkConfig) IsSet() bool {
+	return l.MTU != 0 || l.Alias != "" || l.TxQLen != nil || len(l.Offloads) != 0
+}
+
+// Validate checks that the attributes are in the range the kernel accepts.
+// Whether the device supports them is only known when they are applied.
+func (l LinkConfig) Validate() error {
+	if l.MTU != 0 && l.MTU < minMTU {
+		return fmt.Errorf("mtu %d invalid: value must be at least %d", l.MTU, minMTU)
+	}
+	if len(l.Alias) > maxAliasLen {
+		return fmt.Errorf("alias invalid: must be at most %d characters", maxAliasLen)
+	}
+	if l.TxQLen != nil && *l.TxQLen < 0 {
+		return fmt.Errorf("txqueuelen %d invalid: value must not be negative", *l.TxQLen)
+	}
+	for name := range l.Offloads {
+		if name == "" {
+			return fmt.Errorf("offloads invalid: feature name must not be empty")
+		}
+	}
+	return nil
+}
+
+const (
+	NFRoleIngress    string = "ingress"
+	NFRoleEgress     string = "egress"
+	NFRoleManagement string = "management"
+)
+
+// NFArgs declares the role of an interface of a network function pod. The
+// VSP steers traffic from the ingress to the egress interface of each port
+// pair, management interfaces are not part of the data path. Interfaces
+// without a role are assigned ingress and egress of port pair 0 in the order
+// they are added.
+type NFArgs struct {
+	NFRole     string `json:"nfRole,omitempty"`
+	NFPortPair *int   `json:"nfPortPair,omitempty"`
+}
+
+// Validate checks that the role is known and that only ingress and egress
+// interfaces belong to a port pair.
+func (a NFArgs) Validate() error {
+	switch a.NFRole {
+	case "", NFRoleIngress, NFRoleEgress:
+	case NFRoleManagement:
+		if a.NFPortPair != nil {
+			return fmt.Errorf("management interfaces do not belong to a port pair")
+		}
+	default:
+		return fmt.Errorf("unknown network function interface role %q", a.NFRole)
+	}
+	if a.NFPortPair != nil && *a.NFPortPair < 0 {
+		return fmt.Errorf("invalid network function port pair %d", *a.NFPortPair)
+	}
+	return nil
+}
+
+// PortPair returns the port pair of the interface, 0 if none is set.
+func (a NFArgs) PortPair() int {
+	if a.NFPortPair == nil {
+		return 0
+	}
+	return *a.NFPortPair
+}
+
+// IsSF reports whether DeviceID is the auxiliary device of a sub function
+// (<driver_name>.sf.<id>) rather than the PCI address of a VF. A sub function
+// has no VF state on the PF, only its netdev is configured.
+func (n *NetConf) IsSF() bool {
+	return sriovtypes.IsSFDeviceName(n.DeviceID)
+}
+
+// NetworkFunctionArgs returns the role of the interface, the pod's cni-args
+// override the NAD field by field.
+func (n *NetConf) NetworkFunctionArgs() NFArgs {
+	args := n.NFArgs
+	if n.Args != nil && n.Args.CNI != nil {
+		if n.Args.CNI.NFRole != "" {
+			args.NFRole = n.Args.CNI.NFRole
+		}
+		if n.Args.CNI.NFPortPair != nil {
+			args.NFPortPair = n.Args.CNI.NFPortPair
+		}
+	}
+	return args
 }
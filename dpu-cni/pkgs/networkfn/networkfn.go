@@ -8,7 +8,11 @@ import (
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ipam"
 	"github.com/containernetworking/plugins/pkg/ns"
+	nadapi "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnihelper"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovtypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
 	"github.com/vishvananda/netlink"
 	"k8s.io/klog/v2"
 )
@@ -107,6 +111,9 @@ func moveLinkInNetNamespace(hostDev netlink.Link, containerNs ns.NetNS, ifName s
 		}
 		klog.Infof("moveLinkInNetNamespace: Save original host name %q on %q", origHostDevName, contDev.Attrs().Name)
 
+		// Error is ignored here because enabling this feature is only a performance enhancement.
+		_ = sriovutils.EnableArpAndNdiscNotify(tempDevName)
+
 		// Rename container device to respect ifName coming from CNI netconf
 		if err = netlink.LinkSetName(contDev, ifName); err != nil {
 			return fmt.Errorf("failed to rename device %q to %q: %v", tempDevName, ifName, err)
@@ -230,6 +237,92 @@ func moveLinkOutToHost(containerNs ns.NetNS, ifName string) error {
 	return nil
 }
 
+// nfDevice is the device the device plugin allocated to a network function
+// interface.
+type nfDevice struct {
+	// pciAddress is empty for interfaces that are not PCI devices
+	pciAddress string
+	// linkName is the netdev of the device, empty for DPDK devices
+	linkName string
+	// dpdk is set for devices bound to a userspace driver such as vfio-pci,
+	// which the network function drives itself
+	dpdk bool
+}
+
+// isDpdkDevice reports whether deviceID is the PCI address of a device bound
+// to a userspace driver.
+func isDpdkDevice(deviceID string) (bool, error) {
+	if !sriovutils.IsValidPCIAddress(deviceID) {
+		return false, nil
+	}
+	dpdk, err := sriovutils.HasDpdkDriver(deviceID)
+	if err != nil {
+		return false, fmt.Errorf("failed to detect if device %s has a dpdk driver: %v", deviceID, err)
+	}
+	return dpdk, nil
+}
+
+// resolveDevice finds the device of deviceID, which is the name of an
+// interface, the auxiliary device name of a sub function or the PCI address of
+// a netdev or DPDK device.
+func resolveDevice(deviceID string) (*nfDevice, error) {
+	if sriovtypes.IsSFDeviceName(deviceID) {
+		linkName, err := sriovutils.GetNetNameFromAuxDevice(deviceID)
+		if err != nil {
+			return nil, err
+		}
+		return &nfDevice{linkName: linkName}, nil
+	}
+	if !sriovutils.IsValidPCIAddress(deviceID) {
+		dev := &nfDevice{linkName: deviceID}
+		// Interfaces such as veths have no PCI address
+		if pciAddress, err := sriovutils.GetPciFromNetDev(deviceID); err == nil {
+			dev.pciAddress = pciAddress
+		}
+		return dev, nil
+	}
+
+	linkName, err := sriovutils.GetVFLinkName(deviceID)
+	if err == nil && linkName != "" {
+		return &nfDevice{pciAddress: deviceID, linkName: linkName}, nil
+	}
+	dpdk, err := isDpdkDevice(deviceID)
+	if err != nil {
+		return nil, err
+	}
+	if !dpdk {
+		return nil, fmt.Errorf("device %s has neither an interface nor a dpdk driver", deviceID)
+	}
+	return &nfDevice{pciAddress: deviceID, dpdk: true}, nil
+}
+
+// vfMAC returns the MAC address the PF assigned to the VF at pciAddress, the
+// only way to learn the MAC address of a device without netdev.
+func vfMAC(pciAddress string) (string, error) {
+	pfName, err := sriovutils.GetPfName(pciAddress)
+	if err != nil {
+		return "", fmt.Errorf("failed to find the PF of %s: %v", pciAddress, err)
+	}
+	vfID, err := sriovutils.GetVfid(pciAddress, pfName)
+	if err != nil {
+		return "", err
+	}
+	pf, err := netlink.LinkByName(pfName)
+	if err != nil {
+		return "", fmt.Errorf("failed to find PF %q: %v", pfName, err)
+	}
+	for _, vf := range pf.Attrs().Vfs {
+		if vf.ID == vfID {
+			return vf.Mac.String(), nil
+		}
+	}
+	return "", fmt.Errorf("failed to find VF %d of PF %q", vfID, pfName)
+}
+
+// CmdAdd moves the device of the network function interface into the pod and
+// configures its addresses. A DPDK device stays where it is, the network
+// function opens it by the PCI address set in req.DeviceInfo, and the
+// addresses IPAM assigns are only returned in the result.
 func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 	klog.Info("CmdAdd called for networkfn")
 
@@ -245,29 +338,77 @@ func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 
 	klog.Infof("CmdAdd: Netns: %q", req.Netns)
 
-	result := &current.Result{}
-	var contDev netlink.Link
+	if err := conf.LinkConfig.Validate(); err != nil {
+		return nil, err
+	}
+	// The alias of the interface records its name in the host netns
+	if conf.Alias != "" {
+		return nil, fmt.Errorf("alias is not supported for network function interfaces")
+	}
 
-	// TODO: In the future we may want to support the following formats coming from the device plugin
-	// pciAddr: For Netdev and DPDK use cases
-	// auxDevices: Device plugins may allocate network device on a bus different than PCI
-	// Also this code would not work for DPDK interfaces.
-	hostDev, err := netlink.LinkByName(conf.DeviceID)
+	dev, err := resolveDevice(conf.DeviceID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find host device: %v", err)
 	}
 
-	contDev, err = moveLinkInNetNamespace(hostDev, containerNs, req.IfName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to move link %v", err)
+	result := &current.Result{}
+	if dev.dpdk {
+		mac := conf.MAC
+		if mac == "" {
+			mac, err = vfMAC(dev.pciAddress)
+			if err != nil {
+				return nil, fmt.Errorf("failed to find the MAC address of DPDK device %s: %v", dev.pciAddress, err)
+			}
+		}
+		if conf.LinkConfig.IsSet() {
+			klog.Warningf("CmdAdd: Ignoring the link attributes of DPDK device %s, it has no netdev", dev.pciAddress)
+		}
+		klog.Infof("CmdAdd: DPDK device %s with MAC %s stays in the host netns", dev.pciAddress, mac)
+		result.Interfaces = []*current.Interface{{
+			Name:    req.IfName,
+			Mac:     mac,
+			Sandbox: containerNs.Path(),
+		}}
+		req.CNIConf.MAC = mac
+	} else {
+		hostDev, err := netlink.LinkByName(dev.linkName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find host device: %v", err)
+		}
+
+		contDev, err := moveLinkInNetNamespace(hostDev, containerNs, req.IfName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to move link %v", err)
+		}
+
+		if conf.LinkConfig.IsSet() {
+			err = containerNs.Do(func(_ ns.NetNS) error {
+				orig, err := sriovutils.ApplyLinkConfig(&sriovutils.MyNetlink{}, req.IfName, conf.LinkConfig)
+				req.CNIConf.OrigVfState.Link = orig
+				return err
+			})
+			if err != nil {
+				_ = moveLinkOutToHost(containerNs, req.IfName)
+				return nil, fmt.Errorf("failed to set the link attributes of %s: %v", req.IfName, err)
+			}
+		}
+
+		result.Interfaces = []*current.Interface{{
+			Name:    contDev.Attrs().Name,
+			Mac:     contDev.Attrs().HardwareAddr.String(),
+			Sandbox: containerNs.Path(),
+		}}
+		req.CNIConf.MAC = contDev.Attrs().HardwareAddr.String()
 	}
 
-	result.Interfaces = []*current.Interface{{
-		Name:    contDev.Attrs().Name,
-		Mac:     contDev.Attrs().HardwareAddr.String(),
-		Sandbox: containerNs.Path(),
-	}}
-	req.CNIConf.MAC = contDev.Attrs().HardwareAddr.String()
+	// The device plugin knows the device best, only fill in what it did not
+	if dev.pciAddress != "" && req.DeviceInfo.Type == "" {
+		req.DeviceInfo.DeviceInfo = nadapi.DeviceInfo{
+			Type:    nadapi.DeviceInfoTypePCI,
+			Version: nadapi.DeviceInfoVersion,
+			Pci:     &nadapi.PciDevice{PciAddress: dev.pciAddress},
+		}
+	}
 
 	if conf.IPAM.Type == "" {
 		return result, nil
@@ -275,7 +416,7 @@ func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 
 	klog.Infof("CmdAdd: Running IPAM %q", conf.IPAM.Type)
 	// Run the IPAM plugin and get back the config to apply
-	r, err := ipam.ExecAdd(conf.IPAM.Type, req.CNIReq.Config)
+	r, err := cnihelper.IpamExecAdd(req, conf.IPAM.Type)
 	if err != nil {
 		return nil, err
 	}
@@ -283,7 +424,7 @@ func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 	// Invoke ipam del if err to avoid ip leak
 	defer func() {
 		if err != nil {
-			ipam.ExecDel(conf.IPAM.Type, req.CNIReq.Config)
+			cnihelper.IpamExecDel(req, conf.IPAM.Type)
 		}
 	}()
 
@@ -294,7 +435,8 @@ func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 	}
 
 	if len(newResult.IPs) == 0 {
-		return nil, errors.New("IPAM plugin returned missing IP config")
+		err = errors.New("IPAM plugin returned missing IP config")
+		return nil, err
 	}
 
 	for _, ipc := range newResult.IPs {
@@ -304,11 +446,22 @@ func CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 
 	newResult.Interfaces = result.Interfaces
 
-	err = containerNs.Do(func(_ ns.NetNS) error {
-		return ipam.ConfigureIface(req.IfName, newResult)
-	})
-	if err != nil {
-		return nil, err
+	if !dev.dpdk {
+		err = containerNs.Do(func(_ ns.NetNS) error {
+			if err := ipam.ConfigureIface(req.IfName, newResult); err != nil {
+				return err
+			}
+			// The addresses may have belonged to another pod, update the
+			// neighbours' caches like the sriov CNI does. The error is only logged
+			// because this is only a performance enhancement.
+			if err := sriovutils.AnnounceIPs(req.IfName, newResult.IPs); err != nil {
+				klog.Warningf("Failed to announce the IPs of %s: %v", req.IfName, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	newResult.DNS = conf.DNS
@@ -334,16 +487,67 @@ func CmdDel(req *cnitypes.PodRequest) error {
 	klog.Infof("CmdDel: Netns: %q", req.Netns)
 
 	if conf.IPAM.Type != "" {
-		if err := ipam.ExecDel(conf.IPAM.Type, req.CNIReq.Config); err != nil {
+		if err := cnihelper.IpamExecDel(req, conf.IPAM.Type); err != nil {
 			return err
 		}
 	}
 
 	klog.Infof("CmdDel: Running IPAM %q", conf.IPAM.Type)
 
+	// A netdev moved into the pod is no longer visible here, only DPDK
+	// devices can be recognized by their PCI address
+	dpdk, err := isDpdkDevice(conf.DeviceID)
+	if err != nil {
+		return err
+	}
+	if dpdk {
+		klog.Infof("CmdDel: DPDK device %s was not moved", conf.DeviceID)
+		return nil
+	}
+
+	// The original link attributes were recorded by the caller of CmdAdd. The
+	// device is returned to the host even if they cannot be restored.
+	err = containerNs.Do(func(_ ns.NetNS) error {
+		return sriovutils.RestoreLinkConfig(&sriovutils.MyNetlink{}, req.IfName, conf.LinkConfig, conf.OrigVfState.Link)
+	})
+	if err != nil {
+		klog.Errorf("CmdDel: failed to restore the link attributes of %s: %v", req.IfName, err)
+	}
+
 	if err := moveLinkOutToHost(containerNs, req.IfName); err != nil {
 		return err
 	}
 
 	return nil
 }
+
+// CmdCheck verifies that the network function interface is still in the pod
+// with the MAC address recorded by CmdAdd. A DPDK device is never moved into
+// the pod, it only has to still be bound to its dpdk driver.
+func CmdCheck(req *cnitypes.PodRequest, mac string) error {
+	klog.Info("CmdCheck called for networkfn")
+
+	dpdk, err := isDpdkDevice(req.CNIConf.DeviceID)
+	if err != nil {
+		return err
+	}
+	if dpdk {
+		return nil
+	}
+	containerNs, err := ns.GetNS(req.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %v", req.Netns, err)
+	}
+	defer containerNs.Close()
+
+	return containerNs.Do(func(_ ns.NetNS) error {
+		link, err := netlink.LinkByName(req.IfName)
+		if err != nil {
+			return fmt.Errorf("failed to find %s in netns %s: %v", req.IfName, req.Netns, err)
+		}
+		if got := link.Attrs().HardwareAddr.String(); mac != "" && got != mac {
+			return fmt.Errorf("%s has MAC address %s instead of %s", req.IfName, got, mac)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,13 @@
+package networkfn
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNetworkfn(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Networkfn Suite")
+}
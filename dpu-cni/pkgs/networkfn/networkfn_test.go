@@ -0,0 +1,75 @@
+package networkfn
+
+import (
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+)
+
+var _ = g.Describe("Networkfn device resolution", func() {
+	var sysfs string
+
+	// addPciDevice adds a device bound to driver to the fake sysfs, with a
+	// netdev if linkName is set.
+	addPciDevice := func(pciAddress string, driver string, linkName string) {
+		dev := filepath.Join(sysfs, "devices", pciAddress)
+		o.Expect(os.MkdirAll(dev, 0755)).To(o.Succeed())
+		drv := filepath.Join(sysfs, "drivers", driver)
+		o.Expect(os.MkdirAll(drv, 0755)).To(o.Succeed())
+		o.Expect(os.Symlink(drv, filepath.Join(dev, "driver"))).To(o.Succeed())
+		if linkName != "" {
+			o.Expect(os.MkdirAll(filepath.Join(dev, "net", linkName), 0755)).To(o.Succeed())
+		}
+	}
+
+	g.BeforeEach(func() {
+		sysfs = g.GinkgoT().TempDir()
+		origSysBusPci, origSysBusAux, origNetDirectory := sriovutils.SysBusPci, sriovutils.SysBusAux, sriovutils.NetDirectory
+		sriovutils.SysBusPci = filepath.Join(sysfs, "devices")
+		sriovutils.SysBusAux = filepath.Join(sysfs, "aux")
+		sriovutils.NetDirectory = filepath.Join(sysfs, "net")
+		g.DeferCleanup(func() {
+			sriovutils.SysBusPci, sriovutils.SysBusAux, sriovutils.NetDirectory = origSysBusPci, origSysBusAux, origNetDirectory
+		})
+	})
+
+	g.It("should use an interface name as is", func() {
+		dev, err := resolveDevice("nf_interface0")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(*dev).To(o.Equal(nfDevice{linkName: "nf_interface0"}))
+	})
+
+	g.It("should find the netdev of a PCI address", func() {
+		addPciDevice("0000:02:00.1", "octeon_ep_vf", "enP2p0s1")
+		dev, err := resolveDevice("0000:02:00.1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(*dev).To(o.Equal(nfDevice{pciAddress: "0000:02:00.1", linkName: "enP2p0s1"}))
+	})
+
+	g.It("should recognize a device bound to vfio-pci", func() {
+		addPciDevice("0000:02:00.2", "vfio-pci", "")
+		dev, err := resolveDevice("0000:02:00.2")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(*dev).To(o.Equal(nfDevice{pciAddress: "0000:02:00.2", dpdk: true}))
+
+		dpdk, err := isDpdkDevice("0000:02:00.2")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(dpdk).To(o.BeTrue())
+	})
+
+	g.It("should reject a device without netdev bound to a kernel driver", func() {
+		addPciDevice("0000:02:00.3", "octeon_ep_vf", "")
+		_, err := resolveDevice("0000:02:00.3")
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("neither an interface nor a dpdk driver")))
+	})
+
+	g.It("should find the netdev of a sub function", func() {
+		o.Expect(os.MkdirAll(filepath.Join(sysfs, "aux", "mlx5_core.sf.4", "net", "enp3s0f0s4"), 0755)).To(o.Succeed())
+		dev, err := resolveDevice("mlx5_core.sf.4")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(*dev).To(o.Equal(nfDevice{linkName: "enp3s0f0s4"}))
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 	"k8s.io/klog/v2"
@@ -11,40 +12,66 @@ import (
 	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ipam"
+	nadapi "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnihelper"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovconfig"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
 	"github.com/vishvananda/netlink"
 )
 
-const (
-	// TODO: Some vendors have issues with Host VLANs, thus disable the feature for now
-	host_vlans = false
-)
+// vendorCapabilities are the VF capabilities of the PFs of known vendors by
+// PCI vendor ID. They are used if the VSP does not report any. Some vendors
+// have issues with netlink, so PFs of other vendors get none.
+var vendorCapabilities = map[string]cnitypes.VfCapabilities{
+	"0x8086": {Vlan: true, State: true}, // Intel
+}
 
 type pciUtils interface {
 	GetSriovNumVfs(ifName string) (int, error)
 	GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error)
 	GetPciAddress(ifName string, vf int) (string, error)
 	EnableArpAndNdiscNotify(ifName string) error
+	GetPfVendor(ifName string) (string, error)
 }
 
-type pciUtilsImpl struct{}
+type pciUtilsImpl struct {
+	sysfs *sriovutils.Sysfs
+}
 
 func (p *pciUtilsImpl) GetSriovNumVfs(ifName string) (int, error) {
-	return sriovutils.GetSriovNumVfs(ifName)
+	return p.sysfs.GetSriovNumVfs(ifName)
 }
 
 func (p *pciUtilsImpl) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
-	return sriovutils.GetVFLinkNamesFromVFID(pfName, vfID)
+	return p.sysfs.GetVFLinkNamesFromVFID(pfName, vfID)
 }
 
 func (p *pciUtilsImpl) GetPciAddress(ifName string, vf int) (string, error) {
-	return sriovutils.GetPciAddress(ifName, vf)
+	return p.sysfs.GetPciAddress(ifName, vf)
 }
 
 func (p *pciUtilsImpl) EnableArpAndNdiscNotify(ifName string) error {
-	return sriovutils.EnableArpAndNdiscNotify(ifName)
+	return p.sysfs.EnableArpAndNdiscNotify(ifName)
+}
+
+func (p *pciUtilsImpl) GetPfVendor(ifName string) (string, error) {
+	return p.sysfs.GetPfVendor(ifName)
+}
+
+type nsUtils interface {
+	GetNS(nspath string) (ns.NetNS, error)
+	GetCurrentNS() (ns.NetNS, error)
+}
+
+type nsUtilsImpl struct{}
+
+func (n *nsUtilsImpl) GetNS(nspath string) (ns.NetNS, error) {
+	return ns.GetNS(nspath)
+}
+
+func (n *nsUtilsImpl) GetCurrentNS() (ns.NetNS, error) {
+	return ns.GetCurrentNS()
 }
 
 // Manager provides interface invoke sriov nic related operations
@@ -54,21 +81,101 @@ type Manager interface {
 	ResetVFConfig(conf *cnitypes.NetConf) error
 	ApplyVFConfig(conf *cnitypes.NetConf) error
 	FillOriginalVfInfo(conf *cnitypes.NetConf) error
+	SetCapabilities(caps cnitypes.VfCapabilities)
 	CmdAdd(req *cnitypes.PodRequest) (*current.Result, error)
 	CmdDel(req *cnitypes.PodRequest) error
+	CmdCheck(req *cnitypes.PodRequest) error
+	StaleAttachments(req *cnitypes.PodRequest) ([]*cnitypes.PodRequest, error)
+	CmdGC(req *cnitypes.PodRequest) error
+	ReleaseStaleAllocations() error
 }
 
 type sriovManager struct {
 	nLink sriovutils.NetlinkManager
 	utils pciUtils
+	ns    nsUtils
+	sysfs *sriovutils.Sysfs
+	// cniDir holds the cached NetConfs and PCI allocations of the
+	// attachments
+	cniDir string
+	conf   *sriovconfig.Loader
+
+	// capsMu guards caps, the VF capabilities reported by the VSP. Without
+	// them the capabilities are looked up by the vendor of the PF.
+	capsMu sync.Mutex
+	caps   *cnitypes.VfCapabilities
+}
+
+// WithSysfsRoot makes the manager find the devices in the sysfs below root
+// instead of the one of the node.
+func WithSysfsRoot(root string) func(*sriovManager) {
+	return func(s *sriovManager) {
+		s.sysfs = sriovutils.NewSysfs(root)
+	}
+}
+
+// WithCNIDir makes the manager keep the cached NetConfs and PCI allocations
+// in dir instead of sriovconfig.DefaultCNIDir.
+func WithCNIDir(dir string) func(*sriovManager) {
+	return func(s *sriovManager) {
+		s.cniDir = dir
+	}
 }
 
 // NewSriovManager returns an instance of SriovManager
-func NewSriovManager() *sriovManager {
-	return &sriovManager{
-		nLink: &sriovutils.MyNetlink{},
-		utils: &pciUtilsImpl{},
+func NewSriovManager(opts ...func(*sriovManager)) *sriovManager {
+	s := &sriovManager{
+		nLink:  &sriovutils.MyNetlink{},
+		ns:     &nsUtilsImpl{},
+		sysfs:  sriovutils.NewSysfs("/"),
+		cniDir: sriovconfig.DefaultCNIDir,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.utils = &pciUtilsImpl{sysfs: s.sysfs}
+	s.conf = sriovconfig.NewLoader(s.sysfs, s.cniDir)
+	return s
+}
+
+// SetCapabilities sets the VF capabilities of all PFs, overriding the ones
+// known for their vendor.
+func (s *sriovManager) SetCapabilities(caps cnitypes.VfCapabilities) {
+	s.capsMu.Lock()
+	defer s.capsMu.Unlock()
+	s.caps = &caps
+}
+
+// capabilities returns the VF capabilities of PF pfName.
+func (s *sriovManager) capabilities(pfName string) cnitypes.VfCapabilities {
+	s.capsMu.Lock()
+	caps := s.caps
+	s.capsMu.Unlock()
+	if caps != nil {
+		return *caps
+	}
+	vendor, err := s.utils.GetPfVendor(pfName)
+	if err != nil {
+		klog.Warningf("Disabling VF capabilities of PF %s: %v", pfName, err)
+		return cnitypes.VfCapabilities{}
+	}
+	return vendorCapabilities[vendor]
+}
+
+// checkPfMTU verifies that the MTU of the pod interface does not exceed the
+// MTU of its PF, the VF cannot send larger frames.
+func (s *sriovManager) checkPfMTU(conf *cnitypes.NetConf) error {
+	if conf.MTU == 0 {
+		return nil
 	}
+	pfLink, err := s.nLink.LinkByName(conf.Master)
+	if err != nil {
+		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
+	}
+	if pfMTU := pfLink.Attrs().MTU; conf.MTU > pfMTU {
+		return fmt.Errorf("mtu %d exceeds the mtu %d of PF %s", conf.MTU, pfMTU, conf.Master)
+	}
+	return nil
 }
 
 // SetupVF sets up a VF in Pod netns
@@ -101,6 +208,8 @@ func (s *sriovManager) SetupVF(conf *cnitypes.NetConf, podifName string, netns n
 	// 3. Change netns
 	klog.Infof("3. Change netns %+v %d", linkObj, int(netns.Fd()))
 	if err := s.nLink.LinkSetNsFd(linkObj, int(netns.Fd())); err != nil {
+		// Give the VF its name back, ReleaseVF only finds it in the pod
+		_ = s.nLink.LinkSetName(linkObj, linkName)
 		return fmt.Errorf("failed to move IF %s to netns: %q", tempName, err)
 	}
 
@@ -125,8 +234,18 @@ func (s *sriovManager) SetupVF(conf *cnitypes.NetConf, podifName string, netns n
 			}
 		}
 
-		// 7. Bring IF up in Pod netns
-		klog.Infof("7. Bring IF up in Pod netns %+v", linkObj)
+		// 7. Set the link attributes, the original ones are cached for ReleaseVF
+		if conf.LinkConfig.IsSet() {
+			klog.Infof("7. Set link attributes %s %+v", podifName, conf.LinkConfig)
+			orig, err := sriovutils.ApplyLinkConfig(s.nLink, podifName, conf.LinkConfig)
+			if err != nil {
+				return err
+			}
+			conf.OrigVfState.Link = orig
+		}
+
+		// 8. Bring IF up in Pod netns
+		klog.Infof("8. Bring IF up in Pod netns %+v", linkObj)
 		if err := s.nLink.LinkSetUp(linkObj); err != nil {
 			return fmt.Errorf("error bringing interface up in container ns: %q", err)
 		}
@@ -141,7 +260,7 @@ func (s *sriovManager) SetupVF(conf *cnitypes.NetConf, podifName string, netns n
 
 // ReleaseVF reset a VF from Pod netns and return it to init netns
 func (s *sriovManager) ReleaseVF(conf *cnitypes.NetConf, podifName string, netns ns.NetNS) error {
-	initns, err := ns.GetCurrentNS()
+	initns, err := s.ns.GetCurrentNS()
 	if err != nil {
 		return fmt.Errorf("failed to get init netns: %v", err)
 	}
@@ -160,6 +279,12 @@ func (s *sriovManager) ReleaseVF(conf *cnitypes.NetConf, podifName string, netns
 			return fmt.Errorf("failed to set link %s down: %q", podifName, err)
 		}
 
+		// restore the link attributes SetupVF changed
+		klog.Infof("Restore link attributes %s %+v", podifName, conf.OrigVfState.Link)
+		if err = sriovutils.RestoreLinkConfig(s.nLink, podifName, conf.LinkConfig, conf.OrigVfState.Link); err != nil {
+			return err
+		}
+
 		// rename VF device
 		klog.Infof("Rename VF device %+v %s", linkObj, conf.OrigVfState.HostIFName)
 		err = s.nLink.LinkSetName(linkObj, conf.OrigVfState.HostIFName)
@@ -203,7 +328,7 @@ func (s *sriovManager) ApplyVFConfig(conf *cnitypes.NetConf) error {
 		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
 	}
 	// 1. Set vlan
-	if host_vlans {
+	if conf.Capabilities.Vlan {
 		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, *conf.Vlan, *conf.VlanQoS, cnitypes.VlanProtoInt[*conf.VlanProto]); err != nil {
 			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %s: %v", conf.VFID, *conf.Vlan, *conf.VlanQoS, *conf.VlanProto, err)
 		}
@@ -297,69 +422,119 @@ func (s *sriovManager) FillOriginalVfInfo(conf *cnitypes.NetConf) error {
 	return err
 }
 
+// resetVfState returns the state ResetVFConfig restores the VF of conf to:
+// the state saved by FillOriginalVfInfo if the PF supports it, the defaults of
+// a new VF otherwise.
+func resetVfState(conf *cnitypes.NetConf) cnitypes.VfState {
+	if conf.Capabilities.State {
+		return conf.OrigVfState
+	}
+	return cnitypes.VfState{
+		SpoofChk:  true,
+		LinkState: netlink.VF_LINK_STATE_AUTO,
+	}
+}
+
 // ResetVFConfig reset a VF to its original state
 func (s *sriovManager) ResetVFConfig(conf *cnitypes.NetConf) error {
 	pfLink, err := s.nLink.LinkByName(conf.Master)
 	if err != nil {
 		return fmt.Errorf("failed to lookup master %q: %v", conf.Master, err)
 	}
+	state := resetVfState(conf)
 
-	if host_vlans {
+	if conf.Capabilities.Vlan {
 		// Set 802.1q as default in case cache config does not have a value for vlan proto.
-		if conf.OrigVfState.VlanProto == 0 {
-			conf.OrigVfState.VlanProto = cnitypes.VlanProtoInt[cnitypes.Proto8021q]
+		if state.VlanProto == 0 {
+			state.VlanProto = cnitypes.VlanProtoInt[cnitypes.Proto8021q]
 		}
 
-		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto); err != nil {
-			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %d: %v", conf.VFID, conf.OrigVfState.Vlan, conf.OrigVfState.VlanQoS, conf.OrigVfState.VlanProto, err)
+		if err = s.nLink.LinkSetVfVlanQosProto(pfLink, conf.VFID, state.Vlan, state.VlanQoS, state.VlanProto); err != nil {
+			return fmt.Errorf("failed to set vf %d vlan configuration - id %d, qos %d and proto %d: %v", conf.VFID, state.Vlan, state.VlanQoS, state.VlanProto, err)
 		}
 	}
 
 	// Restore spoofchk
 	if conf.SpoofChk != "" {
-		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, conf.OrigVfState.SpoofChk); err != nil {
+		if err = s.nLink.LinkSetVfSpoofchk(pfLink, conf.VFID, state.SpoofChk); err != nil {
 			return fmt.Errorf("failed to restore spoofchk for vf %d: %v", conf.VFID, err)
 		}
 	}
 
-	// Restore the original administrative MAC address
-	if conf.MAC != "" {
+	// Restore the original administrative MAC address. Without the saved
+	// state it is unknown, so the next ADD overwrites the MAC instead.
+	if conf.MAC != "" && state.AdminMAC != "" {
 		// when we restore the original hardware mac address we may get a device or resource busy. so we introduce retry
-		if err := sriovutils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, conf.OrigVfState.AdminMAC); err != nil {
-			return fmt.Errorf("failed to restore original administrative MAC address %s: %v", conf.OrigVfState.AdminMAC, err)
+		if err := sriovutils.SetVFHardwareMAC(s.nLink, conf.Master, conf.VFID, state.AdminMAC); err != nil {
+			return fmt.Errorf("failed to restore original administrative MAC address %s: %v", state.AdminMAC, err)
 		}
 	}
 
 	// Restore VF trust
 	if conf.Trust != "" {
-		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, conf.OrigVfState.Trust); err != nil {
+		if err = s.nLink.LinkSetVfTrust(pfLink, conf.VFID, state.Trust); err != nil {
 			return fmt.Errorf("failed to set trust for vf %d: %v", conf.VFID, err)
 		}
 	}
 
 	// Restore rate limiting
 	if conf.MinTxRate != nil || conf.MaxTxRate != nil {
-		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, conf.OrigVfState.MinTxRate, conf.OrigVfState.MaxTxRate); err != nil {
+		if err = s.nLink.LinkSetVfRate(pfLink, conf.VFID, state.MinTxRate, state.MaxTxRate); err != nil {
 			return fmt.Errorf("failed to disable rate limiting for vf %d %v", conf.VFID, err)
 		}
 	}
 
-	// Restore link state to `auto`
+	// Restore link state
 	if conf.LinkState != "" {
 		// Reset only when link_state was explicitly specified, to  accommodate for drivers / NICs
 		// that don't support the netlink command (e.g. igb driver)
-		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, conf.OrigVfState.LinkState); err != nil {
-			return fmt.Errorf("failed to set link state to auto for vf %d: %v", conf.VFID, err)
+		if err = s.nLink.LinkSetVfState(pfLink, conf.VFID, state.LinkState); err != nil {
+			return fmt.Errorf("failed to set link state to %d for vf %d: %v", state.LinkState, conf.VFID, err)
 		}
 	}
 
 	return nil
 }
 
+// vfDeviceInfo returns the device-info of the VF with PCI address vfPci
+func (sm *sriovManager) vfDeviceInfo(vfPci string) cnitypes.DeviceInfo {
+	pci := &nadapi.PciDevice{PciAddress: vfPci}
+	pfPci, err := sm.sysfs.GetPfPci(vfPci)
+	if err != nil {
+		klog.Warningf("Leaving the PF out of the device-info of VF %s: %v", vfPci, err)
+	}
+	pci.PfPciAddress = pfPci
+	return cnitypes.DeviceInfo{
+		DeviceInfo: nadapi.DeviceInfo{
+			Type:    nadapi.DeviceInfoTypePCI,
+			Version: nadapi.DeviceInfoVersion,
+			Pci:     pci,
+		},
+	}
+}
+
+// sfDeviceInfo returns the device-info of the sub function with auxiliary
+// device auxDev and sfnum sfNum
+func (sm *sriovManager) sfDeviceInfo(auxDev string, sfNum int) cnitypes.DeviceInfo {
+	aux := &cnitypes.AuxiliaryDevice{DeviceID: auxDev, SFNum: sfNum}
+	pfPci, err := sm.sysfs.GetPfPciFromAux(auxDev)
+	if err != nil {
+		klog.Warningf("Leaving the PF out of the device-info of sub function %s: %v", auxDev, err)
+	}
+	aux.PfPciAddress = pfPci
+	return cnitypes.DeviceInfo{
+		DeviceInfo: nadapi.DeviceInfo{
+			Type:    cnitypes.DeviceInfoTypeAuxiliary,
+			Version: nadapi.DeviceInfoVersion,
+		},
+		Auxiliary: aux,
+	}
+}
+
 func (sm *sriovManager) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 	klog.Info("CmdAdd called")
 
-	netConf, err := sriovconfig.LoadConf(req.CNIConf)
+	netConf, err := sm.conf.LoadConf(req.CNIConf)
 	if err != nil {
 		return nil, fmt.Errorf("SRIOV-CNI failed to load netconf: %v", err)
 	}
@@ -374,31 +549,62 @@ func (sm *sriovManager) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error
 	// Always use lower case for mac address
 	netConf.MAC = strings.ToLower(netConf.MAC)
 
-	netns, err := ns.GetNS(req.Netns)
+	netns, err := sm.ns.GetNS(req.Netns)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open netns %q: %v", netns, err)
+		return nil, fmt.Errorf("failed to open netns %q: %v", req.Netns, err)
 	}
 	defer netns.Close()
 
-	// TODO: Some vendors have issues with Netlink, thus disable the feature for now
-	// err = sm.FillOriginalVfInfo(netConf)
-	// if err != nil {
-	// 	return nil, fmt.Errorf("failed to get original vf information: %v", err)
-	// }
+	// Mark the pci address as in use before touching the VF. LoadConf only
+	// checked it, concurrent ADDs for the same VF are excluded here.
+	klog.Infof("Mark the PCI address as in use %s %s", sm.cniDir, netConf.DeviceID)
+	allocator := sriovutils.NewPCIAllocator(sm.cniDir)
+	allocation := sriovutils.Allocation{Netns: req.Netns, PodUID: req.PodUID, ContainerID: req.ContainerId}
+	if err = allocator.Allocate(netConf.DeviceID, allocation); err != nil {
+		return nil, fmt.Errorf("error saving the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
+	}
+	defer func() {
+		if err != nil {
+			_ = allocator.DeleteAllocatedPCI(netConf.DeviceID, req.ContainerId)
+		}
+	}()
+
+	if netConf.DPDKMode && netConf.LinkConfig.IsSet() {
+		klog.Warningf("Ignoring the link attributes of DPDK VF %s, it has no netdev", netConf.DeviceID)
+	} else if err = sm.checkPfMTU(netConf); err != nil {
+		return nil, err
+	}
+
+	if !netConf.IsSF() {
+		netConf.Capabilities = sm.capabilities(netConf.Master)
+		klog.Infof("VF capabilities of PF %s: %+v", netConf.Master, netConf.Capabilities)
+	}
+	if netConf.Capabilities.State {
+		if err = sm.FillOriginalVfInfo(netConf); err != nil {
+			return nil, fmt.Errorf("failed to get original vf information: %v", err)
+		}
+	}
 	defer func() {
 		if err != nil {
 			err := netns.Do(func(_ ns.NetNS) error {
-				_, err := netlink.LinkByName(req.IfName)
+				_, err := sm.nLink.LinkByName(req.IfName)
 				return err
 			})
 			if err == nil {
 				_ = sm.ReleaseVF(netConf, req.IfName, netns)
 			}
 			// Reset the VF if failure occurs before the netconf is cached
-			_ = sm.ResetVFConfig(netConf)
+			if !netConf.IsSF() {
+				_ = sm.ResetVFConfig(netConf)
+			}
 		}
 	}()
-	if err := sm.ApplyVFConfig(netConf); err != nil {
+	// A sub function has no VF state on the PF, the DPU enforces its
+	// policy on the bridge port
+	if netConf.IsSF() {
+		klog.Infof("Skipping VF configuration of sub function %s (sfnum %d)", netConf.DeviceID, netConf.SFNum)
+	} else if err = sm.ApplyVFConfig(netConf); err != nil {
+		// Assign err so that the deferred reset undoes a partial configuration
 		return nil, fmt.Errorf("SRIOV-CNI failed to configure VF %q", err)
 	}
 
@@ -419,18 +625,32 @@ func (sm *sriovManager) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error
 
 	result.Interfaces[0].Mac = sriovconfig.GetMacAddressForResult(netConf)
 
+	// The device plugin knows the device best, only fill in what it did not
+	if req.DeviceInfo.Type == "" {
+		if netConf.IsSF() {
+			req.DeviceInfo = sm.sfDeviceInfo(netConf.DeviceID, netConf.SFNum)
+		} else {
+			req.DeviceInfo = sm.vfDeviceInfo(netConf.DeviceID)
+		}
+	}
+
+	// Do not start on IPAM if the runtime gave up on the request already
+	if err = req.Ctx.Err(); err != nil {
+		return nil, fmt.Errorf("request expired before running the IPAM plugin: %v", err)
+	}
+
 	// run the IPAM plugin
 	if netConf.IPAM.Type != "" {
 		klog.Infof("Executing Ipam plugin. IPAM type: %s", netConf.IPAM.Type)
 		var r types.Result
-		r, err = ipam.ExecAdd(netConf.IPAM.Type, req.CNIReq.Config)
+		r, err = cnihelper.IpamExecAdd(req, netConf.IPAM.Type)
 		if err != nil {
 			return nil, fmt.Errorf("failed to set up IPAM plugin type %q from the device %q: %v", netConf.IPAM.Type, netConf.Master, err)
 		}
 
 		defer func() {
 			if err != nil {
-				_ = ipam.ExecDel(netConf.IPAM.Type, req.CNIReq.Config)
+				_ = cnihelper.IpamExecDel(req, netConf.IPAM.Type)
 			}
 		}()
 
@@ -472,9 +692,11 @@ func (sm *sriovManager) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error
 				 * Solicitation packets when the connection is unreachable. This would correct the invalid cache; however this may take a significant
 				 * amount of time to complete.
 				 *
-				 * The error is ignored here because enabling this feature is only a performance enhancement.
+				 * The error is only logged here because enabling this feature is only a performance enhancement.
 				 */
-				_ = sriovutils.AnnounceIPs(req.IfName, newResult.IPs)
+				if err := sriovutils.AnnounceIPs(req.IfName, newResult.IPs); err != nil {
+					klog.Warningf("Failed to announce the IPs of %s: %v", req.IfName, err)
+				}
 				return nil
 			})
 			if err != nil {
@@ -487,25 +709,20 @@ func (sm *sriovManager) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error
 	}
 
 	// Cache NetConf for CmdDel
-	klog.Infof("Cache NetConf for CmdDel %s %+v", sriovconfig.DefaultCNIDir, netConf)
-	if err = sriovutils.SaveNetConf(req.ContainerId, sriovconfig.DefaultCNIDir, req.IfName, netConf); err != nil {
+	klog.Infof("Cache NetConf for CmdDel %s %+v", sm.cniDir, netConf)
+	if err = sriovutils.SaveNetConf(req.ContainerId, sm.cniDir, req.IfName, netConf); err != nil {
 		return nil, fmt.Errorf("error saving NetConf %q", err)
 	}
 
-	// Mark the pci address as in use.
-	klog.Infof("Mark the PCI address as in use %s %s", sriovconfig.DefaultCNIDir, netConf.DeviceID)
-	allocator := sriovutils.NewPCIAllocator(sriovconfig.DefaultCNIDir)
-	if err = allocator.SaveAllocatedPCI(netConf.DeviceID, req.Netns); err != nil {
-		return nil, fmt.Errorf("error saving the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
-	}
-
 	return result, nil
 }
 
-func (sm *sriovManager) CmdDel(req *cnitypes.PodRequest) error {
+// CmdDel releases the VF of an attachment. The cached NetConf is kept if it
+// fails, so that the runtime can retry.
+func (sm *sriovManager) CmdDel(req *cnitypes.PodRequest) (err error) {
 	klog.Info("CmdDel called")
 
-	netConf, cRefPath, err := sriovconfig.LoadConfFromCache(req.ContainerId, req.IfName)
+	netConf, cRefPath, err := sm.conf.LoadConfFromCache(req.ContainerId, req.IfName)
 	if err != nil {
 		// If cmdDel() fails, cached netconf is cleaned up by
 		// the followed defer call. However, subsequence calls
@@ -525,7 +742,7 @@ func (sm *sriovManager) CmdDel(req *cnitypes.PodRequest) error {
 	}()
 
 	if netConf.IPAM.Type != "" {
-		err = ipam.ExecDel(netConf.IPAM.Type, req.CNIReq.Config)
+		err = cnihelper.IpamExecDel(req, netConf.IPAM.Type)
 		if err != nil {
 			return err
 		}
@@ -536,21 +753,27 @@ func (sm *sriovManager) CmdDel(req *cnitypes.PodRequest) error {
 		return nil
 	}
 
-	// Verify VF ID existence.
-	if _, err := sriovutils.GetVfid(netConf.DeviceID, netConf.Master); err != nil {
-		return fmt.Errorf("cmdDel() error obtaining VF ID: %q", err)
-	}
+	if !netConf.IsSF() {
+		// DPDKMode is not cached, a VF of a DPDK pod is still bound to its
+		// dpdk driver. A VF without driver is released like a netdev.
+		netConf.DPDKMode, _ = sm.sysfs.HasDpdkDriver(netConf.DeviceID)
 
-	/* ResetVFConfig resets a VF administratively. We must run ResetVFConfig
-	   before ReleaseVF because some drivers will error out if we try to
-	   reset netdev VF with trust off. So, reset VF MAC address via PF first.
-	*/
-	if err := sm.ResetVFConfig(netConf); err != nil {
-		return fmt.Errorf("cmdDel() error reseting VF: %q", err)
+		// Verify VF ID existence.
+		if _, err := sm.sysfs.GetVfid(netConf.DeviceID, netConf.Master); err != nil {
+			return fmt.Errorf("cmdDel() error obtaining VF ID: %q", err)
+		}
+
+		/* ResetVFConfig resets a VF administratively. We must run ResetVFConfig
+		   before ReleaseVF because some drivers will error out if we try to
+		   reset netdev VF with trust off. So, reset VF MAC address via PF first.
+		*/
+		if err := sm.ResetVFConfig(netConf); err != nil {
+			return fmt.Errorf("cmdDel() error reseting VF: %q", err)
+		}
 	}
 
 	if !netConf.DPDKMode {
-		netns, err := ns.GetNS(req.Netns)
+		netns, err := sm.ns.GetNS(req.Netns)
 		if err != nil {
 			// according to:
 			// https://github.com/kubernetes/kubernetes/issues/43014#issuecomment-287164444
@@ -571,13 +794,162 @@ func (sm *sriovManager) CmdDel(req *cnitypes.PodRequest) error {
 		}
 	}
 	req.CNIConf.VFID = netConf.VFID
+	req.CNIConf.SFNum = netConf.SFNum
 
 	// Mark the pci address as released
-	klog.Infof("Mark the PCI address as released %s %s", sriovconfig.DefaultCNIDir, netConf.DeviceID)
-	allocator := sriovutils.NewPCIAllocator(sriovconfig.DefaultCNIDir)
-	if err = allocator.DeleteAllocatedPCI(netConf.DeviceID); err != nil {
+	klog.Infof("Mark the PCI address as released %s %s", sm.cniDir, netConf.DeviceID)
+	allocator := sriovutils.NewPCIAllocator(sm.cniDir)
+	if err = allocator.DeleteAllocatedPCI(netConf.DeviceID, req.ContainerId); err != nil {
 		return fmt.Errorf("error cleaning the pci allocation for vf pci address %s: %v", netConf.DeviceID, err)
 	}
 
 	return nil
 }
+
+// prevResultMac returns the MAC address reported for ifName by the ADD result
+// the runtime passes to CHECK, or "" if there is none.
+func prevResultMac(conf *cnitypes.NetConf, ifName string) (string, error) {
+	if conf.PrevResult == nil {
+		return "", nil
+	}
+	prevResult, err := current.NewResultFromResult(conf.PrevResult)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert prevResult: %v", err)
+	}
+	for _, iface := range prevResult.Interfaces {
+		if iface.Name == ifName && iface.Sandbox != "" {
+			return iface.Mac, nil
+		}
+	}
+	return "", fmt.Errorf("interface %s is not in prevResult", ifName)
+}
+
+// CmdCheck verifies that the VF set up by CmdAdd is still in the pod with the
+// expected name, MAC address and VLAN.
+func (sm *sriovManager) CmdCheck(req *cnitypes.PodRequest) error {
+	klog.Info("CmdCheck called")
+
+	netConf, _, err := sm.conf.LoadConfFromCache(req.ContainerId, req.IfName)
+	if err != nil {
+		return fmt.Errorf("no attachment found for %s: %v", req.IfName, err)
+	}
+	req.CNIConf.VFID = netConf.VFID
+	req.CNIConf.SFNum = netConf.SFNum
+
+	if netConf.IPAM.Type != "" {
+		if err := cnihelper.IpamExecCheck(req, netConf.IPAM.Type); err != nil {
+			return fmt.Errorf("IPAM check failed: %v", err)
+		}
+	}
+
+	if netConf.Capabilities.Vlan && netConf.Vlan != nil && !netConf.IsSF() {
+		pfLink, err := sm.nLink.LinkByName(netConf.Master)
+		if err != nil {
+			return fmt.Errorf("failed to lookup master %q: %v", netConf.Master, err)
+		}
+		vfInfo := getVfInfo(pfLink, netConf.VFID)
+		if vfInfo == nil {
+			return fmt.Errorf("failed to find vf %d", netConf.VFID)
+		}
+		if vfInfo.Vlan != *netConf.Vlan {
+			return fmt.Errorf("vf %d has vlan %d, expected %d", netConf.VFID, vfInfo.Vlan, *netConf.Vlan)
+		}
+	}
+
+	if netConf.DPDKMode {
+		return nil
+	}
+
+	expectedMac := netConf.MAC
+	if expectedMac == "" {
+		expectedMac, err = prevResultMac(req.CNIConf, req.IfName)
+		if err != nil {
+			return err
+		}
+	}
+
+	netns, err := sm.ns.GetNS(req.Netns)
+	if err != nil {
+		return fmt.Errorf("failed to open netns %q: %v", req.Netns, err)
+	}
+	defer netns.Close()
+
+	return netns.Do(func(_ ns.NetNS) error {
+		linkObj, err := sm.nLink.LinkByName(req.IfName)
+		if err != nil {
+			return fmt.Errorf("interface %s not found in netns %s: %v", req.IfName, req.Netns, err)
+		}
+		mac := linkObj.Attrs().HardwareAddr.String()
+		if expectedMac != "" && !strings.EqualFold(mac, expectedMac) {
+			return fmt.Errorf("interface %s has MAC %s, expected %s", req.IfName, mac, expectedMac)
+		}
+		return nil
+	})
+}
+
+// StaleAttachments returns DEL requests for the attachments of the network
+// of the GC request req that are not among its valid attachments.
+func (sm *sriovManager) StaleAttachments(req *cnitypes.PodRequest) ([]*cnitypes.PodRequest, error) {
+	valid := map[string]bool{}
+	for _, a := range req.CNIConf.ValidAttachments {
+		valid[a.ContainerID+"/"+a.IfName] = true
+	}
+
+	cached, err := sm.conf.ListCachedConfs()
+	if err != nil {
+		return nil, err
+	}
+
+	allocator := sriovutils.NewPCIAllocator(sm.cniDir)
+	var stale []*cnitypes.PodRequest
+	for _, c := range cached {
+		if c.NetConf.Name != req.CNIConf.Name || valid[c.ContainerID+"/"+c.IfName] {
+			continue
+		}
+		netns, err := allocator.AllocatedNetns(c.NetConf.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+		stale = append(stale, &cnitypes.PodRequest{
+			Command:     cnitypes.CNIDel,
+			ContainerId: c.ContainerID,
+			Netns:       netns,
+			IfName:      c.IfName,
+			Path:        req.Path,
+			CNIConf:     c.NetConf,
+			CNIReq:      req.CNIReq,
+			NetName:     req.NetName,
+			Timestamp:   req.Timestamp,
+			Ctx:         req.Ctx,
+			Cancel:      func() {},
+		})
+	}
+	return stale, nil
+}
+
+// CmdGC lets the IPAM plugin release the addresses of the attachments that
+// are not among the valid attachments of req. The VFs of stale attachments
+// are released with CmdDel on the requests from StaleAttachments.
+func (sm *sriovManager) CmdGC(req *cnitypes.PodRequest) error {
+	klog.Info("CmdGC called")
+	if req.CNIConf.IPAM.Type == "" {
+		return nil
+	}
+	return cnihelper.IpamExecGC(req, req.CNIConf.IPAM.Type)
+}
+
+// ReleaseStaleAllocations releases the PCI allocations of network namespaces
+// that no longer exist, e.g. of pods deleted while the daemon was down, so
+// that their VFs can be allocated again. The VFs themselves are not reset, the
+// kernel moved their netdevs back to the host when the namespace was deleted
+// and the next ADD configures them again.
+func (sm *sriovManager) ReleaseStaleAllocations() error {
+	released, err := sriovutils.NewPCIAllocator(sm.cniDir).ReleaseStale()
+	for _, a := range released {
+		klog.Infof("Released PCI address %s of container %s of pod %s, its netns %s is gone", a.PciAddress, a.ContainerID, a.PodUID, a.Netns)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release stale PCI allocations: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,621 @@
+package sriov
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/containernetworking/cni/pkg/types"
+	"github.com/containernetworking/plugins/pkg/ns"
+	nadapi "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+	"github.com/vishvananda/netlink"
+)
+
+const (
+	hostNetns = 0
+	podNetns  = 1
+	// podNetnsPath must exist, the PCI allocator checks that the netns of an
+	// allocation is still there
+	podNetnsPath = "/proc/self/ns/net"
+
+	pfName  = "ens1f0"
+	pfPci   = "0000:01:00.0"
+	vfPci   = "0000:01:00.2"
+	vfName  = "ens1f0v0"
+	vfMac   = "02:00:00:00:00:01"
+	dpdkPci = "0000:01:00.3"
+	podMac  = "02:aa:bb:cc:dd:ee"
+)
+
+// fakeNetlink is a NetlinkManager keeping links in fake network namespaces,
+// the netns of the calls is switched by fakeNetNS.Do.
+type fakeNetlink struct {
+	links    []*netlink.Device
+	netns    map[*netlink.Device]int
+	features map[*netlink.Device]map[string]bool
+	current  int
+	// failures makes the calls of a method fail, by method name
+	failures map[string]error
+	calls    []string
+}
+
+func newFakeNetlink() *fakeNetlink {
+	return &fakeNetlink{
+		netns:    map[*netlink.Device]int{},
+		features: map[*netlink.Device]map[string]bool{},
+		failures: map[string]error{},
+	}
+}
+
+func (f *fakeNetlink) addLink(name string, mac string, vfs int) *netlink.Device {
+	hwaddr, _ := net.ParseMAC(mac)
+	link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: name, Index: len(f.links) + 1, HardwareAddr: hwaddr, MTU: 1500, TxQLen: 1000}}
+	for vf := 0; vf < vfs; vf++ {
+		link.Vfs = append(link.Vfs, netlink.VfInfo{ID: vf, Mac: net.HardwareAddr{0, 0, 0, 0, 0, 0}, Spoofchk: true})
+	}
+	f.links = append(f.links, link)
+	f.netns[link] = hostNetns
+	f.features[link] = map[string]bool{"rx-gro": true, "tx-tcp-segmentation": true}
+	return link
+}
+
+// find returns the link with the given name in netns, or nil
+func (f *fakeNetlink) find(netns int, name string) *netlink.Device {
+	for _, l := range f.links {
+		if l.Name == name && f.netns[l] == netns {
+			return l
+		}
+	}
+	return nil
+}
+
+func (f *fakeNetlink) call(method string) error {
+	f.calls = append(f.calls, method)
+	return f.failures[method]
+}
+
+func (f *fakeNetlink) LinkByName(name string) (netlink.Link, error) {
+	if l := f.find(f.current, name); l != nil {
+		return l, nil
+	}
+	return nil, netlink.LinkNotFoundError{}
+}
+
+func (f *fakeNetlink) LinkSetVfVlanQosProto(link netlink.Link, vf, vlan, qos, proto int) error {
+	if err := f.call("LinkSetVfVlanQosProto"); err != nil {
+		return err
+	}
+	info := &link.Attrs().Vfs[vf]
+	info.Vlan, info.Qos, info.VlanProto = vlan, qos, proto
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetVfHardwareAddr(link netlink.Link, vf int, hwaddr net.HardwareAddr) error {
+	if err := f.call("LinkSetVfHardwareAddr"); err != nil {
+		return err
+	}
+	link.Attrs().Vfs[vf].Mac = hwaddr
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetHardwareAddr(link netlink.Link, hwaddr net.HardwareAddr) error {
+	if err := f.call("LinkSetHardwareAddr"); err != nil {
+		return err
+	}
+	link.Attrs().HardwareAddr = hwaddr
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetUp(link netlink.Link) error {
+	if err := f.call("LinkSetUp"); err != nil {
+		return err
+	}
+	link.Attrs().Flags |= net.FlagUp
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetDown(link netlink.Link) error {
+	if err := f.call("LinkSetDown"); err != nil {
+		return err
+	}
+	link.Attrs().Flags &^= net.FlagUp
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetNsFd(link netlink.Link, fd int) error {
+	if err := f.call("LinkSetNsFd"); err != nil {
+		return err
+	}
+	f.netns[link.(*netlink.Device)] = fd
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetName(link netlink.Link, name string) error {
+	if err := f.call("LinkSetName"); err != nil {
+		return err
+	}
+	if f.find(f.netns[link.(*netlink.Device)], name) != nil {
+		return fmt.Errorf("link %s exists", name)
+	}
+	link.Attrs().Name = name
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetVfRate(link netlink.Link, vf int, minRate int, maxRate int) error {
+	if err := f.call("LinkSetVfRate"); err != nil {
+		return err
+	}
+	info := &link.Attrs().Vfs[vf]
+	info.MinTxRate, info.MaxTxRate = uint32(minRate), uint32(maxRate)
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetVfSpoofchk(link netlink.Link, vf int, check bool) error {
+	if err := f.call("LinkSetVfSpoofchk"); err != nil {
+		return err
+	}
+	link.Attrs().Vfs[vf].Spoofchk = check
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetVfTrust(link netlink.Link, vf int, state bool) error {
+	if err := f.call("LinkSetVfTrust"); err != nil {
+		return err
+	}
+	link.Attrs().Vfs[vf].Trust = 0
+	if state {
+		link.Attrs().Vfs[vf].Trust = 1
+	}
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
+	if err := f.call("LinkSetVfState"); err != nil {
+		return err
+	}
+	link.Attrs().Vfs[vf].LinkState = state
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetMTU(link netlink.Link, mtu int) error {
+	if err := f.call("LinkSetMTU"); err != nil {
+		return err
+	}
+	link.Attrs().MTU = mtu
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetAlias(link netlink.Link, name string) error {
+	if err := f.call("LinkSetAlias"); err != nil {
+		return err
+	}
+	link.Attrs().Alias = name
+	return nil
+}
+
+func (f *fakeNetlink) LinkSetTxQLen(link netlink.Link, qlen int) error {
+	if err := f.call("LinkSetTxQLen"); err != nil {
+		return err
+	}
+	link.Attrs().TxQLen = qlen
+	return nil
+}
+
+func (f *fakeNetlink) EthtoolFeatures(ifName string) (map[string]bool, error) {
+	l := f.find(f.current, ifName)
+	if l == nil {
+		return nil, netlink.LinkNotFoundError{}
+	}
+	features := map[string]bool{}
+	for name, on := range f.features[l] {
+		features[name] = on
+	}
+	return features, nil
+}
+
+func (f *fakeNetlink) SetEthtoolFeatures(ifName string, features map[string]bool) error {
+	if err := f.call("SetEthtoolFeatures"); err != nil {
+		return err
+	}
+	l := f.find(f.current, ifName)
+	if l == nil {
+		return netlink.LinkNotFoundError{}
+	}
+	for name, on := range features {
+		if _, ok := f.features[l][name]; !ok {
+			return fmt.Errorf("unknown feature %s", name)
+		}
+		f.features[l][name] = on
+	}
+	return nil
+}
+
+// fakeNetNS is a network namespace of a fakeNetlink
+type fakeNetNS struct {
+	nl   *fakeNetlink
+	fd   int
+	path string
+}
+
+func (n *fakeNetNS) Do(toRun func(ns.NetNS) error) error {
+	prev := n.nl.current
+	n.nl.current = n.fd
+	defer func() { n.nl.current = prev }()
+	return toRun(n)
+}
+
+func (n *fakeNetNS) Set() error {
+	n.nl.current = n.fd
+	return nil
+}
+
+func (n *fakeNetNS) Path() string {
+	return n.path
+}
+
+func (n *fakeNetNS) Fd() uintptr {
+	return uintptr(n.fd)
+}
+
+func (n *fakeNetNS) Close() error {
+	return nil
+}
+
+// fakeNsUtils opens the pod netns for every path
+type fakeNsUtils struct {
+	nl *fakeNetlink
+}
+
+func (u *fakeNsUtils) GetNS(nspath string) (ns.NetNS, error) {
+	return &fakeNetNS{nl: u.nl, fd: podNetns, path: nspath}, nil
+}
+
+func (u *fakeNsUtils) GetCurrentNS() (ns.NetNS, error) {
+	return &fakeNetNS{nl: u.nl, fd: hostNetns, path: "/proc/1/ns/net"}, nil
+}
+
+// fakeSysfs creates the sysfs entries of PFs and VFs below root
+type fakeSysfs struct {
+	root string
+}
+
+func (s fakeSysfs) mkdir(path ...string) string {
+	dir := filepath.Join(append([]string{s.root}, path...)...)
+	o.Expect(os.MkdirAll(dir, 0755)).To(o.Succeed())
+	return dir
+}
+
+func (s fakeSysfs) write(path string, data string) {
+	o.Expect(os.WriteFile(path, []byte(data), 0644)).To(o.Succeed())
+}
+
+func (s fakeSysfs) addPF(name string, pci string, vendor string, numVfs int) {
+	dev := s.mkdir("sys/bus/pci/devices", pci)
+	s.mkdir("sys/bus/pci/devices", pci, "net", name)
+	s.write(filepath.Join(dev, "sriov_numvfs"), fmt.Sprintf("%d\n", numVfs))
+	s.write(filepath.Join(dev, "vendor"), vendor+"\n")
+	s.mkdir("sys/class/net", name)
+	o.Expect(os.Symlink(dev, filepath.Join(s.root, "sys/class/net", name, "device"))).To(o.Succeed())
+}
+
+// addVF adds VF vfID of PF pfPci bound to driver, with a netdev if name is set
+func (s fakeSysfs) addVF(pfPci string, vfID int, pci string, name string, driver string) {
+	pfDev := filepath.Join(s.root, "sys/bus/pci/devices", pfPci)
+	dev := s.mkdir("sys/bus/pci/devices", pci)
+	o.Expect(os.Symlink(dev, filepath.Join(pfDev, fmt.Sprintf("virtfn%d", vfID)))).To(o.Succeed())
+	o.Expect(os.Symlink(pfDev, filepath.Join(dev, "physfn"))).To(o.Succeed())
+	o.Expect(os.Symlink(s.mkdir("sys/bus/pci/drivers", driver), filepath.Join(dev, "driver"))).To(o.Succeed())
+	if name != "" {
+		s.mkdir("sys/bus/pci/devices", pci, "net", name)
+	}
+}
+
+var _ = g.Describe("SRIOV manager", func() {
+	var (
+		nl     *fakeNetlink
+		pf     *netlink.Device
+		vf     *netlink.Device
+		sysfs  fakeSysfs
+		cniDir string
+		sm     *sriovManager
+	)
+
+	request := func(command string, deviceID string, mac string) *cnitypes.PodRequest {
+		return &cnitypes.PodRequest{
+			Command:     command,
+			ContainerId: "container1",
+			Netns:       podNetnsPath,
+			IfName:      "net1",
+			CNIConf: &cnitypes.NetConf{
+				NetConf:  types.NetConf{CNIVersion: "1.0.0", Name: "sriov-net"},
+				DeviceID: deviceID,
+				MAC:      mac,
+			},
+			Ctx: context.Background(),
+		}
+	}
+
+	allocated := func(pci string) bool {
+		_, err := os.Stat(filepath.Join(cniDir, "pci", pci))
+		return err == nil
+	}
+
+	cached := func() bool {
+		_, err := os.Stat(filepath.Join(cniDir, "container1-net1"))
+		return err == nil
+	}
+
+	setUp := func(vendor string) {
+		sysfs = fakeSysfs{root: g.GinkgoT().TempDir()}
+		sysfs.addPF(pfName, pfPci, vendor, 2)
+		sysfs.addVF(pfPci, 0, vfPci, vfName, "iavf")
+		sysfs.addVF(pfPci, 1, dpdkPci, "", "vfio-pci")
+
+		cniDir = g.GinkgoT().TempDir()
+
+		nl = newFakeNetlink()
+		pf = nl.addLink(pfName, "02:00:00:00:00:00", 2)
+		pf.Vfs[0].Mac, _ = net.ParseMAC(vfMac)
+		vf = nl.addLink(vfName, vfMac, 0)
+		sm = NewSriovManager(WithSysfsRoot(sysfs.root), WithCNIDir(cniDir))
+		sm.nLink = nl
+		sm.ns = &fakeNsUtils{nl: nl}
+	}
+
+	type addCase struct {
+		vendor    string
+		deviceID  string
+		maxTxRate int
+		mtu       int
+		// fail makes the netlink method fail
+		fail         string
+		preAllocated bool
+
+		err string
+		// vfNetns and vfName are where the netdev VF ends up
+		vfNetns   int
+		vfName    string
+		allocated bool
+		// notCalled are netlink methods that must not be called
+		notCalled []string
+	}
+
+	g.DescribeTable("ADD",
+		func(c addCase) {
+			if c.vendor == "" {
+				c.vendor = "0x8086"
+			}
+			setUp(c.vendor)
+			if c.preAllocated {
+				o.Expect(sriovutils.NewPCIAllocator(cniDir).Allocate(c.deviceID, sriovutils.Allocation{Netns: podNetnsPath})).To(o.Succeed())
+			}
+			if c.fail != "" {
+				nl.failures[c.fail] = fmt.Errorf("%s failed", c.fail)
+			}
+			req := request(cnitypes.CNIAdd, c.deviceID, podMac)
+			if c.maxTxRate != 0 {
+				req.CNIConf.MaxTxRate = &c.maxTxRate
+			}
+			req.CNIConf.MTU = c.mtu
+
+			result, err := sm.CmdAdd(req)
+			if c.err != "" {
+				o.Expect(err).To(o.MatchError(o.ContainSubstring(c.err)))
+			} else {
+				o.Expect(err).NotTo(o.HaveOccurred())
+				o.Expect(result.Interfaces).To(o.HaveLen(1))
+				o.Expect(result.Interfaces[0].Mac).To(o.Equal(podMac))
+				o.Expect(result.Interfaces[0].Sandbox).To(o.Equal(podNetnsPath))
+				o.Expect(req.DeviceInfo.Type).To(o.Equal(nadapi.DeviceInfoTypePCI))
+				o.Expect(*req.DeviceInfo.Pci).To(o.Equal(nadapi.PciDevice{PciAddress: c.deviceID, PfPciAddress: pfPci}))
+			}
+			o.Expect(nl.netns[vf]).To(o.Equal(c.vfNetns))
+			o.Expect(vf.Name).To(o.Equal(c.vfName))
+			o.Expect(allocated(c.deviceID)).To(o.Equal(c.allocated))
+			o.Expect(cached()).To(o.Equal(c.err == ""))
+			for _, method := range c.notCalled {
+				o.Expect(nl.calls).NotTo(o.ContainElement(method))
+			}
+		},
+		g.Entry("moves a netdev VF into the pod", addCase{
+			deviceID: vfPci, vfNetns: podNetns, vfName: "net1", allocated: true,
+		}),
+		g.Entry("leaves a DPDK VF in the host", addCase{
+			deviceID: dpdkPci, vfNetns: hostNetns, vfName: vfName, allocated: true,
+			notCalled: []string{"LinkSetNsFd"},
+		}),
+		g.Entry("does not set the VLAN without the VF capabilities of the vendor", addCase{
+			vendor: "0x177d", deviceID: vfPci, vfNetns: podNetns, vfName: "net1", allocated: true,
+			notCalled: []string{"LinkSetVfVlanQosProto"},
+		}),
+		g.Entry("leaves the VF in the host if its configuration fails", addCase{
+			deviceID: vfPci, maxTxRate: 100, fail: "LinkSetVfRate",
+			err: "failed to configure VF", vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("gives the VF its name back if moving it fails", addCase{
+			deviceID: vfPci, fail: "LinkSetNsFd",
+			err: "failed to move IF", vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("refuses an MTU larger than the one of the PF", addCase{
+			deviceID: vfPci, mtu: 9000,
+			err: "exceeds the mtu 1500 of PF", vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("moves the VF back if its link attributes cannot be set", addCase{
+			deviceID: vfPci, mtu: 1400, fail: "LinkSetMTU",
+			err: "failed to set mtu", vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("refuses a VF allocated to a running pod", addCase{
+			deviceID: vfPci, preAllocated: true,
+			err: "already allocated", vfNetns: hostNetns, vfName: vfName, allocated: true,
+		}),
+	)
+
+	g.It("should restore the VF state saved by ADD on a PF with the capability", func() {
+		setUp("0x8086")
+		rate := 100
+		req := request(cnitypes.CNIAdd, vfPci, podMac)
+		req.CNIConf.MaxTxRate = &rate
+		_, err := sm.CmdAdd(req)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(pf.Vfs[0].Mac.String()).To(o.Equal(podMac))
+		o.Expect(pf.Vfs[0].MaxTxRate).To(o.Equal(uint32(100)))
+
+		o.Expect(sm.CmdDel(request(cnitypes.CNIDel, vfPci, ""))).To(o.Succeed())
+		o.Expect(pf.Vfs[0].Mac.String()).To(o.Equal(vfMac))
+		o.Expect(pf.Vfs[0].MaxTxRate).To(o.Equal(uint32(0)))
+		o.Expect(vf.HardwareAddr.String()).To(o.Equal(vfMac))
+	})
+
+	g.It("should set the link attributes in the pod and restore them on DEL", func() {
+		setUp("0x8086")
+		qlen := 5000
+		req := request(cnitypes.CNIAdd, vfPci, podMac)
+		req.CNIConf.LinkConfig = cnitypes.LinkConfig{
+			MTU:      1400,
+			Alias:    "data",
+			TxQLen:   &qlen,
+			Offloads: map[string]bool{"rx-gro": false, "tx-tcp-segmentation": true},
+		}
+		_, err := sm.CmdAdd(req)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(vf.MTU).To(o.Equal(1400))
+		o.Expect(vf.Alias).To(o.Equal("data"))
+		o.Expect(vf.TxQLen).To(o.Equal(5000))
+		o.Expect(nl.features[vf]).To(o.Equal(map[string]bool{"rx-gro": false, "tx-tcp-segmentation": true}))
+
+		o.Expect(sm.CmdDel(request(cnitypes.CNIDel, vfPci, ""))).To(o.Succeed())
+		o.Expect(nl.netns[vf]).To(o.Equal(hostNetns))
+		o.Expect(vf.MTU).To(o.Equal(1500))
+		o.Expect(vf.Alias).To(o.BeEmpty())
+		o.Expect(vf.TxQLen).To(o.Equal(1000))
+		o.Expect(nl.features[vf]).To(o.Equal(map[string]bool{"rx-gro": true, "tx-tcp-segmentation": true}))
+	})
+
+	type delCase struct {
+		deviceID string
+		// fail makes the netlink method fail after ADD
+		fail string
+
+		err       string
+		vfNetns   int
+		vfName    string
+		allocated bool
+	}
+
+	g.DescribeTable("DEL",
+		func(c delCase) {
+			setUp("0x8086")
+			_, err := sm.CmdAdd(request(cnitypes.CNIAdd, c.deviceID, podMac))
+			o.Expect(err).NotTo(o.HaveOccurred())
+			if c.fail != "" {
+				nl.failures[c.fail] = fmt.Errorf("%s failed", c.fail)
+			}
+
+			err = sm.CmdDel(request(cnitypes.CNIDel, c.deviceID, ""))
+			if c.err != "" {
+				o.Expect(err).To(o.MatchError(o.ContainSubstring(c.err)))
+			} else {
+				o.Expect(err).NotTo(o.HaveOccurred())
+			}
+			o.Expect(nl.netns[vf]).To(o.Equal(c.vfNetns))
+			o.Expect(vf.Name).To(o.Equal(c.vfName))
+			o.Expect(allocated(c.deviceID)).To(o.Equal(c.allocated))
+			// The runtime retries a failed DEL, which needs the cached NetConf
+			o.Expect(cached()).To(o.Equal(c.err != ""))
+		},
+		g.Entry("returns a netdev VF to the host", delCase{
+			deviceID: vfPci, vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("releases a DPDK VF", delCase{
+			deviceID: dpdkPci, vfNetns: hostNetns, vfName: vfName,
+		}),
+		g.Entry("keeps the attachment if the VF cannot be released", delCase{
+			deviceID: vfPci, fail: "LinkSetName",
+			err: "failed to rename link", vfNetns: podNetns, vfName: "net1", allocated: true,
+		}),
+	)
+
+	g.It("should succeed DEL without cached NetConf", func() {
+		setUp("0x8086")
+		o.Expect(sm.CmdDel(request(cnitypes.CNIDel, vfPci, ""))).To(o.Succeed())
+	})
+
+	type gcCase struct {
+		network string
+		valid   []types.GCAttachment
+		stale   bool
+	}
+
+	g.DescribeTable("GC",
+		func(c gcCase) {
+			setUp("0x8086")
+			_, err := sm.CmdAdd(request(cnitypes.CNIAdd, vfPci, podMac))
+			o.Expect(err).NotTo(o.HaveOccurred())
+
+			gc := request(cnitypes.CNIGC, "", "")
+			gc.CNIConf.Name = c.network
+			gc.CNIConf.ValidAttachments = c.valid
+			stale, err := sm.StaleAttachments(gc)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(sm.CmdGC(gc)).To(o.Succeed())
+			if !c.stale {
+				o.Expect(stale).To(o.BeEmpty())
+				return
+			}
+			o.Expect(stale).To(o.HaveLen(1))
+			del := stale[0]
+			o.Expect(del.Command).To(o.Equal(cnitypes.CNIDel))
+			o.Expect(del.ContainerId).To(o.Equal("container1"))
+			o.Expect(del.IfName).To(o.Equal("net1"))
+			o.Expect(del.Netns).To(o.Equal(podNetnsPath))
+			o.Expect(del.CNIConf.DeviceID).To(o.Equal(vfPci))
+
+			// Releasing the stale attachment frees the VF for the next ADD
+			o.Expect(sm.CmdDel(del)).To(o.Succeed())
+			o.Expect(nl.netns[vf]).To(o.Equal(hostNetns))
+			o.Expect(vf.Name).To(o.Equal(vfName))
+			o.Expect(allocated(vfPci)).To(o.BeFalse())
+			o.Expect(cached()).To(o.BeFalse())
+		},
+		g.Entry("releases an attachment the runtime no longer knows", gcCase{
+			network: "sriov-net", stale: true,
+		}),
+		g.Entry("keeps a valid attachment", gcCase{
+			network: "sriov-net", valid: []types.GCAttachment{{ContainerID: "container1", IfName: "net1"}},
+		}),
+		g.Entry("keeps the attachments of other networks", gcCase{
+			network: "other-net",
+		}),
+	)
+})
+
+var _ = g.Describe("SRIOV manager sub function device-info", func() {
+	g.It("should describe the auxiliary device and its PF", func() {
+		sysfs := fakeSysfs{root: g.GinkgoT().TempDir()}
+		dev := sysfs.mkdir("sys/bus/pci/devices", "0000:3b:00.0", "mlx5_core.sf.2")
+		o.Expect(os.Symlink(dev, filepath.Join(sysfs.mkdir("sys/bus/auxiliary/devices"), "mlx5_core.sf.2"))).To(o.Succeed())
+
+		info := NewSriovManager(WithSysfsRoot(sysfs.root)).sfDeviceInfo("mlx5_core.sf.2", 7)
+		o.Expect(info.Type).To(o.Equal(cnitypes.DeviceInfoTypeAuxiliary))
+		o.Expect(info.Version).To(o.Equal(nadapi.DeviceInfoVersion))
+		o.Expect(info.Pci).To(o.BeNil())
+		o.Expect(*info.Auxiliary).To(o.Equal(cnitypes.AuxiliaryDevice{DeviceID: "mlx5_core.sf.2", PfPciAddress: "0000:3b:00.0", SFNum: 7}))
+	})
+
+	g.It("should only look up devices in the sysfs of its manager", func() {
+		sysfs := fakeSysfs{root: g.GinkgoT().TempDir()}
+		dev := sysfs.mkdir("sys/bus/pci/devices", "0000:3b:00.0", "mlx5_core.sf.2")
+		o.Expect(os.Symlink(dev, filepath.Join(sysfs.mkdir("sys/bus/auxiliary/devices"), "mlx5_core.sf.2"))).To(o.Succeed())
+		withSF := NewSriovManager(WithSysfsRoot(sysfs.root))
+		withoutSF := NewSriovManager(WithSysfsRoot(g.GinkgoT().TempDir()))
+
+		o.Expect(withoutSF.sfDeviceInfo("mlx5_core.sf.2", 7).Auxiliary.PfPciAddress).To(o.BeEmpty())
+		o.Expect(withSF.sfDeviceInfo("mlx5_core.sf.2", 7).Auxiliary.PfPciAddress).To(o.Equal("0000:3b:00.0"))
+	})
+})
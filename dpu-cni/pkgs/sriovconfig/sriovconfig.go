@@ -3,24 +3,43 @@ package sriovconfig
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovtypes"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
 )
 
-var (
-	// DefaultCNIDir used for caching NetConf
-	DefaultCNIDir = "/var/lib/cni/dpusriov"
-)
+// DefaultCNIDir used for caching NetConf
+const DefaultCNIDir = "/var/lib/cni/dpusriov"
+
+// Loader loads the NetConf of the devices in a sysfs and caches the NetConf
+// of every attachment in a CNI data dir.
+type Loader struct {
+	sysfs  *sriovutils.Sysfs
+	cniDir string
+}
+
+// NewLoader returns a Loader of the devices in sysfs caching the NetConfs in
+// cniDir
+func NewLoader(sysfs *sriovutils.Sysfs, cniDir string) *Loader {
+	return &Loader{sysfs: sysfs, cniDir: cniDir}
+}
 
 // LoadConf parses and validates stdin netconf and returns NetConf object
-func LoadConf(n *cnitypes.NetConf) (*cnitypes.NetConf, error) {
+func (l *Loader) LoadConf(n *cnitypes.NetConf) (*cnitypes.NetConf, error) {
 	// DeviceID takes precedence; if we are given a VF pciaddr then work from there
-	if n.DeviceID != "" {
+	if n.IsSF() {
+		if err := l.loadSFConf(n); err != nil {
+			return nil, fmt.Errorf("LoadConf(): failed to get SF information: %q", err)
+		}
+	} else if sriovtypes.IsAuxDeviceName(n.DeviceID) {
+		return nil, fmt.Errorf("LoadConf(): only SF auxiliary devices are supported, got %s", n.DeviceID)
+	} else if n.DeviceID != "" {
 		// Get rest of the VF information
-		pfName, vfID, err := getVfInfo(n.DeviceID)
+		pfName, vfID, err := l.getVfInfo(n.DeviceID)
 		if err != nil {
 			return nil, fmt.Errorf("LoadConf(): failed to get VF information: %q", err)
 		}
@@ -34,12 +53,14 @@ func LoadConf(n *cnitypes.NetConf) (*cnitypes.NetConf, error) {
 	// This is to prevent issues where kubelet request to delete a pod and in the same time a new pod using the same
 	// vf is started. we can have an issue where the cmdDel of the old pod is called AFTER the cmdAdd of the new one
 	// This will block the new pod creation until the cmdDel is done.
+	// CmdAdd allocates the VF exclusively, this check fails early while the
+	// netdev of the VF is still in the old pod.
 	// FIXME: Fix Logging
 	//logging.Debug("Check if the device is already allocated",
 	//	"func", "LoadConf",
-	//	"DefaultCNIDir", DefaultCNIDir,
+	//	"DefaultCNIDir", l.cniDir,
 	//	"n.DeviceID", n.DeviceID)
-	allocator := sriovutils.NewPCIAllocator(DefaultCNIDir)
+	allocator := sriovutils.NewPCIAllocator(l.cniDir)
 	isAllocated, err := allocator.IsAllocated(n.DeviceID)
 	if err != nil {
 		return n, err
@@ -49,11 +70,15 @@ func LoadConf(n *cnitypes.NetConf) (*cnitypes.NetConf, error) {
 		return n, fmt.Errorf("pci address %s is already allocated", n.DeviceID)
 	}
 
-	// Assuming VF is netdev interface; Get interface name(s)
-	hostIFName, err := sriovutils.GetVFLinkName(n.DeviceID)
+	// Assuming VF is netdev interface; Get interface name(s). The netdev
+	// of a sub function was found by loadSFConf.
+	hostIFName := n.OrigVfState.HostIFName
+	if !n.IsSF() {
+		hostIFName, err = l.sysfs.GetVFLinkName(n.DeviceID)
+	}
 	if err != nil || hostIFName == "" {
 		// VF interface not found; check if VF has dpdk driver
-		hasDpdkDriver, err := sriovutils.HasDpdkDriver(n.DeviceID)
+		hasDpdkDriver, err := l.sysfs.HasDpdkDriver(n.DeviceID)
 		if err != nil {
 			return nil, fmt.Errorf("LoadConf(): failed to detect if VF %s has dpdk driver %q", n.DeviceID, err)
 		}
@@ -113,18 +138,47 @@ func LoadConf(n *cnitypes.NetConf) (*cnitypes.NetConf, error) {
 		return nil, fmt.Errorf("LoadConf(): invalid link_state value: %s", n.LinkState)
 	}
 
+	if err := n.LinkConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("LoadConf(): %v", err)
+	}
+
 	return n, nil
 }
 
-func getVfInfo(vfPci string) (string, int, error) {
+// loadSFConf fills in the netdev and PF of the sub function n.DeviceID. Sub
+// functions are always netdevs, they cannot be bound to a dpdk driver.
+func (l *Loader) loadSFConf(n *cnitypes.NetConf) error {
+	hostIFName, err := l.sysfs.GetNetNameFromAuxDevice(n.DeviceID)
+	if err != nil {
+		return err
+	}
+	pfPci, err := l.sysfs.GetPfPciFromAux(n.DeviceID)
+	if err != nil {
+		return err
+	}
+	pfName, err := l.sysfs.GetVFLinkName(pfPci)
+	if err != nil {
+		return fmt.Errorf("failed to find the netdev of PF %s: %v", pfPci, err)
+	}
+	sfNum, err := l.sysfs.GetSfIndex(n.DeviceID)
+	if err != nil {
+		return err
+	}
+	n.Master = pfName
+	n.SFNum = sfNum
+	n.OrigVfState.HostIFName = hostIFName
+	return nil
+}
+
+func (l *Loader) getVfInfo(vfPci string) (string, int, error) {
 	var vfID int
 
-	pf, err := sriovutils.GetPfName(vfPci)
+	pf, err := l.sysfs.GetPfName(vfPci)
 	if err != nil {
 		return "", vfID, err
 	}
 
-	vfID, err = sriovutils.GetVfid(vfPci, pf)
+	vfID, err = l.sysfs.GetVfid(vfPci, pf)
 	if err != nil {
 		return "", vfID, err
 	}
@@ -133,16 +187,16 @@ func getVfInfo(vfPci string) (string, int, error) {
 }
 
 // LoadConfFromCache retrieves cached NetConf returns it along with a handle for removal
-func LoadConfFromCache(containerID string, ifName string) (*cnitypes.NetConf, string, error) {
+func (l *Loader) LoadConfFromCache(containerID string, ifName string) (*cnitypes.NetConf, string, error) {
 	netConf := &cnitypes.NetConf{}
 
 	s := []string{containerID, ifName}
 	cRef := strings.Join(s, "-")
-	cRefPath := filepath.Join(DefaultCNIDir, cRef)
+	cRefPath := filepath.Join(l.cniDir, cRef)
 
 	netConfBytes, err := sriovutils.ReadScratchNetConf(cRefPath)
 	if err != nil {
-		return nil, "", fmt.Errorf("error reading cached NetConf in %s with name %s", DefaultCNIDir, cRef)
+		return nil, "", fmt.Errorf("error reading cached NetConf in %s with name %s", l.cniDir, cRef)
 	}
 
 	if err = json.Unmarshal(netConfBytes, netConf); err != nil {
@@ -152,6 +206,42 @@ func LoadConfFromCache(containerID string, ifName string) (*cnitypes.NetConf, st
 	return netConf, cRefPath, nil
 }
 
+// CachedConf is a NetConf cached by CmdAdd for an attachment.
+type CachedConf struct {
+	ContainerID string
+	IfName      string
+	NetConf     *cnitypes.NetConf
+}
+
+// ListCachedConfs returns the NetConfs cached for all attachments.
+func (l *Loader) ListCachedConfs() ([]CachedConf, error) {
+	files, err := os.ReadDir(l.cniDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list cached NetConfs in %s: %v", l.cniDir, err)
+	}
+
+	var confs []CachedConf
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		// Container IDs never contain "-", interface names may
+		containerID, ifName, found := strings.Cut(f.Name(), "-")
+		if !found {
+			continue
+		}
+		netConf, _, err := l.LoadConfFromCache(containerID, ifName)
+		if err != nil {
+			return nil, err
+		}
+		confs = append(confs, CachedConf{ContainerID: containerID, IfName: ifName, NetConf: netConf})
+	}
+	return confs, nil
+}
+
 // GetMacAddressForResult return the mac address we should report to the CNI call return object
 // if the device is on kernel mode we report that one back
 // if not we check the administrative mac address on the PF
@@ -0,0 +1,13 @@
+package sriovconfig
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSriovconfig(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sriovconfig Suite")
+}
@@ -0,0 +1,76 @@
+package sriovconfig
+
+import (
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
+)
+
+var _ = g.Describe("LoadConf of a sub function", func() {
+	const (
+		pfPci = "0000:3b:00.0"
+		sfDev = "mlx5_core.sf.2"
+	)
+	var (
+		root   string
+		cniDir string
+		loader *Loader
+	)
+
+	g.BeforeEach(func() {
+		root = g.GinkgoT().TempDir()
+		cniDir = filepath.Join(root, "var/lib/cni/dpusriov")
+		loader = NewLoader(sriovutils.NewSysfs(root), cniDir)
+
+		pfDir := filepath.Join(root, "sys/devices/pci0000:00", pfPci)
+		sfDir := filepath.Join(pfDir, sfDev)
+		o.Expect(os.MkdirAll(filepath.Join(pfDir, "net", "ens1f0"), 0755)).To(o.Succeed())
+		o.Expect(os.MkdirAll(filepath.Join(sfDir, "net", "ens1f0s7"), 0755)).To(o.Succeed())
+		o.Expect(os.WriteFile(filepath.Join(sfDir, "sfnum"), []byte("7\n"), 0644)).To(o.Succeed())
+		for link, target := range map[string]string{
+			"sys/bus/pci/devices/" + pfPci:       pfDir,
+			"sys/bus/auxiliary/devices/" + sfDev: sfDir,
+		} {
+			o.Expect(os.MkdirAll(filepath.Dir(filepath.Join(root, link)), 0755)).To(o.Succeed())
+			o.Expect(os.Symlink(target, filepath.Join(root, link))).To(o.Succeed())
+		}
+	})
+
+	g.It("should find the PF, sfnum and netdev of the sub function", func() {
+		n, err := loader.LoadConf(&cnitypes.NetConf{DeviceID: sfDev})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(n.IsSF()).To(o.BeTrue())
+		o.Expect(n.Master).To(o.Equal("ens1f0"))
+		o.Expect(n.SFNum).To(o.Equal(7))
+		o.Expect(n.OrigVfState.HostIFName).To(o.Equal("ens1f0s7"))
+		o.Expect(n.DPDKMode).To(o.BeFalse())
+		o.Expect(*n.Vlan).To(o.Equal(0))
+	})
+
+	g.It("should refuse a sub function that is already allocated", func() {
+		allocator := sriovutils.NewPCIAllocator(cniDir)
+		o.Expect(allocator.Allocate(sfDev, sriovutils.Allocation{Netns: "/proc/self/ns/net"})).To(o.Succeed())
+		_, err := loader.LoadConf(&cnitypes.NetConf{DeviceID: sfDev})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("already allocated")))
+	})
+
+	g.It("should fail if the sub function is missing", func() {
+		_, err := loader.LoadConf(&cnitypes.NetConf{DeviceID: "mlx5_core.sf.9"})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("failed to get SF information")))
+	})
+
+	g.It("should fail if the PF has no netdev", func() {
+		o.Expect(os.RemoveAll(filepath.Join(root, "sys/devices/pci0000:00", pfPci, "net"))).To(o.Succeed())
+		_, err := loader.LoadConf(&cnitypes.NetConf{DeviceID: sfDev})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("failed to find the netdev of PF " + pfPci)))
+	})
+
+	g.It("should refuse auxiliary devices that are not sub functions", func() {
+		_, err := loader.LoadConf(&cnitypes.NetConf{DeviceID: "mlx5_core.eth.0"})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("only SF auxiliary devices are supported")))
+	})
+})
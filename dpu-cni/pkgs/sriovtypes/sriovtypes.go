@@ -2,11 +2,12 @@ package sriovtypes
 
 import (
 	"regexp"
+	"strings"
 )
 
 var (
 	rePciDeviceName = regexp.MustCompile(`^[0-9a-f]{4}:[0-9a-f]{2}:[01][0-9a-f]\.[0-7]$`)
-	reAuxDeviceName = regexp.MustCompile(`^\w+.\w+.\d+$`)
+	reAuxDeviceName = regexp.MustCompile(`^\w+\.\w+\.\d+$`)
 )
 
 // IsPCIDeviceName check if passed device id is a PCI device name
@@ -18,3 +19,9 @@ func IsPCIDeviceName(deviceID string) bool {
 func IsAuxDeviceName(deviceID string) bool {
 	return reAuxDeviceName.MatchString(deviceID)
 }
+
+// IsSFDeviceName check if passed device id is the Auxiliary device name of a
+// sub function - <driver_name>.sf.<id>
+func IsSFDeviceName(deviceID string) bool {
+	return IsAuxDeviceName(deviceID) && strings.Split(deviceID, ".")[1] == "sf"
+}
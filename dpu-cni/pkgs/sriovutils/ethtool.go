@@ -0,0 +1,144 @@
+package sriovutils
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/vishvananda/netlink"
+	"github.com/vishvananda/netlink/nl"
+)
+
+// ethtool netlink interface, see include/uapi/linux/ethtool_netlink.h
+const (
+	ethtoolGenlName    = "ethtool"
+	ethtoolGenlVersion = 1
+
+	ethtoolMsgFeaturesGet = 11
+	ethtoolMsgFeaturesSet = 12
+
+	ethtoolAHeaderDevName  = 2
+	ethtoolAHeaderFlags    = 3
+	ethtoolFlagOmitReply   = 1 << 1
+	ethtoolAFeaturesHeader = 1
+	ethtoolAFeaturesWanted = 3
+	ethtoolAFeaturesActive = 4
+
+	ethtoolABitsetBits     = 3
+	ethtoolABitsetBitsBit  = 1
+	ethtoolABitsetBitName  = 2
+	ethtoolABitsetBitValue = 3
+)
+
+// ethtoolRequest returns a request of the ethtool generic netlink family for
+// the features of ifName
+func ethtoolRequest(cmd uint8, ifName string, flags int, headerFlags uint32) (*nl.NetlinkRequest, error) {
+	family, err := netlink.GenlFamilyGet(ethtoolGenlName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the ethtool netlink family: %v", err)
+	}
+	req := nl.NewNetlinkRequest(int(family.ID), flags)
+	req.AddData(&nl.Genlmsg{Command: cmd, Version: ethtoolGenlVersion})
+	header := nl.NewRtAttr(ethtoolAFeaturesHeader|int(nl.NLA_F_NESTED), nil)
+	header.AddRtAttr(ethtoolAHeaderDevName, nl.ZeroTerminated(ifName))
+	if headerFlags != 0 {
+		header.AddRtAttr(ethtoolAHeaderFlags, nl.Uint32Attr(headerFlags))
+	}
+	req.AddData(header)
+	return req, nil
+}
+
+// EthtoolFeatures returns which of the ethtool features of ifName are active,
+// by their kernel name
+func EthtoolFeatures(ifName string) (map[string]bool, error) {
+	req, err := ethtoolRequest(ethtoolMsgFeaturesGet, ifName, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := req.Execute(syscall.NETLINK_GENERIC, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the ethtool features of %s: %v", ifName, err)
+	}
+
+	features := map[string]bool{}
+	for _, msg := range msgs {
+		attrs, err := nl.ParseRouteAttr(msg[nl.SizeofGenlmsg:])
+		if err != nil {
+			return nil, err
+		}
+		for _, attr := range attrs {
+			if attr.Attr.Type&nl.NLA_TYPE_MASK != ethtoolAFeaturesActive {
+				continue
+			}
+			// The active features are a bitset without mask, which lists
+			// only the bits that are set
+			active, err := bitsetNames(attr.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, name := range active {
+				features[name] = true
+			}
+		}
+	}
+	return features, nil
+}
+
+// bitsetNames returns the names of the bits listed in a verbose bitset
+func bitsetNames(b []byte) ([]string, error) {
+	attrs, err := nl.ParseRouteAttr(b)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, attr := range attrs {
+		if attr.Attr.Type&nl.NLA_TYPE_MASK != ethtoolABitsetBits {
+			continue
+		}
+		bits, err := nl.ParseRouteAttr(attr.Value)
+		if err != nil {
+			return nil, err
+		}
+		for _, bit := range bits {
+			fields, err := nl.ParseRouteAttr(bit.Value)
+			if err != nil {
+				return nil, err
+			}
+			for _, field := range fields {
+				if field.Attr.Type&nl.NLA_TYPE_MASK == ethtoolABitsetBitName {
+					names = append(names, nl.BytesToString(field.Value))
+				}
+			}
+		}
+	}
+	return names, nil
+}
+
+// SetEthtoolFeatures turns the ethtool features of ifName on or off by their
+// kernel name, the other features are left as they are. It fails if a feature
+// is unknown or cannot be changed.
+func SetEthtoolFeatures(ifName string, features map[string]bool) error {
+	if len(features) == 0 {
+		return nil
+	}
+	req, err := ethtoolRequest(ethtoolMsgFeaturesSet, ifName, syscall.NLM_F_ACK, ethtoolFlagOmitReply)
+	if err != nil {
+		return err
+	}
+	// The bits listed in a bitset with mask are changed, to on if they have
+	// a value and to off if not
+	wanted := nl.NewRtAttr(ethtoolAFeaturesWanted|int(nl.NLA_F_NESTED), nil)
+	bits := wanted.AddRtAttr(ethtoolABitsetBits|int(nl.NLA_F_NESTED), nil)
+	for name, on := range features {
+		bit := bits.AddRtAttr(ethtoolABitsetBitsBit|int(nl.NLA_F_NESTED), nil)
+		bit.AddRtAttr(ethtoolABitsetBitName, nl.ZeroTerminated(name))
+		if on {
+			bit.AddRtAttr(ethtoolABitsetBitValue, nil)
+		}
+	}
+	req.AddData(wanted)
+
+	if _, err := req.Execute(syscall.NETLINK_GENERIC, 0); err != nil {
+		return fmt.Errorf("failed to set the ethtool features %v of %s: %v", features, ifName, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package sriovutils
+
+import (
+	"fmt"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+)
+
+// ApplyLinkConfig sets the attributes of conf on the netdev ifName and returns
+// the original values of the attributes it changed, which RestoreLinkConfig
+// sets back. If an attribute cannot be set, the ones already changed are
+// restored.
+func ApplyLinkConfig(nLink NetlinkManager, ifName string, conf cnitypes.LinkConfig) (orig cnitypes.LinkConfig, err error) {
+	link, err := nLink.LinkByName(ifName)
+	if err != nil {
+		return orig, fmt.Errorf("failed to get netlink device with name %s: %v", ifName, err)
+	}
+	attrs := *link.Attrs()
+
+	defer func() {
+		if err != nil {
+			// The alias is set last, it was not changed
+			_ = RestoreLinkConfig(nLink, ifName, cnitypes.LinkConfig{}, orig)
+			orig = cnitypes.LinkConfig{}
+		}
+	}()
+
+	if conf.MTU != 0 && conf.MTU != attrs.MTU {
+		if err = nLink.LinkSetMTU(link, conf.MTU); err != nil {
+			return orig, fmt.Errorf("failed to set mtu of %s to %d: %v", ifName, conf.MTU, err)
+		}
+		orig.MTU = attrs.MTU
+	}
+
+	if conf.TxQLen != nil && *conf.TxQLen != attrs.TxQLen {
+		if err = nLink.LinkSetTxQLen(link, *conf.TxQLen); err != nil {
+			return orig, fmt.Errorf("failed to set txqueuelen of %s to %d: %v", ifName, *conf.TxQLen, err)
+		}
+		orig.TxQLen = &attrs.TxQLen
+	}
+
+	if len(conf.Offloads) != 0 {
+		var active map[string]bool
+		if active, err = nLink.EthtoolFeatures(ifName); err != nil {
+			return orig, err
+		}
+		changed := map[string]bool{}
+		for name, on := range conf.Offloads {
+			if active[name] != on {
+				changed[name] = on
+			}
+		}
+		if err = nLink.SetEthtoolFeatures(ifName, changed); err != nil {
+			return orig, err
+		}
+		if len(changed) != 0 {
+			orig.Offloads = map[string]bool{}
+			for name, on := range changed {
+				orig.Offloads[name] = !on
+			}
+		}
+	}
+
+	// The original alias is usually empty, so it is restored for every conf
+	// with an alias rather than when orig has one
+	if conf.Alias != "" {
+		if conf.Alias != attrs.Alias {
+			if err = nLink.LinkSetAlias(link, conf.Alias); err != nil {
+				return orig, fmt.Errorf("failed to set alias of %s to %q: %v", ifName, conf.Alias, err)
+			}
+		}
+		orig.Alias = attrs.Alias
+	}
+
+	return orig, nil
+}
+
+// RestoreLinkConfig sets the attributes of the netdev ifName back to the
+// original values returned by ApplyLinkConfig for conf.
+func RestoreLinkConfig(nLink NetlinkManager, ifName string, conf cnitypes.LinkConfig, orig cnitypes.LinkConfig) error {
+	if !orig.IsSet() && conf.Alias == "" {
+		return nil
+	}
+	link, err := nLink.LinkByName(ifName)
+	if err != nil {
+		return fmt.Errorf("failed to get netlink device with name %s: %v", ifName, err)
+	}
+
+	if conf.Alias != "" {
+		if err := nLink.LinkSetAlias(link, orig.Alias); err != nil {
+			return fmt.Errorf("failed to restore alias of %s to %q: %v", ifName, orig.Alias, err)
+		}
+	}
+	if err := nLink.SetEthtoolFeatures(ifName, orig.Offloads); err != nil {
+		return err
+	}
+	if orig.TxQLen != nil {
+		if err := nLink.LinkSetTxQLen(link, *orig.TxQLen); err != nil {
+			return fmt.Errorf("failed to restore txqueuelen of %s to %d: %v", ifName, *orig.TxQLen, err)
+		}
+	}
+	if orig.MTU != 0 {
+		if err := nLink.LinkSetMTU(link, orig.MTU); err != nil {
+			return fmt.Errorf("failed to restore mtu of %s to %d: %v", ifName, orig.MTU, err)
+		}
+	}
+	return nil
+}
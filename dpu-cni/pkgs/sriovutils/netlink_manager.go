@@ -22,6 +22,11 @@ type NetlinkManager interface {
 	LinkSetVfSpoofchk(netlink.Link, int, bool) error
 	LinkSetVfTrust(netlink.Link, int, bool) error
 	LinkSetVfState(netlink.Link, int, uint32) error
+	LinkSetMTU(netlink.Link, int) error
+	LinkSetAlias(netlink.Link, string) error
+	LinkSetTxQLen(netlink.Link, int) error
+	EthtoolFeatures(string) (map[string]bool, error)
+	SetEthtoolFeatures(string, map[string]bool) error
 }
 
 // MyNetlink NetlinkManager
@@ -88,3 +93,28 @@ func (n *MyNetlink) LinkSetVfTrust(link netlink.Link, vf int, state bool) error
 func (n *MyNetlink) LinkSetVfState(link netlink.Link, vf int, state uint32) error {
 	return netlink.LinkSetVfState(link, vf, state)
 }
+
+// LinkSetMTU using NetlinkManager
+func (n *MyNetlink) LinkSetMTU(link netlink.Link, mtu int) error {
+	return netlink.LinkSetMTU(link, mtu)
+}
+
+// LinkSetAlias using NetlinkManager
+func (n *MyNetlink) LinkSetAlias(link netlink.Link, name string) error {
+	return netlink.LinkSetAlias(link, name)
+}
+
+// LinkSetTxQLen using NetlinkManager
+func (n *MyNetlink) LinkSetTxQLen(link netlink.Link, qlen int) error {
+	return netlink.LinkSetTxQLen(link, qlen)
+}
+
+// EthtoolFeatures using NetlinkManager
+func (n *MyNetlink) EthtoolFeatures(ifName string) (map[string]bool, error) {
+	return EthtoolFeatures(ifName)
+}
+
+// SetEthtoolFeatures using NetlinkManager
+func (n *MyNetlink) SetEthtoolFeatures(ifName string, features map[string]bool) error {
+	return SetEthtoolFeatures(ifName, features)
+}
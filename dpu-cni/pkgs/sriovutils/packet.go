@@ -3,11 +3,13 @@ package sriovutils
 import (
 	"bytes"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"net"
 	"syscall"
 
 	current "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ip"
 	"github.com/vishvananda/netlink"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv6"
@@ -18,6 +20,10 @@ var (
 	icmpV6PacketName = "ICMPv6"
 )
 
+// dadTimeout is how long in seconds AnnounceIPs waits for the Duplicate
+// Address Detection of the IPv6 addresses to complete
+const dadTimeout = 10
+
 // htons converts an uint16 from host to network byte order.
 func htons(i uint16) uint16 {
 	return (i<<8)&0xff00 | i>>8
@@ -176,9 +182,25 @@ func AnnounceIPs(ifName string, ipConfigs []*current.IPConfig) error {
 	}
 
 	// For all the IP addresses assigned by IPAM, we will send either a GARP (IPv4) or Unsolicited NA (IPv6).
+	// A failure for one address does not keep the others from being announced.
+	var errs []error
+	settled, settleFailed := false, false
 	for _, ipc := range ipConfigs {
 		var err error
 		if IsIPv6(ipc.Address.IP) {
+			// A tentative address must not be advertised (RFC 4862 section 5.4), wait
+			// for Duplicate Address Detection, which ConfigureIface only does when the
+			// IPv6 config has a gateway.
+			if !settled {
+				settled = true
+				if err := ip.SettleAddresses(ifName, dadTimeout); err != nil {
+					errs = append(errs, fmt.Errorf("failed to settle the IPv6 addresses on interface %q, not announcing them: %v", ifName, err))
+					settleFailed = true
+				}
+			}
+			if settleFailed {
+				continue
+			}
 			/* As per RFC 4861, sending unsolicited neighbor advertisements should be considered as a performance
 			* optimization. It does not reliably update caches in all nodes. The Neighbor Unreachability Detection
 			* algorithm is more reliable although it may take slightly longer to update.
@@ -187,12 +209,13 @@ func AnnounceIPs(ifName string, ipConfigs []*current.IPConfig) error {
 		} else if IsIPv4(ipc.Address.IP) {
 			err = SendGratuitousArp(ipc.Address.IP, linkObj)
 		} else {
-			return fmt.Errorf("the IP %s on interface %q is neither IPv4 or IPv6", ipc.Address.IP.String(), ifName)
+			errs = append(errs, fmt.Errorf("the IP %s on interface %q is neither IPv4 or IPv6", ipc.Address.IP.String(), ifName))
+			continue
 		}
 
 		if err != nil {
-			return fmt.Errorf("failed to send GARP/NA message for ip %s on interface %q: %v", ipc.Address.IP.String(), ifName, err)
+			errs = append(errs, fmt.Errorf("failed to send GARP/NA message for ip %s on interface %q: %v", ipc.Address.IP.String(), ifName, err))
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
@@ -1,92 +1,264 @@
 package sriovutils
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/containernetworking/plugins/pkg/ns"
 )
 
 type PCIAllocation interface {
-	SaveAllocatedPCI(string, string) error
-	DeleteAllocatedPCI(string) error
-	IsAllocated(string) error
+	Allocate(string, Allocation) error
+	DeleteAllocatedPCI(string, string) error
+	IsAllocated(string) (bool, error)
+	List() ([]Allocation, error)
+	ReleaseStale() ([]Allocation, error)
+}
+
+// Allocation is the attachment a PCI address is allocated to
+type Allocation struct {
+	PciAddress  string `json:"-"`
+	Netns       string `json:"netns"`
+	PodUID      string `json:"podUID,omitempty"`
+	ContainerID string `json:"containerID,omitempty"`
 }
 
 type PCIAllocator struct {
 	dataDir string
 }
 
+// lockFile serializes the allocator operations of all processes, it is
+// hidden from List like the temporary files of Allocate
+const lockFile = ".lock"
+
 // NewPCIAllocator returns a new PCI allocator
 // it will use the <dataDir>/pci folder to store the information about allocated PCI addresses
 func NewPCIAllocator(dataDir string) *PCIAllocator {
 	return &PCIAllocator{dataDir: filepath.Join(dataDir, "pci")}
 }
 
-// SaveAllocatedPCI creates a file with the pci address as a name and the network namespace as the content
-// return error if the file was not created
-func (p *PCIAllocator) SaveAllocatedPCI(pciAddress, ns string) error {
-	if err := os.MkdirAll(p.dataDir, 0600); err != nil {
-		return fmt.Errorf("failed to create the sriov data directory(%q): %v", p.dataDir, err)
+// lock takes the allocator lock and returns the function releasing it
+func (p *PCIAllocator) lock() (func(), error) {
+	if err := os.MkdirAll(p.dataDir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create the sriov data directory(%q): %v", p.dataDir, err)
+	}
+	path := filepath.Join(p.dataDir, lockFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the PCI allocator lock %s: %v", path, err)
 	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to lock the PCI allocator lock %s: %v", path, err)
+	}
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
 
-	path := filepath.Join(p.dataDir, pciAddress)
-	err := os.WriteFile(path, []byte(ns), 0600)
+// Allocate allocates the PCI address to the attachment a. It fails if the PCI
+// address is allocated to a network namespace that still exists, allocations
+// of deleted network namespaces are released.
+// The allocation is written to a temporary file that is linked into place, so
+// that it is created exclusively and a crash never leaves a partial one.
+func (p *PCIAllocator) Allocate(pciAddress string, a Allocation) error {
+	unlock, err := p.lock()
 	if err != nil {
-		return fmt.Errorf("failed to write used PCI address lock file in the path(%q): %v", path, err)
+		return err
+	}
+	defer unlock()
+
+	allocated, err := p.isAllocated(pciAddress)
+	if err != nil {
+		return err
+	}
+	if allocated {
+		return fmt.Errorf("pci address %s is already allocated", pciAddress)
+	}
+
+	data, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to encode the allocation of pci address %s: %v", pciAddress, err)
+	}
+	tmp, err := os.CreateTemp(p.dataDir, "."+pciAddress+"-")
+	if err != nil {
+		return fmt.Errorf("failed to create the allocation of pci address %s: %v", pciAddress, err)
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write the allocation of pci address %s: %v", pciAddress, err)
 	}
 
-	return err
+	path := filepath.Join(p.dataDir, pciAddress)
+	if err := os.Link(tmp.Name(), path); err != nil {
+		if os.IsExist(err) {
+			return fmt.Errorf("pci address %s is already allocated", pciAddress)
+		}
+		return fmt.Errorf("failed to write used PCI address lock file in the path(%q): %v", path, err)
+	}
+	return nil
 }
 
-// DeleteAllocatedPCI Remove the allocated PCI file
-// return error if the file doesn't exist
-func (p *PCIAllocator) DeleteAllocatedPCI(pciAddress string) error {
+// DeleteAllocatedPCI Remove the allocated PCI file of the container
+// A PCI address that is not allocated, e.g. released by ReleaseStale, is not an error.
+// An allocation of another container, e.g. made after ReleaseStale released the
+// one of the container, is left alone. Allocations without a container ID were
+// written before it was recorded and are removed.
+func (p *PCIAllocator) DeleteAllocatedPCI(pciAddress string, containerID string) error {
+	unlock, err := p.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	a, err := p.read(pciAddress)
+	if err != nil || a == nil {
+		return err
+	}
+	if a.ContainerID != "" && a.ContainerID != containerID {
+		return nil
+	}
 	path := filepath.Join(p.dataDir, pciAddress)
-	if err := os.Remove(path); err != nil {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
 		return fmt.Errorf("error removing PCI address lock file %s: %v", path, err)
 	}
 	return nil
 }
 
+// read returns the allocation of the PCI address, or nil if it is not
+// allocated. Allocations written before pod UID and container ID were
+// recorded only contain the netns path.
+func (p *PCIAllocator) read(pciAddress string) (*Allocation, error) {
+	path := filepath.Join(p.dataDir, pciAddress)
+	dat, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read for pci address file for %s: %v", path, err)
+	}
+
+	a := &Allocation{}
+	if err := json.Unmarshal(dat, a); err != nil {
+		a = &Allocation{Netns: string(dat)}
+	}
+	a.PciAddress = pciAddress
+	return a, nil
+}
+
+// AllocatedNetns returns the network namespace the PCI address is allocated
+// to, or "" if it is not allocated
+func (p *PCIAllocator) AllocatedNetns(pciAddress string) (string, error) {
+	a, err := p.read(pciAddress)
+	if err != nil || a == nil {
+		return "", err
+	}
+	return a.Netns, nil
+}
+
+// netnsExists reports whether the network namespace still exists
+func netnsExists(path string) bool {
+	networkNamespace, err := ns.GetNS(path)
+	if err != nil {
+		return false
+	}
+	networkNamespace.Close()
+	return true
+}
+
 // IsAllocated checks if the PCI address file exist
 // if it exists we also check the network namespace still exist if not we delete the allocation
 // The function will return an error if the pci is still allocated to a running pod
 func (p *PCIAllocator) IsAllocated(pciAddress string) (bool, error) {
-	path := filepath.Join(p.dataDir, pciAddress)
-	_, err := os.Stat(path)
+	unlock, err := p.lock()
 	if err != nil {
-		if os.IsNotExist(err) {
-			return false, nil
-		}
-
-		return false, fmt.Errorf("failed to check for pci address file for %s: %v", path, err)
+		return false, err
 	}
+	defer unlock()
+	return p.isAllocated(pciAddress)
+}
 
-	dat, err := os.ReadFile(path)
-	if err != nil {
-		return false, fmt.Errorf("failed to read for pci address file for %s: %v", path, err)
+// isAllocated is IsAllocated with the allocator lock held
+func (p *PCIAllocator) isAllocated(pciAddress string) (bool, error) {
+	a, err := p.read(pciAddress)
+	if err != nil || a == nil {
+		return false, err
 	}
 
 	// To prevent a locking of a PCI address for every pciAddress file we also add the netns path where it's been used
 	// This way if for some reason the cmdDel command was not called but the pod namespace doesn't exist anymore
 	// we release the PCI address
-	networkNamespace, err := ns.GetNS(string(dat))
+	if !netnsExists(a.Netns) {
+		path := filepath.Join(p.dataDir, pciAddress)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return false, fmt.Errorf("error deleting the pci allocation for vf pci address %s: %v", pciAddress, err)
+		}
+		return false, nil
+	}
+	return true, nil
+}
+
+// List returns all allocations
+func (p *PCIAllocator) List() ([]Allocation, error) {
+	entries, err := os.ReadDir(p.dataDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
 	if err != nil {
-		// FIXME: Fix Logging
-		//logging.Debug("Mark the PCI address as released",
-		//	"func", "IsAllocated",
-		//	"pciAddress", pciAddress)
-		err = p.DeleteAllocatedPCI(pciAddress)
+		return nil, fmt.Errorf("failed to list the PCI allocations in %s: %v", p.dataDir, err)
+	}
+
+	var allocations []Allocation
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		a, err := p.read(e.Name())
 		if err != nil {
-			return false, fmt.Errorf("error deleting the pci allocation for vf pci address %s: %v", pciAddress, err)
+			return nil, err
+		}
+		if a != nil {
+			allocations = append(allocations, *a)
 		}
+	}
+	return allocations, nil
+}
 
-		return false, nil
+// ReleaseStale releases the allocations of network namespaces that no longer
+// exist, e.g. of pods that were deleted without a successful cmdDel, and
+// returns them
+func (p *PCIAllocator) ReleaseStale() ([]Allocation, error) {
+	unlock, err := p.lock()
+	if err != nil {
+		return nil, err
 	}
+	defer unlock()
 
-	// Close the network namespace
-	networkNamespace.Close()
-	return true, nil
+	allocations, err := p.List()
+	if err != nil {
+		return nil, err
+	}
+	var released []Allocation
+	for _, a := range allocations {
+		allocated, err := p.isAllocated(a.PciAddress)
+		if err != nil {
+			return released, err
+		}
+		if !allocated {
+			released = append(released, a)
+		}
+	}
+	return released, nil
 }
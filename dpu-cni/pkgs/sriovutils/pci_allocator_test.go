@@ -0,0 +1,102 @@
+package sriovutils
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("PCI allocator", func() {
+	const (
+		pci = "0000:01:00.2"
+		// liveNetns exists for as long as the test runs
+		liveNetns = "/proc/self/ns/net"
+	)
+	var (
+		dataDir   string
+		allocator *PCIAllocator
+		deadNetns string
+	)
+
+	g.BeforeEach(func() {
+		dataDir = g.GinkgoT().TempDir()
+		allocator = NewPCIAllocator(dataDir)
+		deadNetns = filepath.Join(dataDir, "netns-of-deleted-pod")
+	})
+
+	g.It("should record the attachment of an allocation", func() {
+		a := Allocation{Netns: liveNetns, PodUID: "uid1", ContainerID: "container1"}
+		o.Expect(allocator.Allocate(pci, a)).To(o.Succeed())
+
+		a.PciAddress = pci
+		o.Expect(allocator.List()).To(o.Equal([]Allocation{a}))
+		o.Expect(allocator.AllocatedNetns(pci)).To(o.Equal(liveNetns))
+		info, err := os.Stat(filepath.Join(dataDir, "pci"))
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(info.Mode().Perm()).To(o.Equal(os.FileMode(0700)))
+	})
+
+	g.It("should allocate a PCI address to only one of concurrent attachments", func() {
+		var wg sync.WaitGroup
+		errs := make(chan error, 10)
+		for i := 0; i < cap(errs); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs <- allocator.Allocate(pci, Allocation{Netns: liveNetns})
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		succeeded := 0
+		for err := range errs {
+			if err == nil {
+				succeeded++
+			} else {
+				o.Expect(err).To(o.MatchError(o.ContainSubstring("already allocated")))
+			}
+		}
+		o.Expect(succeeded).To(o.Equal(1))
+	})
+
+	g.It("should take over the allocation of a deleted network namespace", func() {
+		o.Expect(allocator.Allocate(pci, Allocation{Netns: deadNetns, ContainerID: "container1"})).To(o.Succeed())
+		o.Expect(allocator.Allocate(pci, Allocation{Netns: liveNetns, ContainerID: "container2"})).To(o.Succeed())
+		o.Expect(allocator.List()).To(o.ConsistOf(o.HaveField("ContainerID", "container2")))
+	})
+
+	g.It("should read allocations that only record the netns", func() {
+		o.Expect(os.MkdirAll(filepath.Join(dataDir, "pci"), 0700)).To(o.Succeed())
+		o.Expect(os.WriteFile(filepath.Join(dataDir, "pci", pci), []byte(liveNetns), 0600)).To(o.Succeed())
+		o.Expect(allocator.IsAllocated(pci)).To(o.BeTrue())
+		o.Expect(allocator.List()).To(o.Equal([]Allocation{{PciAddress: pci, Netns: liveNetns}}))
+	})
+
+	g.It("should release the allocations of deleted network namespaces", func() {
+		o.Expect(allocator.Allocate(pci, Allocation{Netns: deadNetns, PodUID: "uid1"})).To(o.Succeed())
+		o.Expect(allocator.Allocate("0000:01:00.3", Allocation{Netns: liveNetns, PodUID: "uid2"})).To(o.Succeed())
+
+		released, err := allocator.ReleaseStale()
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(released).To(o.Equal([]Allocation{{PciAddress: pci, Netns: deadNetns, PodUID: "uid1"}}))
+		o.Expect(allocator.List()).To(o.ConsistOf(o.HaveField("PodUID", "uid2")))
+
+		// A DEL after the release must not fail
+		o.Expect(allocator.DeleteAllocatedPCI(pci, "container1")).To(o.Succeed())
+	})
+
+	g.DescribeTable("should only release the allocation of the container",
+		func(a Allocation, containerID string, released bool) {
+			o.Expect(allocator.Allocate(pci, a)).To(o.Succeed())
+			o.Expect(allocator.DeleteAllocatedPCI(pci, containerID)).To(o.Succeed())
+			o.Expect(allocator.IsAllocated(pci)).To(o.Equal(!released))
+		},
+		g.Entry("of the container", Allocation{Netns: liveNetns, ContainerID: "container1"}, "container1", true),
+		g.Entry("of another container", Allocation{Netns: liveNetns, ContainerID: "container2"}, "container1", false),
+		g.Entry("without a container ID", Allocation{Netns: liveNetns}, "container1", true),
+	)
+})
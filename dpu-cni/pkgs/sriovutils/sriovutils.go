@@ -18,6 +18,8 @@ var (
 	NetDirectory = "/sys/class/net"
 	// SysBusPci is sysfs pci device directory
 	SysBusPci = "/sys/bus/pci/devices"
+	// SysBusAux is sysfs auxiliary device directory
+	SysBusAux = "/sys/bus/auxiliary/devices"
 	// SysV4ArpNotify is the sysfs IPv4 ARP Notify directory
 	SysV4ArpNotify = "/proc/sys/net/ipv4/conf/"
 	// SysV6NdiscNotify is the sysfs IPv6 Neighbor Discovery Notify directory
@@ -26,8 +28,93 @@ var (
 	UserspaceDrivers = []string{"vfio-pci", "uio_pci_generic", "igb_uio"}
 )
 
-// EnableArpAndNdiscNotify enables IPv4 arp_notify and IPv6 ndisc_notify for netdev
+// Sysfs locates the sysfs and procfs files of the network devices below a
+// root directory, / on a node. Pointed at another root it works on a fake
+// sysfs, e.g. in tests.
+type Sysfs struct {
+	netDirectory     string
+	sysBusPci        string
+	sysBusAux        string
+	sysV4ArpNotify   string
+	sysV6NdiscNotify string
+}
+
+// NewSysfs returns the sysfs below root
+func NewSysfs(root string) *Sysfs {
+	return &Sysfs{
+		netDirectory:     filepath.Join(root, NetDirectory),
+		sysBusPci:        filepath.Join(root, SysBusPci),
+		sysBusAux:        filepath.Join(root, SysBusAux),
+		sysV4ArpNotify:   filepath.Join(root, SysV4ArpNotify),
+		sysV6NdiscNotify: filepath.Join(root, SysV6NdiscNotify),
+	}
+}
+
+// The functions below look up the devices of the node, see the methods of
+// Sysfs.
+
 func EnableArpAndNdiscNotify(ifName string) error {
+	return NewSysfs("/").EnableArpAndNdiscNotify(ifName)
+}
+
+func GetPciFromNetDev(ifName string) (string, error) {
+	return NewSysfs("/").GetPciFromNetDev(ifName)
+}
+
+func GetSriovNumVfs(ifName string) (int, error) {
+	return NewSysfs("/").GetSriovNumVfs(ifName)
+}
+
+func GetPfVendor(ifName string) (string, error) {
+	return NewSysfs("/").GetPfVendor(ifName)
+}
+
+func GetVfid(addr string, pfName string) (int, error) {
+	return NewSysfs("/").GetVfid(addr, pfName)
+}
+
+func GetPfName(vf string) (string, error) {
+	return NewSysfs("/").GetPfName(vf)
+}
+
+func GetPfPci(vf string) (string, error) {
+	return NewSysfs("/").GetPfPci(vf)
+}
+
+func GetPciAddress(ifName string, vf int) (string, error) {
+	return NewSysfs("/").GetPciAddress(ifName, vf)
+}
+
+func GetSharedPF(ifName string) (string, error) {
+	return NewSysfs("/").GetSharedPF(ifName)
+}
+
+func GetVFLinkName(pciAddr string) (string, error) {
+	return NewSysfs("/").GetVFLinkName(pciAddr)
+}
+
+func GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
+	return NewSysfs("/").GetVFLinkNamesFromVFID(pfName, vfID)
+}
+
+func GetNetNameFromAuxDevice(auxDev string) (string, error) {
+	return NewSysfs("/").GetNetNameFromAuxDevice(auxDev)
+}
+
+func GetPfPciFromAux(auxDev string) (string, error) {
+	return NewSysfs("/").GetPfPciFromAux(auxDev)
+}
+
+func GetSfIndex(auxDev string) (int, error) {
+	return NewSysfs("/").GetSfIndex(auxDev)
+}
+
+func HasDpdkDriver(pciAddr string) (bool, error) {
+	return NewSysfs("/").HasDpdkDriver(pciAddr)
+}
+
+// EnableArpAndNdiscNotify enables IPv4 arp_notify and IPv6 ndisc_notify for netdev
+func (s *Sysfs) EnableArpAndNdiscNotify(ifName string) error {
 	/* For arp_notify, when a value of "1" is set then a Gratuitous ARP request will be sent
 	 * when the network device is brought up or if the link-layer address changes.
 	 * For ndsic_notify, when a value of "1" is set then a Unsolicited Neighbor Advertisement
@@ -36,12 +123,12 @@ func EnableArpAndNdiscNotify(ifName string) error {
 	 * an interface or if the MAC address configuration is changed. The kernel is responsible
 	 * for sending of these packets when the conditions are met.
 	 */
-	v4ArpNotifyPath := filepath.Join(SysV4ArpNotify, ifName, "arp_notify")
+	v4ArpNotifyPath := filepath.Join(s.sysV4ArpNotify, ifName, "arp_notify")
 	err := os.WriteFile(v4ArpNotifyPath, []byte("1"), os.ModeAppend)
 	if err != nil {
 		return fmt.Errorf("failed to write arp_notify=1 for interface %s: %v", ifName, err)
 	}
-	v6NdiscNotifyPath := filepath.Join(SysV6NdiscNotify, ifName, "ndisc_notify")
+	v6NdiscNotifyPath := filepath.Join(s.sysV6NdiscNotify, ifName, "ndisc_notify")
 	err = os.WriteFile(v6NdiscNotifyPath, []byte("1"), os.ModeAppend)
 	if err != nil {
 		return fmt.Errorf("failed to write ndisc_notify=1 for interface %s: %v", ifName, err)
@@ -50,8 +137,8 @@ func EnableArpAndNdiscNotify(ifName string) error {
 }
 
 // GetPciFromNetDev takes in a network device name and returns its PCI address
-func GetPciFromNetDev(ifName string) (string, error) {
-	netDevPath := filepath.Join(NetDirectory, ifName, "device")
+func (s *Sysfs) GetPciFromNetDev(ifName string) (string, error) {
+	netDevPath := filepath.Join(s.netDirectory, ifName, "device")
 	pciAddr, err := filepath.EvalSymlinks(netDevPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to find PCI address for net device %s: %v", ifName, err)
@@ -61,10 +148,10 @@ func GetPciFromNetDev(ifName string) (string, error) {
 }
 
 // GetSriovNumVfs takes in a PF name(ifName) as string and returns number of VF configured as int
-func GetSriovNumVfs(ifName string) (int, error) {
+func (s *Sysfs) GetSriovNumVfs(ifName string) (int, error) {
 	var vfTotal int
 
-	sriovFile := filepath.Join(NetDirectory, ifName, "device", sriovConfigured)
+	sriovFile := filepath.Join(s.netDirectory, ifName, "device", sriovConfigured)
 	if _, err := os.Lstat(sriovFile); err != nil {
 		return vfTotal, fmt.Errorf("failed to open the sriov_numfs of device %q: %v", ifName, err)
 	}
@@ -87,15 +174,25 @@ func GetSriovNumVfs(ifName string) (int, error) {
 	return vfTotal, nil
 }
 
+// GetPfVendor takes in a PF name(ifName) and returns its PCI vendor ID, e.g. "0x8086"
+func (s *Sysfs) GetPfVendor(ifName string) (string, error) {
+	vendorFile := filepath.Join(s.netDirectory, ifName, "device", "vendor")
+	data, err := os.ReadFile(vendorFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the vendor of device %q: %v", ifName, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
 // GetVfid takes in VF's PCI address(addr) and pfName as string and returns VF's ID as int
-func GetVfid(addr string, pfName string) (int, error) {
+func (s *Sysfs) GetVfid(addr string, pfName string) (int, error) {
 	var id int
-	vfTotal, err := GetSriovNumVfs(pfName)
+	vfTotal, err := s.GetSriovNumVfs(pfName)
 	if err != nil {
 		return id, err
 	}
 	for vf := 0; vf < vfTotal; vf++ {
-		vfDir := filepath.Join(NetDirectory, pfName, "device", fmt.Sprintf("virtfn%d", vf))
+		vfDir := filepath.Join(s.netDirectory, pfName, "device", fmt.Sprintf("virtfn%d", vf))
 		_, err := os.Lstat(vfDir)
 		if err != nil {
 			continue
@@ -113,8 +210,8 @@ func GetVfid(addr string, pfName string) (int, error) {
 }
 
 // GetPfName returns PF net device name of a given VF pci address
-func GetPfName(vf string) (string, error) {
-	pfSymLink := filepath.Join(SysBusPci, vf, "physfn", "net")
+func (s *Sysfs) GetPfName(vf string) (string, error) {
+	pfSymLink := filepath.Join(s.sysBusPci, vf, "physfn", "net")
 	_, err := os.Lstat(pfSymLink)
 	if err != nil {
 		return "", err
@@ -132,10 +229,19 @@ func GetPfName(vf string) (string, error) {
 	return strings.TrimSpace(files[0].Name()), nil
 }
 
+// GetPfPci returns the PCI address of the PF of a given VF pci address
+func (s *Sysfs) GetPfPci(vf string) (string, error) {
+	pfLink, err := os.Readlink(filepath.Join(s.sysBusPci, vf, "physfn"))
+	if err != nil {
+		return "", fmt.Errorf("failed to find the PF of VF %s: %v", vf, err)
+	}
+	return filepath.Base(pfLink), nil
+}
+
 // GetPciAddress takes in a interface(ifName) and VF id and returns its pci addr as string
-func GetPciAddress(ifName string, vf int) (string, error) {
+func (s *Sysfs) GetPciAddress(ifName string, vf int) (string, error) {
 	var pciaddr string
-	vfDir := filepath.Join(NetDirectory, ifName, "device", fmt.Sprintf("virtfn%d", vf))
+	vfDir := filepath.Join(s.netDirectory, ifName, "device", fmt.Sprintf("virtfn%d", vf))
 	dirInfo, err := os.Lstat(vfDir)
 	if err != nil {
 		return pciaddr, fmt.Errorf("can't get the symbolic link of virtfn%d dir of the device %q: %v", vf, ifName, err)
@@ -155,9 +261,9 @@ func GetPciAddress(ifName string, vf int) (string, error) {
 }
 
 // GetSharedPF takes in VF name(ifName) as string and returns the other VF name that shares same PCI address as string
-func GetSharedPF(ifName string) (string, error) {
+func (s *Sysfs) GetSharedPF(ifName string) (string, error) {
 	pfName := ""
-	pfDir := filepath.Join(NetDirectory, ifName)
+	pfDir := filepath.Join(s.netDirectory, ifName)
 	dirInfo, err := os.Lstat(pfDir)
 	if err != nil {
 		return pfName, fmt.Errorf("can't get the symbolic link of the device %q: %v", ifName, err)
@@ -182,9 +288,9 @@ func GetSharedPF(ifName string) (string, error) {
 }
 
 // GetVFLinkName returns VF's network interface name given it's PCI addr
-func GetVFLinkName(pciAddr string) (string, error) {
+func (s *Sysfs) GetVFLinkName(pciAddr string) (string, error) {
 	var names []string
-	vfDir := filepath.Join(SysBusPci, pciAddr, "net")
+	vfDir := filepath.Join(s.sysBusPci, pciAddr, "net")
 	if _, err := os.Lstat(vfDir); err != nil {
 		return "", err
 	}
@@ -207,9 +313,9 @@ func GetVFLinkName(pciAddr string) (string, error) {
 }
 
 // GetVFLinkNamesFromVFID returns VF's network interface name given it's PF name as string and VF id as int
-func GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
+func (s *Sysfs) GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
 	var names []string
-	vfDir := filepath.Join(NetDirectory, pfName, "device", fmt.Sprintf("virtfn%d", vfID), "net")
+	vfDir := filepath.Join(s.netDirectory, pfName, "device", fmt.Sprintf("virtfn%d", vfID), "net")
 	if _, err := os.Lstat(vfDir); err != nil {
 		return nil, err
 	}
@@ -227,9 +333,48 @@ func GetVFLinkNamesFromVFID(pfName string, vfID int) ([]string, error) {
 	return names, nil
 }
 
+// GetNetNameFromAuxDevice returns the network interface name of an auxiliary device, such as a sub function
+func (s *Sysfs) GetNetNameFromAuxDevice(auxDev string) (string, error) {
+	netDir := filepath.Join(s.sysBusAux, auxDev, "net")
+	fInfos, err := os.ReadDir(netDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read net dir of the auxiliary device %s: %v", auxDev, err)
+	}
+	if len(fInfos) == 0 {
+		return "", fmt.Errorf("auxiliary device %s sysfs path (%s) has no entries", auxDev, netDir)
+	}
+	return fInfos[0].Name(), nil
+}
+
+// GetPfPciFromAux returns the PCI address of the PF an auxiliary device belongs to
+func (s *Sysfs) GetPfPciFromAux(auxDev string) (string, error) {
+	auxPath, err := filepath.EvalSymlinks(filepath.Join(s.sysBusAux, auxDev))
+	if err != nil {
+		return "", fmt.Errorf("failed to find auxiliary device %s: %v", auxDev, err)
+	}
+	pfPci := filepath.Base(filepath.Dir(auxPath))
+	if !IsValidPCIAddress(pfPci) {
+		return "", fmt.Errorf("parent %s of auxiliary device %s is not a PCI device", pfPci, auxDev)
+	}
+	return pfPci, nil
+}
+
+// GetSfIndex returns the sfnum of a sub function given its auxiliary device name
+func (s *Sysfs) GetSfIndex(auxDev string) (int, error) {
+	data, err := os.ReadFile(filepath.Join(s.sysBusAux, auxDev, "sfnum"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read sfnum of %s: %v", auxDev, err)
+	}
+	sfNum, err := strconv.Atoi(string(bytes.TrimSpace(data)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse sfnum of %s: %v", auxDev, err)
+	}
+	return sfNum, nil
+}
+
 // HasDpdkDriver checks if a device is attached to dpdk supported driver
-func HasDpdkDriver(pciAddr string) (bool, error) {
-	driverLink := filepath.Join(SysBusPci, pciAddr, "driver")
+func (s *Sysfs) HasDpdkDriver(pciAddr string) (bool, error) {
+	driverLink := filepath.Join(s.sysBusPci, pciAddr, "driver")
 	driverPath, err := filepath.EvalSymlinks(driverLink)
 	if err != nil {
 		return false, err
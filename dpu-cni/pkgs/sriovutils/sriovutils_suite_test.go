@@ -0,0 +1,13 @@
+package sriovutils
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSriovutils(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sriovutils Suite")
+}
@@ -0,0 +1,70 @@
+package sriovutils
+
+import (
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("Sub function auxiliary devices", func() {
+	var (
+		root  string
+		sysfs *Sysfs
+	)
+
+	// addAuxDevice adds the auxiliary device dev under the device parent to
+	// the fake sysfs, with the given sfnum if it is not empty
+	addAuxDevice := func(parent string, dev string, sfNum string) {
+		dir := filepath.Join(root, "sys/devices/pci0000:00", parent, dev)
+		o.Expect(os.MkdirAll(filepath.Join(dir, "net", "enp59s0f0s"+sfNum), 0755)).To(o.Succeed())
+		if sfNum != "" {
+			o.Expect(os.WriteFile(filepath.Join(dir, "sfnum"), []byte(sfNum+"\n"), 0644)).To(o.Succeed())
+		}
+		o.Expect(os.Symlink(dir, filepath.Join(root, "sys/bus/auxiliary/devices", dev))).To(o.Succeed())
+	}
+
+	g.BeforeEach(func() {
+		root = g.GinkgoT().TempDir()
+		sysfs = NewSysfs(root)
+		o.Expect(os.MkdirAll(filepath.Join(root, "sys/bus/auxiliary/devices"), 0755)).To(o.Succeed())
+		addAuxDevice("0000:3b:00.0", "mlx5_core.sf.2", "7")
+		addAuxDevice("0000:3b:00.0", "mlx5_core.sf.3", "")
+		addAuxDevice("virtual", "mlx5_core.sf.4", "8")
+	})
+
+	g.DescribeTable("GetPfPciFromAux",
+		func(dev string, pfPci string, err string) {
+			got, gotErr := sysfs.GetPfPciFromAux(dev)
+			if err != "" {
+				o.Expect(gotErr).To(o.MatchError(o.ContainSubstring(err)))
+				return
+			}
+			o.Expect(gotErr).NotTo(o.HaveOccurred())
+			o.Expect(got).To(o.Equal(pfPci))
+		},
+		g.Entry("sub function of a PF", "mlx5_core.sf.2", "0000:3b:00.0", ""),
+		g.Entry("parent that is not a PCI device", "mlx5_core.sf.4", "", "is not a PCI device"),
+		g.Entry("missing device", "mlx5_core.sf.9", "", "failed to find auxiliary device"),
+	)
+
+	g.DescribeTable("GetSfIndex",
+		func(dev string, sfNum int, err string) {
+			got, gotErr := sysfs.GetSfIndex(dev)
+			if err != "" {
+				o.Expect(gotErr).To(o.MatchError(o.ContainSubstring(err)))
+				return
+			}
+			o.Expect(gotErr).NotTo(o.HaveOccurred())
+			o.Expect(got).To(o.Equal(sfNum))
+		},
+		g.Entry("sub function", "mlx5_core.sf.2", 7, ""),
+		g.Entry("device without sfnum", "mlx5_core.sf.3", 0, "failed to read sfnum"),
+		g.Entry("missing device", "mlx5_core.sf.9", 0, "failed to read sfnum"),
+	)
+
+	g.It("should find the netdev of a sub function", func() {
+		o.Expect(sysfs.GetNetNameFromAuxDevice("mlx5_core.sf.2")).To(o.Equal("enp59s0f0s7"))
+	})
+})
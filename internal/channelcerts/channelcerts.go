@@ -0,0 +1,186 @@
+package channelcerts
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// The OPI channel between the daemon on the host and the daemon on the DPU is
+// secured with mutual TLS. Both sides present a certificate issued by the
+// same CA for their node, and refuse peers that are not the node they are
+// paired with.
+const (
+	// PeerNodeLabel is the label of a DPU node naming the node on the other
+	// side of its pair: the DPU node on a host, the host node on a DPU.
+	PeerNodeLabel = "dpu.config.openshift.io/peer-node"
+
+	// CASecretName is the Secret holding the CA that issues node
+	// certificates. Host and DPU run in separate clusters, so the same CA
+	// Secret needs to exist in both for the daemons to trust each other. The
+	// operator only generates one when none is present.
+	CASecretName = "dpu-channel-ca"
+
+	CACertKey = "ca.crt"
+	CAKeyKey  = "ca.key"
+	// PeerNodeKey is the key of the node Secret holding the name of the peer
+	// node, the only one the daemon accepts on the channel.
+	PeerNodeKey = "peer-node"
+
+	CAValidity   = 10 * 365 * 24 * time.Hour
+	CertValidity = 90 * 24 * time.Hour
+)
+
+// NodeSecretName returns the name of the Secret holding the certificate of
+// the daemon on the given node.
+func NodeSecretName(nodeName string) string {
+	return "dpu-channel-" + nodeName
+}
+
+// KeyPair is a PEM encoded certificate and its private key.
+type KeyPair struct {
+	CertPEM []byte
+	KeyPEM  []byte
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+func encode(template, parent *x509.Certificate, pub *ecdsa.PublicKey, signer *ecdsa.PrivateKey, key *ecdsa.PrivateKey) (*KeyPair, error) {
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, pub, signer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %v", err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	return &KeyPair{
+		CertPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		KeyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}),
+	}, nil
+}
+
+// GenerateCA creates a new self-signed CA valid from now.
+func GenerateCA(now time.Time) (*KeyPair, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %v", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "dpu-operator-channel-ca"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return encode(template, template, &key.PublicKey, key, key)
+}
+
+// IssueCert creates a certificate for the daemon on nodeName signed by ca.
+// The certificate is usable both as a server and as a client certificate
+// since either side of the channel may be on any node. Its only name is the
+// node name, the peer verifies it against the node it is paired with.
+func IssueCert(ca *KeyPair, nodeName string, now time.Time) (*KeyPair, error) {
+	caCert, caKey, err := parse(ca)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA: %v", err)
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate key: %v", err)
+	}
+	serial, err := newSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: nodeName},
+		DNSNames:     []string{nodeName},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(CertValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	return encode(template, caCert, &key.PublicKey, caKey, key)
+}
+
+func parse(kp *KeyPair) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	pair, err := tls.X509KeyPair(kp.CertPEM, kp.KeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("unsupported private key type")
+	}
+	return cert, key, nil
+}
+
+func parseCert(certPEM []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// NeedsRenewal reports whether certPEM must be (re)issued: it is missing,
+// unparsable, not only for nodeName, not signed by caPEM, or has less than a
+// third of its validity left.
+func NeedsRenewal(certPEM []byte, caPEM []byte, nodeName string, now time.Time) bool {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return true
+	}
+	if cert.Subject.CommonName != nodeName || len(cert.DNSNames) != 1 || cert.DNSNames[0] != nodeName {
+		return true
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return true
+	}
+	_, err = cert.Verify(x509.VerifyOptions{
+		Roots:       pool,
+		CurrentTime: now,
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return true
+	}
+	return now.After(renewAt(cert))
+}
+
+// RenewalTime returns the time at which certPEM should be replaced.
+func RenewalTime(certPEM []byte) (time.Time, error) {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return renewAt(cert), nil
+}
+
+func renewAt(cert *x509.Certificate) time.Time {
+	validity := cert.NotAfter.Sub(cert.NotBefore)
+	return cert.NotAfter.Add(-validity / 3)
+}
@@ -0,0 +1,13 @@
+package channelcerts_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestChannelcerts(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "ChannelCerts Suite")
+}
@@ -0,0 +1,143 @@
+package channelcerts_test
+
+import (
+	"crypto/tls"
+	"net"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func newSource(ca *channelcerts.KeyPair, node string, peer string, now time.Time) channelcerts.Source {
+	kp, err := channelcerts.IssueCert(ca, node, now)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	src, err := channelcerts.NewStaticSource(kp.CertPEM, kp.KeyPEM, ca.CertPEM, peer)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	return src
+}
+
+func clientConfig(src channelcerts.Source) *tls.Config {
+	config, err := channelcerts.ClientConfig(src)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	return config
+}
+
+// handshake runs a TLS handshake between client and server configurations
+// and returns the error seen by the server.
+func handshake(client *tls.Config, server *tls.Config) error {
+	_, err, _ := runHandshake(client, server)
+	return err
+}
+
+// clientHandshake runs a TLS handshake between client and server
+// configurations and returns the error seen by the client.
+func clientHandshake(client *tls.Config, server *tls.Config) error {
+	_, _, err := runHandshake(client, server)
+	return err
+}
+
+// runHandshake runs a TLS handshake between client and server configurations
+// and returns the connection state and error of the server, and the error of
+// the client.
+func runHandshake(client *tls.Config, server *tls.Config) (tls.ConnectionState, error, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	o.Expect(err).NotTo(o.HaveOccurred())
+	defer listener.Close()
+
+	clientErr := make(chan error, 1)
+	go func() {
+		c, err := tls.Dial("tcp", listener.Addr().String(), client)
+		if err == nil {
+			c.Close()
+		}
+		clientErr <- err
+	}()
+	conn, err := listener.Accept()
+	o.Expect(err).NotTo(o.HaveOccurred())
+	serverConn := tls.Server(conn, server)
+	err = serverConn.Handshake()
+	// Unblock a client waiting for the server
+	conn.Close()
+	return serverConn.ConnectionState(), err, <-clientErr
+}
+
+var _ = g.Describe("Channel certificates", func() {
+	var (
+		now time.Time
+		ca  *channelcerts.KeyPair
+	)
+
+	g.BeforeEach(func() {
+		var err error
+		now = time.Now()
+		ca, err = channelcerts.GenerateCA(now)
+		o.Expect(err).NotTo(o.HaveOccurred())
+	})
+
+	g.It("should authenticate peers with certificates from the same CA", func() {
+		host := newSource(ca, "host", "dpu", now)
+		dpu := newSource(ca, "dpu", "host", now)
+		o.Expect(handshake(clientConfig(host), channelcerts.ServerConfig(dpu))).To(o.Succeed())
+	})
+
+	g.It("should identify the peer node from its certificate", func() {
+		host := newSource(ca, "host", "dpu", now)
+		dpu := newSource(ca, "dpu", "host", now)
+		state, err, _ := runHandshake(clientConfig(host), channelcerts.ServerConfig(dpu))
+		o.Expect(err).NotTo(o.HaveOccurred())
+		p := &peer.Peer{AuthInfo: credentials.TLSInfo{State: state}}
+		o.Expect(channelcerts.PeerNodeName(p)).To(o.Equal("host"))
+	})
+
+	g.It("should refuse clients without a certificate", func() {
+		dpu := newSource(ca, "dpu", "host", now)
+		client := &tls.Config{InsecureSkipVerify: true}
+		o.Expect(handshake(client, channelcerts.ServerConfig(dpu))).NotTo(o.Succeed())
+	})
+
+	g.It("should refuse peers with a certificate from another CA", func() {
+		otherCa, err := channelcerts.GenerateCA(now)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		host := newSource(otherCa, "host", "dpu", now)
+		dpu := newSource(ca, "dpu", "host", now)
+		o.Expect(handshake(clientConfig(host), channelcerts.ServerConfig(dpu))).NotTo(o.Succeed())
+	})
+
+	g.It("should refuse a host that is not paired with the DPU", func() {
+		otherHost := newSource(ca, "other-host", "dpu", now)
+		dpu := newSource(ca, "dpu", "host", now)
+		err := handshake(clientConfig(otherHost), channelcerts.ServerConfig(dpu))
+		o.Expect(err).To(o.MatchError(o.ContainSubstring(`not the paired node "host"`)))
+	})
+
+	g.It("should refuse a DPU that is not paired with the host", func() {
+		host := newSource(ca, "host", "dpu", now)
+		otherDpu := newSource(ca, "other-dpu", "host", now)
+		err := clientHandshake(clientConfig(host), channelcerts.ServerConfig(otherDpu))
+		o.Expect(err).To(o.MatchError(o.ContainSubstring(`not the paired node "dpu"`)))
+	})
+
+	g.It("should refuse credentials without a peer node", func() {
+		kp, err := channelcerts.IssueCert(ca, "host", now)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		_, err = channelcerts.NewStaticSource(kp.CertPEM, kp.KeyPEM, ca.CertPEM, "")
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("no peer node")))
+	})
+
+	g.It("should renew certificates that are missing, foreign or close to expiry", func() {
+		kp, err := channelcerts.IssueCert(ca, "node", now)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		otherCa, err := channelcerts.GenerateCA(now)
+		o.Expect(err).NotTo(o.HaveOccurred())
+
+		o.Expect(channelcerts.NeedsRenewal(kp.CertPEM, ca.CertPEM, "node", now)).To(o.BeFalse())
+		o.Expect(channelcerts.NeedsRenewal(nil, ca.CertPEM, "node", now)).To(o.BeTrue())
+		o.Expect(channelcerts.NeedsRenewal(kp.CertPEM, otherCa.CertPEM, "node", now)).To(o.BeTrue())
+		o.Expect(channelcerts.NeedsRenewal(kp.CertPEM, ca.CertPEM, "other-node", now)).To(o.BeTrue())
+		o.Expect(channelcerts.NeedsRenewal(kp.CertPEM, ca.CertPEM, "node", now.Add(channelcerts.CertValidity*3/4))).To(o.BeTrue())
+	})
+})
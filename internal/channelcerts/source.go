@@ -0,0 +1,230 @@
+package channelcerts
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DefaultRefreshInterval is how often a SecretSource re-reads its Secret so
+// that rotated certificates are picked up without restarting the daemon.
+const DefaultRefreshInterval = 5 * time.Minute
+
+// Credentials is the certificate of this daemon, the CAs it trusts and the
+// node it is paired with.
+type Credentials struct {
+	Certificate tls.Certificate
+	CAs         *x509.CertPool
+	// PeerNodeName is the node whose certificate the peer must present
+	PeerNodeName string
+}
+
+// Source provides the current credentials for the OPI channel. It is
+// consulted on every handshake so rotated certificates take effect for new
+// connections.
+type Source interface {
+	Credentials() (*Credentials, error)
+}
+
+func newCredentials(certPEM, keyPEM, caPEM []byte, peerNodeName string) (*Credentials, error) {
+	if peerNodeName == "" {
+		return nil, errors.New("no peer node")
+	}
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key pair: %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("invalid CA bundle")
+	}
+	return &Credentials{Certificate: cert, CAs: pool, PeerNodeName: peerNodeName}, nil
+}
+
+// StaticSource always returns the same credentials.
+type StaticSource struct {
+	creds *Credentials
+}
+
+func NewStaticSource(certPEM, keyPEM, caPEM []byte, peerNodeName string) (*StaticSource, error) {
+	creds, err := newCredentials(certPEM, keyPEM, caPEM, peerNodeName)
+	if err != nil {
+		return nil, err
+	}
+	return &StaticSource{creds: creds}, nil
+}
+
+func (s *StaticSource) Credentials() (*Credentials, error) {
+	return s.creds, nil
+}
+
+// SecretSource reads the credentials of a node from the Secret maintained by
+// the operator and caches them for the refresh interval.
+type SecretSource struct {
+	log     logr.Logger
+	client  client.Reader
+	key     types.NamespacedName
+	refresh time.Duration
+
+	mu        sync.Mutex
+	creds     *Credentials
+	fetchedAt time.Time
+}
+
+func NewSecretSource(c client.Reader, namespace string, nodeName string) *SecretSource {
+	return &SecretSource{
+		log:     ctrl.Log.WithName("ChannelCerts"),
+		client:  c,
+		key:     types.NamespacedName{Namespace: namespace, Name: NodeSecretName(nodeName)},
+		refresh: DefaultRefreshInterval,
+	}
+}
+
+func (s *SecretSource) Credentials() (*Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.creds != nil && time.Since(s.fetchedAt) < s.refresh {
+		return s.creds, nil
+	}
+
+	creds, err := s.fetch()
+	if err != nil {
+		if s.creds != nil {
+			// Keep using the previous credentials until they can be
+			// refreshed, they are valid for much longer than the
+			// refresh interval.
+			s.log.Error(err, "Failed to refresh channel credentials", "secret", s.key)
+			return s.creds, nil
+		}
+		return nil, err
+	}
+	s.creds = creds
+	s.fetchedAt = time.Now()
+	return creds, nil
+}
+
+func (s *SecretSource) fetch() (*Credentials, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	secret := &corev1.Secret{}
+	if err := s.client.Get(ctx, s.key, secret); err != nil {
+		return nil, fmt.Errorf("failed to get secret %v: %v", s.key, err)
+	}
+	creds, err := newCredentials(secret.Data[corev1.TLSCertKey], secret.Data[corev1.TLSPrivateKeyKey], secret.Data[CACertKey], string(secret.Data[PeerNodeKey]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret %v: %v", s.key, err)
+	}
+	return creds, nil
+}
+
+// ServerConfig returns the TLS configuration for the OPI server on the DPU.
+// Clients must present the certificate of the paired host issued by the
+// channel CA.
+func ServerConfig(src Source) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			creds, err := src.Credentials()
+			if err != nil {
+				return nil, err
+			}
+			return &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{creds.Certificate},
+				ClientCAs:    creds.CAs,
+				ClientAuth:   tls.RequireAndVerifyClientCert,
+				NextProtos:   []string{"h2"},
+				// The chain is verified against the CA already, only the
+				// paired host is accepted
+				VerifyPeerCertificate: func(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+					if len(verifiedChains) == 0 || len(verifiedChains[0]) == 0 {
+						return errors.New("client presented no verified certificate")
+					}
+					return verifyPeerNode(verifiedChains[0][0], creds.PeerNodeName)
+				},
+			}, nil
+		},
+	}
+}
+
+// ClientConfig returns the TLS configuration the host uses to dial the OPI
+// server on the DPU. The server must present the certificate of the paired
+// DPU node.
+func ClientConfig(src Source) (*tls.Config, error) {
+	creds, err := src.Credentials()
+	if err != nil {
+		return nil, err
+	}
+	peerNodeName := creds.PeerNodeName
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: peerNodeName,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			creds, err := src.Credentials()
+			if err != nil {
+				return nil, err
+			}
+			return &creds.Certificate, nil
+		},
+		// The server certificate is verified in VerifyConnection against the
+		// CA that is current at handshake time instead of a pool fixed at
+		// dial time.
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			creds, err := src.Credentials()
+			if err != nil {
+				return err
+			}
+			if len(cs.PeerCertificates) == 0 {
+				return errors.New("server presented no certificate")
+			}
+			intermediates := x509.NewCertPool()
+			for _, cert := range cs.PeerCertificates[1:] {
+				intermediates.AddCert(cert)
+			}
+			_, err = cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:         creds.CAs,
+				Intermediates: intermediates,
+			})
+			if err != nil {
+				return err
+			}
+			return verifyPeerNode(cs.PeerCertificates[0], peerNodeName)
+		},
+	}, nil
+}
+
+// verifyPeerNode checks that cert is the certificate of the node peerNodeName
+func verifyPeerNode(cert *x509.Certificate, peerNodeName string) error {
+	if cert.Subject.CommonName != peerNodeName {
+		return fmt.Errorf("peer certificate is for node %q, not the paired node %q", cert.Subject.CommonName, peerNodeName)
+	}
+	return cert.VerifyHostname(peerNodeName)
+}
+
+// PeerNodeName returns the node name in the certificate presented by the
+// peer of an authenticated OPI channel connection, or "" if there is none.
+func PeerNodeName(p *peer.Peer) string {
+	if p == nil {
+		return ""
+	}
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return ""
+	}
+	return tlsInfo.State.PeerCertificates[0].Subject.CommonName
+}
@@ -19,16 +19,29 @@ package controller
 import (
 	"context"
 	"embed"
+	"encoding/json"
 	"fmt"
+	"net"
+	"time"
 
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/dpu-operator/api/v1"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
 	"github.com/openshift/dpu-operator/pkgs/render"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 //go:embed bindata/*
@@ -58,10 +71,14 @@ func (r *DpuOperatorConfigReconciler) WithImagePullPolicy(policy string) *DpuOpe
 	return r
 }
 
+//+kubebuilder:rbac:groups=config.openshift.io,resources=dpunodepairs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=config.openshift.io,resources=dpunodepairs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=config.openshift.io,resources=dpuoperatorconfigs,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=config.openshift.io,resources=dpuoperatorconfigs/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=config.openshift.io,resources=dpuoperatorconfigs/finalizers,verbs=update
 //+kubebuilder:rbac:groups=config.openshift.io,resources=servicefunctionchains/finalizers,verbs=create;delete;get;list;patch;update;watch
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
 //+kubebuilder:rbac:groups="",resources=serviceaccounts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=rbac.authorization.k8s.io,resources=rolebindings,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=roles,resources=*,verbs=get;list;watch;create;update;patch;delete
@@ -107,7 +124,13 @@ func (r *DpuOperatorConfigReconciler) Reconcile(ctx context.Context, req ctrl.Re
 		return ctrl.Result{}, err
 	}
 
-	return ctrl.Result{}, nil
+	renewAfter, err := r.ensureChannelCertificates(ctx, dpuOperatorConfig)
+	if err != nil {
+		logger.Error(err, "Failed to ensure OPI channel certificates")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{RequeueAfter: renewAfter}, nil
 }
 
 func (r *DpuOperatorConfigReconciler) createCommonData(cfg *configv1.DpuOperatorConfig) map[string]string {
@@ -141,23 +164,218 @@ func (r *DpuOperatorConfigReconciler) ensureDpuDeamonSet(ctx context.Context, cf
 func (r *DpuOperatorConfigReconciler) ensureNetworkFunctioNAD(ctx context.Context, cfg *configv1.DpuOperatorConfig) error {
 	logger := log.FromContext(ctx)
 	logger.Info("Create the Network Function NAD")
-	nadFile := ""
 	switch cfg.Spec.Mode {
 	case "dpu":
-		nadFile = "networkfn-nad-dpu"
+		return r.createAndApplyAllFromBinData(logger, "networkfn-nad-dpu", cfg)
 	case "host":
-		nadFile = "networkfn-nad-host"
+		ranges, err := networkFunctionIpamRanges(cfg.Spec.NetworkFunctionSubnets)
+		if err != nil {
+			err = errors.NewBadRequest(err.Error())
+			logger.Error(err, "Invalid network function subnets specified")
+			return err
+		}
+		data := r.createCommonData(cfg)
+		data["IpamRanges"] = ranges
+		return render.ApplyAllFromBinData(logger, "networkfn-nad-host", data, binData, r.Client, cfg, r.Scheme)
 	default:
 		err := errors.NewBadRequest(fmt.Sprintf("Invalid Mode: %s", cfg.Spec.Mode))
 		logger.Error(err, "Invalid mode specified")
 		return err
 	}
-	return r.createAndApplyAllFromBinData(logger, nadFile, cfg)
+}
+
+// defaultNetworkFunctionSubnet is the subnet of the network function
+// interfaces on the host when none is configured
+const defaultNetworkFunctionSubnet = "10.56.217.0/24"
+
+// networkFunctionIpamRanges returns the host-local IPAM "ranges" of the
+// subnets, one range set per address family, so that a dual-stack
+// configuration assigns an IPv4 and an IPv6 address to each interface.
+func networkFunctionIpamRanges(subnets []string) (string, error) {
+	if len(subnets) == 0 {
+		subnets = []string{defaultNetworkFunctionSubnet}
+	}
+
+	type ipamRange struct {
+		Subnet string `json:"subnet"`
+	}
+	var ranges [][]ipamRange
+	var v4, v6 bool
+	for _, subnet := range subnets {
+		ip, ipNet, err := net.ParseCIDR(subnet)
+		if err != nil {
+			return "", fmt.Errorf("invalid network function subnet %q: %v", subnet, err)
+		}
+		family := &v6
+		if ip.To4() != nil {
+			family = &v4
+		}
+		if *family {
+			return "", fmt.Errorf("network function subnet %q: only one subnet per address family is supported", subnet)
+		}
+		*family = true
+		ranges = append(ranges, []ipamRange{{Subnet: ipNet.String()}})
+	}
+
+	data, err := json.Marshal(ranges)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ensureChannelCA returns the CA issuing the certificates of the OPI channel,
+// generating it if it doesn't exist yet. The CA Secret is deliberately not
+// owned by the DpuOperatorConfig: it may have been copied from the peer
+// cluster and must outlive the config to keep both clusters trusting each
+// other.
+func (r *DpuOperatorConfigReconciler) ensureChannelCA(ctx context.Context, namespace string) (*channelcerts.KeyPair, error) {
+	logger := log.FromContext(ctx)
+	secret := &corev1.Secret{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: channelcerts.CASecretName}, secret)
+	if err == nil {
+		return &channelcerts.KeyPair{
+			CertPEM: secret.Data[channelcerts.CACertKey],
+			KeyPEM:  secret.Data[channelcerts.CAKeyKey],
+		}, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get CA secret: %v", err)
+	}
+
+	logger.Info("Generating OPI channel CA", "secret", channelcerts.CASecretName)
+	ca, err := channelcerts.GenerateCA(time.Now())
+	if err != nil {
+		return nil, err
+	}
+	secret = &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: channelcerts.CASecretName, Namespace: namespace},
+		Data: map[string][]byte{
+			channelcerts.CACertKey: ca.CertPEM,
+			channelcerts.CAKeyKey:  ca.KeyPEM,
+		},
+	}
+	if err := r.Create(ctx, secret); err != nil {
+		return nil, fmt.Errorf("failed to create CA secret: %v", err)
+	}
+	return ca, nil
+}
+
+// ensureChannelCertificates issues a certificate for the daemon on every DPU
+// node labeled with its peer node and renews those that are about to expire
+// or whose node was paired with another peer. It returns the time until
+// the next certificate has to be renewed.
+func (r *DpuOperatorConfigReconciler) ensureChannelCertificates(ctx context.Context, cfg *configv1.DpuOperatorConfig) (time.Duration, error) {
+	logger := log.FromContext(ctx)
+	namespace := r.createCommonData(cfg)["Namespace"]
+
+	ca, err := r.ensureChannelCA(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+
+	nodes := &corev1.NodeList{}
+	if err := r.List(ctx, nodes, client.MatchingLabels{"dpu": "true"}); err != nil {
+		return 0, fmt.Errorf("failed to list DPU nodes: %v", err)
+	}
+
+	now := time.Now()
+	renewAfter := channelcerts.CertValidity
+	for _, node := range nodes.Items {
+		peerNodeName := node.Labels[channelcerts.PeerNodeLabel]
+		if peerNodeName == "" {
+			logger.Info("Not issuing OPI channel certificate, node has no peer", "node", node.Name, "label", channelcerts.PeerNodeLabel)
+			continue
+		}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: channelcerts.NodeSecretName(node.Name), Namespace: namespace},
+		}
+		_, err := controllerutil.CreateOrUpdate(ctx, r.Client, secret, func() error {
+			if secret.CreationTimestamp.IsZero() {
+				secret.Type = corev1.SecretTypeTLS
+			}
+			if secret.Data == nil {
+				secret.Data = map[string][]byte{}
+			}
+			if channelcerts.NeedsRenewal(secret.Data[corev1.TLSCertKey], ca.CertPEM, node.Name, now) ||
+				string(secret.Data[channelcerts.PeerNodeKey]) != peerNodeName {
+				logger.Info("Issuing OPI channel certificate", "node", node.Name, "peer", peerNodeName)
+				kp, err := channelcerts.IssueCert(ca, node.Name, now)
+				if err != nil {
+					return err
+				}
+				secret.Data[corev1.TLSCertKey] = kp.CertPEM
+				secret.Data[corev1.TLSPrivateKeyKey] = kp.KeyPEM
+			}
+			secret.Data[channelcerts.CACertKey] = ca.CertPEM
+			secret.Data[channelcerts.PeerNodeKey] = []byte(peerNodeName)
+			return controllerutil.SetControllerReference(cfg, secret, r.Scheme)
+		})
+		if err != nil {
+			return 0, fmt.Errorf("failed to ensure certificate for node %s: %v", node.Name, err)
+		}
+
+		renewAt, err := channelcerts.RenewalTime(secret.Data[corev1.TLSCertKey])
+		if err != nil {
+			return 0, err
+		}
+		if d := renewAt.Sub(now); d < renewAfter {
+			renewAfter = d
+		}
+	}
+	return renewAfter, nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DpuOperatorConfigReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&configv1.DpuOperatorConfig{}).
+		Owns(&corev1.Secret{}).
+		// Nodes joining the cluster or getting paired need a certificate for
+		// the OPI channel
+		Watches(&corev1.Node{}, handler.EnqueueRequestsFromMapFunc(r.requestsForNode),
+			builder.WithPredicates(channelNodePredicate())).
 		Complete(r)
 }
+
+// isChannelNode returns whether node is a DPU node or paired with a peer
+// node, i.e. whether it may need a certificate for the OPI channel
+func isChannelNode(node client.Object) bool {
+	labels := node.GetLabels()
+	return labels["dpu"] == "true" || labels[channelcerts.PeerNodeLabel] != ""
+}
+
+// channelNodePredicate ignores the frequent Node status updates and only
+// passes Nodes joining or leaving the cluster and changes to the labels the
+// OPI channel certificates depend on
+func channelNodePredicate() predicate.Predicate {
+	return predicate.Funcs{
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			oldLabels := e.ObjectOld.GetLabels()
+			newLabels := e.ObjectNew.GetLabels()
+			return oldLabels["dpu"] != newLabels["dpu"] ||
+				oldLabels[channelcerts.PeerNodeLabel] != newLabels[channelcerts.PeerNodeLabel]
+		},
+		GenericFunc: func(e event.GenericEvent) bool {
+			return false
+		},
+	}
+}
+
+func (r *DpuOperatorConfigReconciler) requestsForNode(ctx context.Context, obj client.Object) []reconcile.Request {
+	if !isChannelNode(obj) {
+		return nil
+	}
+	cfgs := &configv1.DpuOperatorConfigList{}
+	if err := r.List(ctx, cfgs); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list DpuOperatorConfigs")
+		return nil
+	}
+	requests := []reconcile.Request{}
+	for _, cfg := range cfgs.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: cfg.Namespace, Name: cfg.Name},
+		})
+	}
+	return requests
+}
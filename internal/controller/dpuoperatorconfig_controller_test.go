@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"sync"
+	"time"
 
 	netattdefv1 "github.com/k8snetworkplumbingwg/network-attachment-definition-client/pkg/apis/k8s.cni.cncf.io/v1"
 
@@ -27,10 +28,12 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/server"
 	"sigs.k8s.io/controller-runtime/pkg/webhook"
 
+	"github.com/openshift/dpu-operator/internal/channelcerts"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	"github.com/openshift/dpu-operator/internal/testutils"
 
@@ -268,3 +271,71 @@ var _ = Describe("Main Controller", Ordered, func() {
 		})
 	})
 })
+
+var _ = Describe("Network function IPAM ranges", func() {
+	DescribeTable("should render one range set per address family",
+		func(subnets []string, expected string) {
+			ranges, err := networkFunctionIpamRanges(subnets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ranges).To(Equal(expected))
+		},
+		Entry("default", nil, `[[{"subnet":"10.56.217.0/24"}]]`),
+		Entry("IPv6 only", []string{"fd00:56:217::/64"}, `[[{"subnet":"fd00:56:217::/64"}]]`),
+		Entry("dual-stack", []string{"10.56.217.0/24", "fd00:56:217::/64"},
+			`[[{"subnet":"10.56.217.0/24"}],[{"subnet":"fd00:56:217::/64"}]]`),
+	)
+
+	DescribeTable("should reject invalid subnets",
+		func(subnets []string) {
+			_, err := networkFunctionIpamRanges(subnets)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("not a CIDR", []string{"10.56.217.1"}),
+		Entry("two IPv4 subnets", []string{"10.56.217.0/24", "10.56.218.0/24"}),
+		Entry("two IPv6 subnets", []string{"fd00:1::/64", "fd00:2::/64"}),
+	)
+})
+
+var _ = Describe("OPI channel node watch", func() {
+	node := func(labels map[string]string, heartbeat metav1.Time) *corev1.Node {
+		return &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-1", Labels: labels},
+			Status: corev1.NodeStatus{
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, LastHeartbeatTime: heartbeat}},
+			},
+		}
+	}
+	dpu := map[string]string{"dpu": "true"}
+	paired := map[string]string{"dpu": "true", channelcerts.PeerNodeLabel: "host-1"}
+	repaired := map[string]string{"dpu": "true", channelcerts.PeerNodeLabel: "host-2"}
+	now := metav1.Now()
+	later := metav1.NewTime(now.Add(time.Minute))
+
+	DescribeTable("should only pass label changes the certificates depend on",
+		func(oldLabels, newLabels map[string]string, expected bool) {
+			p := channelNodePredicate()
+			Expect(p.Update(event.UpdateEvent{
+				ObjectOld: node(oldLabels, now),
+				ObjectNew: node(newLabels, later),
+			})).To(Equal(expected))
+		},
+		Entry("heartbeat of a paired node", paired, paired, false),
+		Entry("heartbeat of a plain node", nil, nil, false),
+		Entry("unrelated label", paired, map[string]string{"dpu": "true", channelcerts.PeerNodeLabel: "host-1", "zone": "a"}, false),
+		Entry("node becoming a DPU", nil, dpu, true),
+		Entry("node getting paired", dpu, paired, true),
+		Entry("node paired with another peer", paired, repaired, true),
+		Entry("node getting unpaired", paired, dpu, true),
+	)
+
+	It("should pass nodes joining and leaving the cluster", func() {
+		p := channelNodePredicate()
+		Expect(p.Create(event.CreateEvent{Object: node(dpu, now)})).To(BeTrue())
+		Expect(p.Delete(event.DeleteEvent{Object: node(paired, now)})).To(BeTrue())
+	})
+
+	It("should not reconcile for nodes that are neither DPU nor paired", func() {
+		r := &DpuOperatorConfigReconciler{}
+		Expect(r.requestsForNode(context.Background(), node(map[string]string{"zone": "a"}, now))).To(BeEmpty())
+	})
+})
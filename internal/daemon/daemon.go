@@ -4,10 +4,16 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"os"
 
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cniserver"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
 	dpudevicehandler "github.com/openshift/dpu-operator/internal/daemon/device-handler/dpu-device-handler"
 	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	nodepair "github.com/openshift/dpu-operator/internal/daemon/node-pair"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"github.com/openshift/dpu-operator/internal/platform"
 	"github.com/openshift/dpu-operator/internal/utils"
 
@@ -27,20 +33,37 @@ type SideManager interface {
 	Stop()
 }
 
-func createDaemon(dpuMode bool, config *rest.Config, vspImages map[string]string, client client.Client) (SideManager, error) {
+func createDaemon(dpuMode bool, config *rest.Config, vspImages map[string]string, client client.Client, pm *utils.PathManager, cniAuditLog io.Writer) (SideManager, error) {
 	platform := platform.NewPlatformInfo()
-	plugin, err := platform.VspPlugin(dpuMode, vspImages, client)
+	// The VSP plugin and the device handler share a single connection to the VSP
+	vspClient := vspclient.NewVspClient(vspclient.WithPathManager(*pm))
+	plugin, err := platform.VspPlugin(dpuMode, vspImages, client, vspClient)
 	if err != nil {
 		return nil, err
 	}
 
-	deviceHandler := dpudevicehandler.NewDpuDeviceHandler(dpudevicehandler.WithDpuMode(dpuMode))
+	deviceHandler := dpudevicehandler.NewDpuDeviceHandler(
+		dpudevicehandler.WithDpuMode(dpuMode),
+		dpudevicehandler.WithVspClient(vspClient))
 	dp := deviceplugin.NewDevicePlugin(deviceHandler)
 
+	// The OPI channel between host and DPU is authenticated with the
+	// certificate the operator issued for this node
+	nodeName := os.Getenv("K8S_NODE")
+	channelCreds := channelcerts.NewSecretSource(client, "openshift-dpu-operator", nodeName)
+	nodePair := nodepair.NewReporter(client, vspClient, "openshift-dpu-operator", nodeName, dpuMode)
+
 	if dpuMode {
-		return NewDpuSideManger(plugin, dp, config), nil
+		return NewDpuSideManger(plugin, dp, config,
+			WithChannelCredentials(channelCreds),
+			WithNodePairReporter(nodePair),
+			WithCNIAuditLog(cniAuditLog)), nil
 	} else {
-		return NewHostSideManager(plugin, dp), nil
+		return NewHostSideManager(plugin, dp).
+			WithChannelCredentials(channelCreds).
+			WithNodePairReporter(nodePair).
+			WithCNIAuditLog(cniAuditLog).
+			WithVspClient(vspClient), nil
 	}
 }
 
@@ -51,11 +74,13 @@ type Daemon struct {
 	log       logr.Logger
 	vspImages map[string]string
 	config    *rest.Config
+	// cniAuditLogFile is where the CNI requests are recorded, if set
+	cniAuditLogFile string
 }
 
-func NewDaemon(mode string, client client.Client, scheme *runtime.Scheme, vspImages map[string]string, config *rest.Config) Daemon {
+func NewDaemon(mode string, client client.Client, scheme *runtime.Scheme, vspImages map[string]string, config *rest.Config, options ...func(*Daemon)) Daemon {
 	log := ctrl.Log.WithName("Daemon")
-	return Daemon{
+	d := Daemon{
 		client:    client,
 		mode:      mode,
 		pm:        utils.NewPathManager("/"),
@@ -63,6 +88,18 @@ func NewDaemon(mode string, client client.Client, scheme *runtime.Scheme, vspIma
 		vspImages: vspImages,
 		config:    config,
 	}
+	for _, o := range options {
+		o(&d)
+	}
+	return d
+}
+
+// WithCNIAuditLogFile makes the daemon record every CNI request in path,
+// which is rotated as it grows.
+func WithCNIAuditLogFile(path string) func(*Daemon) {
+	return func(d *Daemon) {
+		d.cniAuditLogFile = path
+	}
 }
 
 func (d *Daemon) Run() error {
@@ -80,7 +117,14 @@ func (d *Daemon) Run() error {
 	if err != nil {
 		return err
 	}
-	daemon, err := createDaemon(dpuMode, d.config, d.vspImages, d.client)
+	var cniAuditLog io.Writer
+	if d.cniAuditLogFile != "" {
+		f := cniserver.NewAuditLogFile(d.cniAuditLogFile, 100, 5)
+		defer f.Close()
+		cniAuditLog = f
+		d.log.Info("Recording CNI requests", "path", d.cniAuditLogFile)
+	}
+	daemon, err := createDaemon(dpuMode, d.config, d.vspImages, d.client, d.pm, cniAuditLog)
 	if err != nil {
 		d.log.Error(err, "Failed to start daemon")
 		return err
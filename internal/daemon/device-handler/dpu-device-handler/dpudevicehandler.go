@@ -3,15 +3,15 @@ package dpudevicehandler
 import (
 	"context"
 	"fmt"
-	"net"
+	"sync"
 
 	"github.com/go-logr/logr"
 	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovtypes"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriovutils"
 	dp "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"github.com/openshift/dpu-operator/internal/utils"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -19,23 +19,35 @@ import (
 // dpuDeviceHandler handles NF networking devices
 type dpuDeviceHandler struct {
 	log logr.Logger
-	// Connection client to the API for the VSP DeviceService
-	client           pb.DeviceServiceClient
-	conn             *grpc.ClientConn
+	// Connection to the VSP, shared with the rest of the daemon
+	vspClient        *vspclient.VspClient
 	pathManager      utils.PathManager
 	setupDevicesDone chan struct{}
 	dpuMode          bool
+	// allocations caches the allocation details of the devices returned by
+	// the last GetDevices call, keyed by the device ID given to Kubelet.
+	allocations   map[string]*dp.DeviceAllocation
+	allocationsMu sync.RWMutex
 }
 
-func normalizeDeviceToPci(device string) (string, error) {
+// normalizeDeviceToPci returns the ID the sriov CNI expects for a host
+// device: its PCI address, or the auxiliary device name for a sub function.
+func normalizeDeviceToPci(device *pb.Device) (string, error) {
+	if device.AuxDevice != "" {
+		if !sriovtypes.IsSFDeviceName(device.AuxDevice) {
+			return "", fmt.Errorf("auxiliary device %s is not a sub function", device.AuxDevice)
+		}
+		return device.AuxDevice, nil
+	}
 
-	if sriovutils.IsValidPCIAddress(device) {
-		return device, nil
+	if sriovutils.IsValidPCIAddress(device.ID) || sriovtypes.IsSFDeviceName(device.ID) {
+		return device.ID, nil
 	}
 
-	pciAddr, err := sriovutils.GetPciFromNetDev(device)
+	// The device of the netdev of a sub function is its auxiliary device
+	pciAddr, err := sriovutils.GetPciFromNetDev(device.ID)
 	if err != nil {
-		return device, fmt.Errorf("failed to get PCI address for netdev %s: %v", device, err)
+		return device.ID, fmt.Errorf("failed to get PCI address for netdev %s: %v", device.ID, err)
 	}
 
 	return pciAddr, nil
@@ -45,55 +57,87 @@ func (d *dpuDeviceHandler) GetDevices() (*dp.DeviceList, error) {
 	// Wait for devices to be done initializing
 	<-d.setupDevicesDone
 
-	err := d.ensureConnected()
+	client, err := d.vspClient.Device()
 	if err != nil {
 		return nil, fmt.Errorf("failed to ensure connection to plugin: %v", err)
 	}
 
-	Devices, err := d.client.GetDevices(context.Background(), &pb.Empty{})
+	ctx, cancel := d.vspClient.RPCContext(context.Background())
+	defer cancel()
+	Devices, err := client.GetDevices(ctx, &pb.Empty{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to handle GetDevices request: %v", err)
 	}
 
 	devices := make(dp.DeviceList)
+	allocations := make(map[string]*dp.DeviceAllocation)
 
 	// TODO: We need to properly enforce API boundaries at the VSP level. The host side requires pci-addresses when handling devices, however the dpu side requires a higher level of abstraction. For now, just enforce PCI addresses for device ID on the host only.
 	for _, device := range Devices.Devices {
 		if d.dpuMode {
 			devices[device.ID] = pluginapi.Device{ID: device.ID, Health: pluginapi.Healthy}
+			allocations[device.ID] = toDeviceAllocation(device)
 			continue
 		}
 
-		devPciId, err := normalizeDeviceToPci(device.ID)
+		devPciId, err := normalizeDeviceToPci(device)
 		if err != nil {
 			return nil, fmt.Errorf("Failed to normalize device %s from GetDevice request: %v", device.ID, err)
 		}
 		devices[devPciId] = pluginapi.Device{ID: devPciId, Health: pluginapi.Healthy}
+		allocation := toDeviceAllocation(device)
+		if sriovtypes.IsSFDeviceName(devPciId) {
+			allocation.AuxDevice = devPciId
+		} else if allocation.PciAddress == "" {
+			allocation.PciAddress = devPciId
+		}
+		allocations[devPciId] = allocation
 	}
 
+	d.allocationsMu.Lock()
+	d.allocations = allocations
+	d.allocationsMu.Unlock()
+
 	return &devices, nil
 }
 
-// ensureConnected makes sure we are connected to the VSP's gRPC
-func (d *dpuDeviceHandler) ensureConnected() error {
-	if d.client != nil {
-		return nil
-	}
-	dialOptions := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-			return net.Dial("unix", addr)
-		}),
-	}
-	conn, err := grpc.DialContext(context.Background(), d.pathManager.VendorPluginSocket(), dialOptions...)
-	if err != nil {
-		return fmt.Errorf("failed to connect to vendor plugin: %v", err)
+// GetDeviceAllocation returns the allocation details the VSP reported for a device
+func (d *dpuDeviceHandler) GetDeviceAllocation(id string) (*dp.DeviceAllocation, error) {
+	d.allocationsMu.RLock()
+	defer d.allocationsMu.RUnlock()
+
+	allocation, ok := d.allocations[id]
+	if !ok {
+		return nil, fmt.Errorf("no allocation details for device %s", id)
 	}
-	d.conn = conn
+	return allocation, nil
+}
 
-	d.client = pb.NewDeviceServiceClient(d.conn)
-	d.log.Info("Connected to DeviceServiceClient")
-	return nil
+// toDeviceAllocation converts the VSP's device description to the Kubelet
+// device plugin types used in the Allocate response.
+func toDeviceAllocation(device *pb.Device) *dp.DeviceAllocation {
+	allocation := &dp.DeviceAllocation{
+		PciAddress: device.PciAddress,
+		AuxDevice:  device.AuxDevice,
+		NetDev:     device.Netdev,
+		MAC:        device.Mac,
+		CDIDevices: device.CdiDevices,
+	}
+	for _, spec := range device.DeviceSpecs {
+		allocation.DeviceSpecs = append(allocation.DeviceSpecs, &pluginapi.DeviceSpec{
+			ContainerPath: spec.ContainerPath,
+			HostPath:      spec.HostPath,
+			Permissions:   spec.Permissions,
+		})
+	}
+	for _, mount := range device.Mounts {
+		allocation.Mounts = append(allocation.Mounts, &pluginapi.Mount{
+			ContainerPath: mount.ContainerPath,
+			HostPath:      mount.HostPath,
+			ReadOnly:      mount.ReadOnly,
+		})
+	}
+	return allocation
 }
 
 // SetupDevices
@@ -108,7 +152,7 @@ func (d *dpuDeviceHandler) SetupDevices() error {
 		return nil
 	}
 
-	err := d.ensureConnected()
+	client, err := d.vspClient.Device()
 	if err != nil {
 		return fmt.Errorf("failed to ensure connection to vsp: %v", err)
 	}
@@ -117,7 +161,9 @@ func (d *dpuDeviceHandler) SetupDevices() error {
 		VfCnt: 8,
 	}
 
-	numVfs, err := d.client.SetNumVfs(context.Background(), vfCount)
+	ctx, cancel := d.vspClient.RPCContext(context.Background())
+	defer cancel()
+	numVfs, err := client.SetNumVfs(ctx, vfCount)
 	if err != nil {
 		return fmt.Errorf("Failed to set sriov numVfs: %v", err)
 	}
@@ -137,6 +183,14 @@ func WithDpuMode(dpuMode bool) func(*dpuDeviceHandler) {
 	}
 }
 
+// WithVspClient makes the device handler share the connection to the VSP with
+// other components of the daemon.
+func WithVspClient(vspClient *vspclient.VspClient) func(*dpuDeviceHandler) {
+	return func(d *dpuDeviceHandler) {
+		d.vspClient = vspClient
+	}
+}
+
 func WithPathManager(pathManager utils.PathManager) func(*dpuDeviceHandler) {
 	return func(d *dpuDeviceHandler) {
 		d.pathManager = pathManager
@@ -145,14 +199,19 @@ func WithPathManager(pathManager utils.PathManager) func(*dpuDeviceHandler) {
 
 func NewDpuDeviceHandler(opts ...func(*dpuDeviceHandler)) *dpuDeviceHandler {
 	devHandler := &dpuDeviceHandler{
-		log:     ctrl.Log.WithName("DpuDeviceHandler"),
-		dpuMode: false,
+		log:         ctrl.Log.WithName("DpuDeviceHandler"),
+		dpuMode:     false,
+		allocations: make(map[string]*dp.DeviceAllocation),
 	}
 
 	for _, opt := range opts {
 		opt(devHandler)
 	}
 
+	if devHandler.vspClient == nil {
+		devHandler.vspClient = vspclient.NewVspClient(vspclient.WithPathManager(devHandler.pathManager))
+	}
+
 	// TODO: When changing the SRIOV numVfs, we should do the following:
 	// 1) Drain all pods running on the node with a drain controller running
 	// on the control plane. The nodes will be marked for draining and read by
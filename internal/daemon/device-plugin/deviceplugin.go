@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -20,6 +22,13 @@ import (
 
 const (
 	DpuResourceName = "openshift.io/dpu"
+
+	// EnvDeviceList is the env variable holding all allocated device IDs
+	EnvDeviceList = "NF-DEV"
+	// EnvDeviceCount is the env variable holding the number of allocated devices
+	EnvDeviceCount = "DPU_DEVICE_COUNT"
+	// EnvDevicePrefix prefixes the per device env variables, see Allocate()
+	EnvDevicePrefix = "DPU_DEVICE_"
 )
 
 // dpServer manages the k8s Device Plugin Server
@@ -103,13 +112,31 @@ func (dp *dpServer) ListAndWatch(empty *pluginapi.Empty, stream pluginapi.Device
 	}
 }
 
-// Allocate passes the dev name as an env variable to the requesting container
+// Allocate passes the devices' identity as env variables to the requesting
+// container, together with the device nodes, mounts and CDI devices the VSP
+// reported for them.
+//
+// For every container the following env variables are set:
+//
+//	NF-DEV                   comma separated list of all allocated device IDs
+//	DPU_DEVICE_COUNT         number of allocated devices
+//	DPU_DEVICE_<N>_ID        device ID as advertised to Kubelet
+//	DPU_DEVICE_<N>_PCI       PCI address of the device, if any
+//	DPU_DEVICE_<N>_AUX       auxiliary device name of a sub function, if any
+//	DPU_DEVICE_<N>_NETDEV    kernel netdev name of the device, if any
+//	DPU_DEVICE_<N>_MAC       MAC address of the device, if any
+//
+// where <N> is the index of the device in the request, starting from 0.
 func (dp *dpServer) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest) (*pluginapi.AllocateResponse, error) {
 	resp := new(pluginapi.AllocateResponse)
-	devName := ""
 	for _, container := range rqt.ContainerRequests {
 		containerResp := new(pluginapi.ContainerAllocateResponse)
-		for _, id := range container.DevicesIDs {
+		envmap := make(map[string]string)
+		deviceSpecs := make(map[string]bool)
+		mounts := make(map[string]bool)
+		cdiDevices := make(map[string]bool)
+
+		for i, id := range container.DevicesIDs {
 			dp.log.Info("DeviceID in Allocate:", "id", id)
 			isHealthy, err := dp.checkCachedDeviceHealth(id)
 			if err != nil {
@@ -121,12 +148,43 @@ func (dp *dpServer) Allocate(ctx context.Context, rqt *pluginapi.AllocateRequest
 				return nil, fmt.Errorf("invalid allocation request with unhealthy device: %s", id)
 			}
 
-			devName = devName + id + ","
+			allocation, err := dp.deviceHandler.GetDeviceAllocation(id)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get allocation for device %s: %v", id, err)
+			}
+
+			prefix := fmt.Sprintf("%s%d_", EnvDevicePrefix, i)
+			envmap[prefix+"ID"] = id
+			envmap[prefix+"PCI"] = allocation.PciAddress
+			envmap[prefix+"AUX"] = allocation.AuxDevice
+			envmap[prefix+"NETDEV"] = allocation.NetDev
+			envmap[prefix+"MAC"] = allocation.MAC
+
+			// Devices may share resources (e.g. a hugepage mount), only pass those once
+			for _, spec := range allocation.DeviceSpecs {
+				if !deviceSpecs[spec.ContainerPath] {
+					deviceSpecs[spec.ContainerPath] = true
+					containerResp.Devices = append(containerResp.Devices, spec)
+				}
+			}
+			for _, mount := range allocation.Mounts {
+				if !mounts[mount.ContainerPath] {
+					mounts[mount.ContainerPath] = true
+					containerResp.Mounts = append(containerResp.Mounts, mount)
+				}
+			}
+			for _, name := range allocation.CDIDevices {
+				if !cdiDevices[name] {
+					cdiDevices[name] = true
+					containerResp.CDIDevices = append(containerResp.CDIDevices, &pluginapi.CDIDevice{Name: name})
+				}
+			}
 		}
 
+		devName := strings.Join(container.DevicesIDs, ",")
 		dp.log.Info("Device(s) allocated:", "devName", devName)
-		envmap := make(map[string]string)
-		envmap["NF-DEV"] = devName
+		envmap[EnvDeviceList] = devName
+		envmap[EnvDeviceCount] = strconv.Itoa(len(container.DevicesIDs))
 
 		containerResp.Envs = envmap
 		resp.ContainerResponses = append(resp.ContainerResponses, containerResp)
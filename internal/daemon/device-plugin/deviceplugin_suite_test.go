@@ -0,0 +1,13 @@
+package deviceplugin
+
+import (
+	"testing"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+func TestDevicePlugin(t *testing.T) {
+	o.RegisterFailHandler(g.Fail)
+	g.RunSpecs(t, "Device Plugin Suite")
+}
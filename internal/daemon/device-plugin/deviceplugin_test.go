@@ -0,0 +1,136 @@
+package deviceplugin
+
+import (
+	"context"
+	"errors"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	pluginapi "k8s.io/kubelet/pkg/apis/deviceplugin/v1beta1"
+)
+
+// fakeDeviceHandler serves fixed allocations, keyed by device ID
+type fakeDeviceHandler struct {
+	devices     DeviceList
+	allocations map[string]*DeviceAllocation
+	allocErr    error
+}
+
+func (f *fakeDeviceHandler) SetupDevices() error {
+	return nil
+}
+
+func (f *fakeDeviceHandler) GetDevices() (*DeviceList, error) {
+	return &f.devices, nil
+}
+
+func (f *fakeDeviceHandler) GetDeviceAllocation(id string) (*DeviceAllocation, error) {
+	if f.allocErr != nil {
+		return nil, f.allocErr
+	}
+	return f.allocations[id], nil
+}
+
+var _ = g.Describe("Device Plugin Allocate", func() {
+	var (
+		dh *fakeDeviceHandler
+		dp *dpServer
+	)
+
+	hugepages := &pluginapi.Mount{ContainerPath: "/dev/hugepages", HostPath: "/dev/hugepages"}
+
+	g.BeforeEach(func() {
+		dh = &fakeDeviceHandler{
+			devices: DeviceList{
+				"vf0":  {ID: "vf0", Health: pluginapi.Healthy},
+				"sf1":  {ID: "sf1", Health: pluginapi.Healthy},
+				"sick": {ID: "sick", Health: pluginapi.Unhealthy},
+			},
+			allocations: map[string]*DeviceAllocation{
+				"vf0": {
+					PciAddress:  "0000:3b:00.2",
+					NetDev:      "ens1f0v0",
+					MAC:         "00:11:22:33:44:55",
+					DeviceSpecs: []*pluginapi.DeviceSpec{{ContainerPath: "/dev/vfio/vfio", HostPath: "/dev/vfio/vfio", Permissions: "rw"}},
+					Mounts:      []*pluginapi.Mount{hugepages},
+					CDIDevices:  []string{"vendor.com/dpu=vf0", "vendor.com/dpu=common"},
+				},
+				"sf1": {
+					PciAddress:  "0000:3b:00.0",
+					AuxDevice:   "mlx5_core.sf.1",
+					DeviceSpecs: []*pluginapi.DeviceSpec{{ContainerPath: "/dev/vfio/vfio", HostPath: "/dev/vfio/vfio", Permissions: "rw"}},
+					Mounts:      []*pluginapi.Mount{hugepages},
+					CDIDevices:  []string{"vendor.com/dpu=sf1", "vendor.com/dpu=common"},
+				},
+			},
+		}
+		dp = NewDevicePlugin(dh)
+		dp.setDeviceCache(&dh.devices)
+	})
+
+	allocate := func(ids ...string) (*pluginapi.AllocateResponse, error) {
+		return dp.Allocate(context.Background(), &pluginapi.AllocateRequest{
+			ContainerRequests: []*pluginapi.ContainerAllocateRequest{{DevicesIDs: ids}},
+		})
+	}
+
+	g.It("should describe each device in the env", func() {
+		resp, err := allocate("vf0", "sf1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(resp.ContainerResponses).To(o.HaveLen(1))
+		o.Expect(resp.ContainerResponses[0].Envs).To(o.Equal(map[string]string{
+			"DPU_DEVICE_0_ID":     "vf0",
+			"DPU_DEVICE_0_PCI":    "0000:3b:00.2",
+			"DPU_DEVICE_0_AUX":    "",
+			"DPU_DEVICE_0_NETDEV": "ens1f0v0",
+			"DPU_DEVICE_0_MAC":    "00:11:22:33:44:55",
+			"DPU_DEVICE_1_ID":     "sf1",
+			"DPU_DEVICE_1_PCI":    "0000:3b:00.0",
+			"DPU_DEVICE_1_AUX":    "mlx5_core.sf.1",
+			"DPU_DEVICE_1_NETDEV": "",
+			"DPU_DEVICE_1_MAC":    "",
+			EnvDeviceList:         "vf0,sf1",
+			EnvDeviceCount:        "2",
+		}))
+	})
+
+	g.It("should pass the resources shared by devices once", func() {
+		resp, err := allocate("vf0", "sf1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		containerResp := resp.ContainerResponses[0]
+		o.Expect(containerResp.Devices).To(o.HaveLen(1))
+		o.Expect(containerResp.Devices[0].ContainerPath).To(o.Equal("/dev/vfio/vfio"))
+		o.Expect(containerResp.Mounts).To(o.Equal([]*pluginapi.Mount{hugepages}))
+		var names []string
+		for _, cdi := range containerResp.CDIDevices {
+			names = append(names, cdi.Name)
+		}
+		o.Expect(names).To(o.Equal([]string{"vendor.com/dpu=vf0", "vendor.com/dpu=common", "vendor.com/dpu=sf1"}))
+	})
+
+	g.It("should answer each container request separately", func() {
+		resp, err := dp.Allocate(context.Background(), &pluginapi.AllocateRequest{
+			ContainerRequests: []*pluginapi.ContainerAllocateRequest{
+				{DevicesIDs: []string{"vf0"}},
+				{DevicesIDs: []string{"sf1"}},
+			},
+		})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(resp.ContainerResponses).To(o.HaveLen(2))
+		o.Expect(resp.ContainerResponses[1].Envs).To(o.HaveKeyWithValue("DPU_DEVICE_0_ID", "sf1"))
+		o.Expect(resp.ContainerResponses[1].Envs).To(o.HaveKeyWithValue(EnvDeviceCount, "1"))
+		o.Expect(resp.ContainerResponses[1].Devices).To(o.HaveLen(1))
+		o.Expect(resp.ContainerResponses[1].CDIDevices).To(o.HaveLen(2))
+	})
+
+	g.DescribeTable("should reject",
+		func(allocErr error, id string, msg string) {
+			dh.allocErr = allocErr
+			_, err := allocate(id)
+			o.Expect(err).To(o.MatchError(o.ContainSubstring(msg)))
+		},
+		g.Entry("an unknown device", nil, "vf9", "non-existing device: vf9"),
+		g.Entry("an unhealthy device", nil, "sick", "unhealthy device: sick"),
+		g.Entry("a device without allocation", errors.New("VSP unreachable"), "vf0", "failed to get allocation for device vf0: VSP unreachable"),
+	)
+})
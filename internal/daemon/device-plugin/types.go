@@ -6,7 +6,22 @@ import (
 
 type DeviceList map[string]pluginapi.Device
 
+// DeviceAllocation describes what a container needs in order to consume a
+// device, as reported by the VSP.
+type DeviceAllocation struct {
+	PciAddress  string
+	AuxDevice   string
+	NetDev      string
+	MAC         string
+	DeviceSpecs []*pluginapi.DeviceSpec
+	Mounts      []*pluginapi.Mount
+	CDIDevices  []string
+}
+
 type DeviceHandler interface {
 	SetupDevices() error
 	GetDevices() (*DeviceList, error)
+	// GetDeviceAllocation returns the allocation details of a device
+	// previously returned by GetDevices.
+	GetDeviceAllocation(id string) (*DeviceAllocation, error)
 }
@@ -4,22 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/containernetworking/cni/pkg/types"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
+	"github.com/containernetworking/plugins/pkg/ns"
 	"github.com/go-logr/logr"
 	pb2 "github.com/openshift/dpu-operator/dpu-api/gen"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cniserver"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/networkfn"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
 	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	nfstore "github.com/openshift/dpu-operator/internal/daemon/nf-store"
+	nodepair "github.com/openshift/dpu-operator/internal/daemon/node-pair"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	sfcreconciler "github.com/openshift/dpu-operator/internal/daemon/sfc-reconciler"
 	"github.com/openshift/dpu-operator/internal/utils"
 	pb "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -30,6 +41,8 @@ import (
 type DpuSideManager struct {
 	pb.UnimplementedBridgePortServiceServer
 	pb2.UnimplementedDeviceServiceServer
+	pb2.UnimplementedBridgePortPolicyServiceServer
+	healthpb.UnimplementedHealthServer
 
 	vsp           plugin.VendorPlugin
 	dp            deviceplugin.DevicePlugin
@@ -37,33 +50,60 @@ type DpuSideManager struct {
 	server        *grpc.Server
 	cniserver     *cniserver.Server
 	manager       ctrl.Manager
-	macStore      map[string][]string
+	nfStore       *nfstore.Store
 	wg            sync.WaitGroup
 	startedWg     sync.WaitGroup
 	cancelManager context.CancelFunc
 	done          chan error
 	config        *rest.Config
 	pathManager   utils.PathManager
+	channelCreds  channelcerts.Source
+	nodePair      *nodepair.Reporter
+	cniAuditLog   io.Writer
 }
 
-func (s *DpuSideManager) CreateBridgePort(context context.Context, bpr *pb.CreateBridgePortRequest) (*pb.BridgePort, error) {
+func (s *DpuSideManager) CreateBridgePort(ctx context.Context, bpr *pb.CreateBridgePortRequest) (*pb.BridgePort, error) {
 	s.log.Info("Passing CreateBridgePort", "name", bpr.BridgePort.Name)
-	return s.vsp.CreateBridgePort(bpr)
+	return s.vsp.CreateBridgePort(ctx, bpr)
 }
 
-func (s *DpuSideManager) DeleteBridgePort(context context.Context, bpr *pb.DeleteBridgePortRequest) (*emptypb.Empty, error) {
+func (s *DpuSideManager) DeleteBridgePort(ctx context.Context, bpr *pb.DeleteBridgePortRequest) (*emptypb.Empty, error) {
 	s.log.Info("Passing DeleteBridgePort", "name", bpr.Name)
-	err := s.vsp.DeleteBridgePort(bpr)
+	err := s.vsp.DeleteBridgePort(ctx, bpr)
 	return &emptypb.Empty{}, err
 }
 
+func (s *DpuSideManager) GetBridgePort(ctx context.Context, bpr *pb.GetBridgePortRequest) (*pb.BridgePort, error) {
+	s.log.Info("Passing GetBridgePort", "name", bpr.Name)
+	return s.vsp.GetBridgePort(ctx, bpr)
+}
+
+func (s *DpuSideManager) SetBridgePortPolicy(ctx context.Context, policy *pb2.BridgePortPolicy) (*pb2.Empty, error) {
+	s.log.Info("Passing SetBridgePortPolicy", "name", policy.BridgePortName)
+	err := s.vsp.SetBridgePortPolicy(ctx, policy)
+	return &pb2.Empty{}, err
+}
+
+// Check answers the health checks the host uses to probe the OPI channel.
+func (s *DpuSideManager) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+// recordPeer records the host node making a call on the OPI channel.
+func (s *DpuSideManager) recordPeer(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if s.nodePair != nil {
+		p, _ := peer.FromContext(ctx)
+		s.nodePair.PeerSeen(channelcerts.PeerNodeName(p))
+	}
+	return handler(ctx, req)
+}
+
 func NewDpuSideManger(vsp plugin.VendorPlugin, dp deviceplugin.DevicePlugin, config *rest.Config, opts ...func(*DpuSideManager)) *DpuSideManager {
 	d := &DpuSideManager{
 		vsp:         vsp,
 		dp:          dp,
 		pathManager: *utils.NewPathManager("/"),
 		log:         ctrl.Log.WithName("DpuDaemon"),
-		macStore:    make(map[string][]string),
 		done:        make(chan error, 5),
 		config:      config,
 	}
@@ -71,6 +111,7 @@ func NewDpuSideManger(vsp plugin.VendorPlugin, dp deviceplugin.DevicePlugin, con
 	for _, opt := range opts {
 		opt(d)
 	}
+	d.nfStore = nfstore.NewStore(d.pathManager.NetworkFunctionStoreDir())
 
 	return d
 }
@@ -81,18 +122,89 @@ func WithPathManager(pathManager utils.PathManager) func(*DpuSideManager) {
 	}
 }
 
+// WithChannelCredentials sets the credentials used to authenticate the host
+// on the OPI channel. Without them the DPU refuses to serve the channel.
+func WithChannelCredentials(src channelcerts.Source) func(*DpuSideManager) {
+	return func(d *DpuSideManager) {
+		d.channelCreds = src
+	}
+}
+
+// WithNodePairReporter sets the reporter maintaining the DpuNodePair of this
+// node.
+func WithNodePairReporter(r *nodepair.Reporter) func(*DpuSideManager) {
+	return func(d *DpuSideManager) {
+		d.nodePair = r
+	}
+}
+
+// WithCNIAuditLog makes the CNI server write a record of every request to w.
+func WithCNIAuditLog(w io.Writer) func(*DpuSideManager) {
+	return func(d *DpuSideManager) {
+		d.cniAuditLog = w
+	}
+}
+
+// nfCmdAdd and nfCmdDel are networkfn.CmdAdd and networkfn.CmdDel, replaced
+// in tests
+var (
+	nfCmdAdd = networkfn.CmdAdd
+	nfCmdDel = networkfn.CmdDel
+)
+
 func (d *DpuSideManager) cniCmdNfAddHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
 	d.log.Info("cniCmdNfAddHandler")
-	res, err := networkfn.CmdAdd(req)
+	nfArgs := req.CNIConf.NetworkFunctionArgs()
+	if err := nfArgs.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid network function interface role: %v", err)
+	}
+	if err := checkMTU(req.Ctx, d.vsp, req.CNIConf.MTU); err != nil {
+		return nil, err
+	}
+
+	res, err := nfCmdAdd(req)
 	if err != nil {
 		return nil, fmt.Errorf("SRIOV manager failed in add handler: %v", err)
 	}
+	undo := func() {
+		if delErr := nfCmdDel(req); delErr != nil {
+			d.log.Error(delErr, "Failed to undo network function interface", "req.Netns", req.Netns, "req.IfName", req.IfName)
+		}
+	}
+	if err := req.Ctx.Err(); err != nil {
+		undo()
+		return nil, types.NewError(types.ErrTryAgainLater, "CNI request expired before creating the network function", err.Error())
+	}
 
-	d.macStore[req.Netns] = append(d.macStore[req.Netns], req.CNIConf.MAC)
-	if len(d.macStore[req.Netns]) == 2 {
-		d.log.Info("cniCmdNfAddHandler", "req.Netns", req.Netns)
-		macs := d.macStore[req.Netns]
-		d.vsp.CreateNetworkFunction(macs[0], macs[1])
+	iface := nfstore.Interface{
+		IfName:      req.IfName,
+		MAC:         req.CNIConf.MAC,
+		Role:        nfArgs.NFRole,
+		PortPair:    nfArgs.PortPair(),
+		ContainerID: req.ContainerId,
+		Network:     req.CNIConf.Name,
+	}
+	if origLink := req.CNIConf.OrigVfState.Link; origLink.IsSet() {
+		iface.OrigLink = &origLink
+	}
+	ifaces, err := d.nfStore.Add(req.Netns, iface)
+	if err != nil {
+		undo()
+		return nil, fmt.Errorf("Failed to record network function interface: %v", err)
+	}
+	for _, pair := range nfstore.PortPairs(ifaces) {
+		if !pair.Contains(req.IfName) {
+			continue
+		}
+		d.log.Info("cniCmdNfAddHandler", "req.Netns", req.Netns, "portPair", pair.Index, "ingress", pair.Ingress.IfName, "egress", pair.Egress.IfName)
+		if err := d.vsp.CreateNetworkFunction(req.Ctx, pair.Ingress.MAC, pair.Egress.MAC); err != nil {
+			// Forget the interface so that the retried ADD pairs it again
+			if rmErr := d.nfStore.Remove(req.Netns, req.IfName); rmErr != nil {
+				d.log.Error(rmErr, "Failed to forget network function interface", "req.Netns", req.Netns, "req.IfName", req.IfName)
+			}
+			undo()
+			return nil, opiError(fmt.Sprintf("Failed to create network function of port pair %d", pair.Index), err)
+		}
 	}
 	d.log.Info("cniCmdNfAddHandler CmdAdd succeeded")
 	return res, nil
@@ -100,36 +212,192 @@ func (d *DpuSideManager) cniCmdNfAddHandler(req *cnitypes.PodRequest) (*cni100.R
 
 func (d *DpuSideManager) cniCmdNfDelHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
 	d.log.Info("cniCmdNfDelHandler")
-	err := networkfn.CmdDel(req)
+	ifaces, err := d.nfStore.Get(req.Netns)
 	if err != nil {
-		return nil, errors.New("SRIOV manager failed in del handler")
+		d.log.Error(err, "Failed to read network function interfaces", "req.Netns", req.Netns)
+	}
+	for _, iface := range ifaces {
+		if iface.IfName == req.IfName && iface.OrigLink != nil {
+			req.CNIConf.OrigVfState.Link = *iface.OrigLink
+		}
 	}
 
-	macs := d.macStore[req.Netns]
+	err = nfCmdDel(req)
+	if err != nil {
+		return nil, errors.New("SRIOV manager failed in del handler")
+	}
 
-	if len(macs) == 2 {
-		d.log.Info("cniCmdNfDelHandler", "req.Netns", req.Netns)
-		d.vsp.DeleteNetworkFunction(macs[0], macs[1])
+	for _, pair := range nfstore.PortPairs(ifaces) {
+		if !pair.Contains(req.IfName) {
+			continue
+		}
+		d.log.Info("cniCmdNfDelHandler", "req.Netns", req.Netns, "portPair", pair.Index)
+		if err := d.vsp.DeleteNetworkFunction(req.Ctx, pair.Ingress.MAC, pair.Egress.MAC); err != nil {
+			d.log.Error(err, "Failed to delete network function", "req.Netns", req.Netns, "portPair", pair.Index)
+		}
 	}
 
-	d.macStore[req.Netns] = macs[:len(macs)-1]
+	if err := d.nfStore.Remove(req.Netns, req.IfName); err != nil {
+		d.log.Error(err, "Failed to record network function interfaces", "req.Netns", req.Netns)
+	}
 
 	d.log.Info("cniCmdNfDelHandler CmdDel succeeded")
 	return nil, nil
 }
 
+// cniCmdNfCheckHandler verifies that the network function interface is still
+// in the pod, recorded in the network function store and, once both
+// interfaces of its port pair are added, that the VSP has the network function.
+func (d *DpuSideManager) cniCmdNfCheckHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	d.log.Info("cniCmdNfCheckHandler")
+	ifaces, err := d.nfStore.Get(req.Netns)
+	if err != nil {
+		return nil, types.NewError(types.ErrIOFailure, "Failed to read network function interfaces", err.Error())
+	}
+	var iface *nfstore.Interface
+	for i := range ifaces {
+		if ifaces[i].IfName == req.IfName {
+			iface = &ifaces[i]
+		}
+	}
+	if iface == nil {
+		return nil, types.NewError(cnitypes.ErrCheckFailed, fmt.Sprintf("Network function interface %s of %s is not recorded", req.IfName, req.Netns), "")
+	}
+
+	if err := networkfn.CmdCheck(req, iface.MAC); err != nil {
+		return nil, types.NewError(cnitypes.ErrCheckFailed, fmt.Sprintf("Network function interface %s failed check", req.IfName), err.Error())
+	}
+
+	for _, pair := range nfstore.PortPairs(ifaces) {
+		if !pair.Contains(req.IfName) {
+			continue
+		}
+		err := d.vsp.GetNetworkFunction(req.Ctx, pair.Ingress.MAC, pair.Egress.MAC)
+		switch status.Code(err) {
+		case codes.OK:
+		case codes.Unimplemented:
+			// Not all VSPs can confirm their network functions
+			d.log.Info("VSP cannot confirm network functions", "req.Netns", req.Netns, "portPair", pair.Index)
+		case codes.NotFound:
+			return nil, types.NewError(cnitypes.ErrCheckFailed, fmt.Sprintf("Network function of port pair %d is missing on the VSP", pair.Index), err.Error())
+		default:
+			return nil, opiError(fmt.Sprintf("Failed to confirm network function of port pair %d", pair.Index), err)
+		}
+	}
+	return nil, nil
+}
+
+// cniCmdNfStatusHandler reports the daemon as ready for ADD requests if the
+// VSP answers.
+func (d *DpuSideManager) cniCmdNfStatusHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	if err := d.vsp.Ready(req.Ctx); err != nil {
+		return nil, types.NewError(cnitypes.ErrPluginNotAvailable, "VSP is not ready", err.Error())
+	}
+	return nil, nil
+}
+
+// cniCmdNfGCHandler deletes the network functions and records of the
+// attachments of the network that the runtime no longer knows about.
+func (d *DpuSideManager) cniCmdNfGCHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	valid := map[string]bool{}
+	for _, a := range req.CNIConf.ValidAttachments {
+		valid[a.ContainerID+"/"+a.IfName] = true
+	}
+
+	nfs, err := d.nfStore.List()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list network functions: %v", err)
+	}
+
+	var errs []error
+	for netns, ifaces := range nfs {
+		// Both interfaces of a pair may be stale, its network function is
+		// only deleted once
+		deleted := map[int]bool{}
+		for _, iface := range ifaces {
+			// Interfaces recorded before GC was supported have no
+			// attachment, they are only removed with their netns
+			if iface.ContainerID == "" || iface.Network != req.CNIConf.Name || valid[iface.ContainerID+"/"+iface.IfName] {
+				continue
+			}
+			d.log.Info("Removing stale network function interface", "netns", netns, "ifName", iface.IfName)
+			for _, pair := range nfstore.PortPairs(ifaces) {
+				if !pair.Contains(iface.IfName) || deleted[pair.Index] {
+					continue
+				}
+				deleted[pair.Index] = true
+				if err := d.vsp.DeleteNetworkFunction(req.Ctx, pair.Ingress.MAC, pair.Egress.MAC); err != nil {
+					errs = append(errs, fmt.Errorf("failed to delete network function of %s port pair %d: %v", netns, pair.Index, err))
+				}
+			}
+			if err := d.nfStore.Remove(netns, iface.IfName); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return nil, errors.Join(errs...)
+}
+
+// reconcileNetworkFunctions deletes the network functions of pods whose
+// network namespace is gone, e.g. because they were removed while the daemon
+// was not running and CNI DEL never reached it.
+func (d *DpuSideManager) reconcileNetworkFunctions(ctx context.Context) {
+	nfs, err := d.nfStore.List()
+	if err != nil {
+		d.log.Error(err, "Failed to list network functions")
+		return
+	}
+
+	for netns, ifaces := range nfs {
+		netNS, err := ns.GetNS(netns)
+		if err == nil {
+			netNS.Close()
+			continue
+		}
+
+		d.log.Info("Removing orphaned network function", "netns", netns, "interfaces", ifaces)
+		failed := false
+		for _, pair := range nfstore.PortPairs(ifaces) {
+			if err := d.vsp.DeleteNetworkFunction(ctx, pair.Ingress.MAC, pair.Egress.MAC); err != nil {
+				d.log.Error(err, "Failed to delete orphaned network function", "netns", netns, "portPair", pair.Index)
+				failed = true
+			}
+		}
+		if failed {
+			// Keep the record to retry on the next start
+			continue
+		}
+		if err := d.nfStore.Set(netns, nil); err != nil {
+			d.log.Error(err, "Failed to remove network function record", "netns", netns)
+		}
+	}
+}
+
 func (d *DpuSideManager) Listen() (net.Listener, error) {
 	d.startedWg.Add(1)
 	d.log.Info("Starting DpuDaemon")
 	d.setupReconcilers()
 
-	addr, port, err := d.vsp.Start()
+	addr, port, err := d.vsp.Start(context.Background())
 	if err != nil {
 		return nil, fmt.Errorf("Failed to get addr:port from VendorPlugin: %v", err)
 	}
 
-	d.server = grpc.NewServer()
+	if d.channelCreds == nil {
+		return nil, errors.New("No credentials configured for the OPI channel")
+	}
+	if d.nodePair != nil {
+		d.nodePair.SetEndpoint(addr, port)
+	}
+
+	d.reconcileNetworkFunctions(context.Background())
+
+	d.server = grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(channelcerts.ServerConfig(d.channelCreds))),
+		grpc.UnaryInterceptor(d.recordPeer))
 	pb.RegisterBridgePortServiceServer(d.server, d)
+	pb2.RegisterBridgePortPolicyServiceServer(d.server, d)
+	healthpb.RegisterHealthServer(d.server, d)
 
 	lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", addr, port))
 	if err != nil {
@@ -144,7 +412,23 @@ func (d *DpuSideManager) Listen() (net.Listener, error) {
 		return d.cniCmdNfDelHandler(r)
 	}
 
-	d.cniserver = cniserver.NewCNIServer(add, del, cniserver.WithPathManager(d.pathManager))
+	check := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdNfCheckHandler(r)
+	}
+	status := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdNfStatusHandler(r)
+	}
+	gc := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdNfGCHandler(r)
+	}
+
+	d.cniserver = cniserver.NewCNIServer(add, del,
+		cniserver.WithPathManager(d.pathManager),
+		cniserver.WithResultCacheDir(d.pathManager.CNIResultCacheDir()),
+		cniserver.WithAuditLog(d.cniAuditLog),
+		cniserver.WithCheckHandler(check),
+		cniserver.WithStatusHandler(status),
+		cniserver.WithGCHandler(gc))
 
 	return lis, err
 }
@@ -164,8 +448,6 @@ func (d *DpuSideManager) Serve(listener net.Listener) error {
 	d.wg.Add(1)
 	go func() {
 		d.log.Info("Starting OPI server")
-		d.server = grpc.NewServer()
-		pb.RegisterBridgePortServiceServer(d.server, d)
 		if err := d.server.Serve(listener); err != nil {
 			d.done <- fmt.Errorf("Error from OPI server: %v", err)
 		} else {
@@ -213,6 +495,14 @@ func (d *DpuSideManager) Serve(listener net.Listener) error {
 	}()
 	d.cancelManager = cancelManager
 
+	if d.nodePair != nil {
+		d.wg.Add(1)
+		go func() {
+			d.nodePair.Run(ctx, nil)
+			d.wg.Done()
+		}()
+	}
+
 	// Block on any go routines writing to the done channel when an error occurs or they
 	// are forced to exit.
 	err := <-d.done
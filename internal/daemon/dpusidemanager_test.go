@@ -3,18 +3,25 @@ package daemon
 import (
 	"context"
 	"os"
+	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	"k8s.io/client-go/rest"
 
+	"github.com/containernetworking/cni/pkg/types"
+	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
 	dpudevicehandler "github.com/openshift/dpu-operator/internal/daemon/device-handler/dpu-device-handler"
 	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	nfstore "github.com/openshift/dpu-operator/internal/daemon/nf-store"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	mockvsp "github.com/openshift/dpu-operator/internal/daemon/vendor-specific-plugins/mock-vsp"
 	"github.com/openshift/dpu-operator/internal/testutils"
 	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	corev1 "k8s.io/api/core/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -73,8 +80,10 @@ var _ = g.Describe("DPU side maanger", Ordered, func() {
 			dpudevicehandler.WithDpuMode(true))
 		dp := deviceplugin.NewDevicePlugin(dpuDeviceHandler,
 			deviceplugin.WithPathManager(pathManager))
+		_, dpuCreds := newChannelCredentials()
 		dpuDaemon = NewDpuSideManger(dpuPlugin, dp, config,
-			WithPathManager(pathManager))
+			WithPathManager(pathManager),
+			WithChannelCredentials(dpuCreds))
 
 		dpuListen, err := dpuDaemon.Listen()
 		Expect(err).NotTo(HaveOccurred())
@@ -98,3 +107,242 @@ var _ = g.Describe("DPU side maanger", Ordered, func() {
 		})
 	})
 })
+
+var _ = g.Describe("DPU side manager CHECK", func() {
+	// The interfaces are checked in the netns of the test, where lo exists
+	// without MAC address
+	const netns = "/proc/self/ns/net"
+	var (
+		vsp       *DummyPlugin
+		dpuDaemon *DpuSideManager
+	)
+
+	g.BeforeEach(func() {
+		vsp = NewDummyPlugin()
+		dpuDaemon = NewDpuSideManger(vsp, nil, nil,
+			WithPathManager(*utils.NewPathManager(g.GinkgoT().TempDir())))
+	})
+
+	check := func(ifName string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		req := &cnitypes.PodRequest{
+			Ctx:     ctx,
+			Netns:   netns,
+			IfName:  ifName,
+			CNIConf: &cnitypes.NetConf{DeviceID: ifName},
+		}
+		_, err := dpuDaemon.cniCmdNfCheckHandler(req)
+		return err
+	}
+
+	record := func(ifName string, mac string, role string) {
+		_, err := dpuDaemon.nfStore.Add(netns, nfstore.Interface{IfName: ifName, MAC: mac, Role: role})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	g.It("should pass for a recorded interface of an incomplete port pair", func() {
+		record("lo", "", cnitypes.NFRoleIngress)
+		Expect(check("lo")).To(Succeed())
+	})
+
+	// checkFailure matches a CNI error with code whose message or details
+	// contain substr
+	checkFailure := func(code uint, substr string) OmegaMatcher {
+		return And(
+			BeAssignableToTypeOf(&types.Error{}),
+			HaveField("Code", code),
+			WithTransform(func(e *types.Error) string { return e.Msg + ": " + e.Details }, ContainSubstring(substr)))
+	}
+
+	g.It("should fail for an interface that is not recorded", func() {
+		Expect(check("lo")).To(checkFailure(cnitypes.ErrCheckFailed, "is not recorded"))
+	})
+
+	g.It("should fail if the interface is not in the pod", func() {
+		record("nf-missing", "02:00:00:00:00:00", cnitypes.NFRoleIngress)
+		Expect(check("nf-missing")).To(checkFailure(cnitypes.ErrCheckFailed, "failed to find nf-missing"))
+	})
+
+	g.It("should fail if the MAC address of the interface changed", func() {
+		record("lo", "02:00:00:00:00:00", cnitypes.NFRoleIngress)
+		Expect(check("lo")).To(checkFailure(cnitypes.ErrCheckFailed, "instead of 02:00:00:00:00:00"))
+	})
+
+	g.Context("of a complete port pair", func() {
+		g.BeforeEach(func() {
+			record("lo", "", cnitypes.NFRoleIngress)
+			record("net2", "02:00:00:00:00:02", cnitypes.NFRoleEgress)
+		})
+
+		g.It("should pass if the VSP has the network function", func() {
+			Expect(check("lo")).To(Succeed())
+		})
+
+		g.It("should fail if the VSP does not have the network function", func() {
+			vsp.nfErr = status.Error(codes.NotFound, "network function not found")
+			Expect(check("lo")).To(checkFailure(cnitypes.ErrCheckFailed, "Network function of port pair 0 is missing"))
+		})
+
+		g.It("should ask to retry if the VSP cannot be reached", func() {
+			vsp.nfErr = status.Error(codes.Unavailable, "connection refused")
+			Expect(check("lo")).To(checkFailure(types.ErrTryAgainLater, "Failed to confirm network function of port pair 0"))
+		})
+
+		g.It("should pass if the VSP cannot confirm network functions", func() {
+			vsp.nfErr = status.Error(codes.Unimplemented, "method GetNetworkFunction not implemented")
+			Expect(check("lo")).To(Succeed())
+		})
+	})
+})
+
+var _ = g.Describe("DPU side manager ADD", func() {
+	const netns = "/var/run/netns/nf-pod"
+	var (
+		vsp       *DummyPlugin
+		dpuDaemon *DpuSideManager
+		deleted   []string
+	)
+
+	g.BeforeEach(func() {
+		vsp = NewDummyPlugin()
+		dpuDaemon = NewDpuSideManger(vsp, nil, nil,
+			WithPathManager(*utils.NewPathManager(g.GinkgoT().TempDir())))
+		deleted = nil
+		origAdd, origDel := nfCmdAdd, nfCmdDel
+		g.DeferCleanup(func() {
+			nfCmdAdd, nfCmdDel = origAdd, origDel
+		})
+		nfCmdAdd = func(req *cnitypes.PodRequest) (*cni100.Result, error) {
+			return &cni100.Result{}, nil
+		}
+		nfCmdDel = func(req *cnitypes.PodRequest) error {
+			deleted = append(deleted, req.IfName)
+			return nil
+		}
+	})
+
+	add := func(ifName string, mac string, role string) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		conf := &cnitypes.NetConf{DeviceID: ifName, MAC: mac}
+		conf.Name = "nf-net"
+		conf.NFArgs = cnitypes.NFArgs{NFRole: role}
+		_, err := dpuDaemon.cniCmdNfAddHandler(&cnitypes.PodRequest{
+			Ctx:         ctx,
+			Netns:       netns,
+			IfName:      ifName,
+			ContainerId: "nf",
+			CNIConf:     conf,
+		})
+		return err
+	}
+
+	recorded := func() []string {
+		ifaces, err := dpuDaemon.nfStore.Get(netns)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, iface := range ifaces {
+			names = append(names, iface.IfName)
+		}
+		return names
+	}
+
+	g.It("should create the network function once the port pair is complete", func() {
+		Expect(add("net1", "02:00:00:00:00:01", cnitypes.NFRoleIngress)).To(Succeed())
+		Expect(vsp.createdNFs).To(BeEmpty())
+		Expect(add("net2", "02:00:00:00:00:02", cnitypes.NFRoleEgress)).To(Succeed())
+		Expect(vsp.createdNFs).To(Equal([]string{"02:00:00:00:00:01-02:00:00:00:00:02"}))
+		Expect(recorded()).To(Equal([]string{"net1", "net2"}))
+	})
+
+	g.It("should undo the interface if the network function cannot be created", func() {
+		Expect(add("net1", "02:00:00:00:00:01", cnitypes.NFRoleIngress)).To(Succeed())
+		vsp.createNFErr = status.Error(codes.Unavailable, "VSP restarting")
+		err := add("net2", "02:00:00:00:00:02", cnitypes.NFRoleEgress)
+		Expect(err).To(BeAssignableToTypeOf(&types.Error{}))
+		Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+		Expect(deleted).To(Equal([]string{"net2"}))
+		Expect(recorded()).To(Equal([]string{"net1"}))
+
+		// The runtime retries ADD
+		vsp.createNFErr = nil
+		Expect(add("net2", "02:00:00:00:00:02", cnitypes.NFRoleEgress)).To(Succeed())
+		Expect(vsp.createdNFs).To(Equal([]string{"02:00:00:00:00:01-02:00:00:00:00:02"}))
+		Expect(recorded()).To(Equal([]string{"net1", "net2"}))
+	})
+})
+
+var _ = g.Describe("DPU side manager GC", func() {
+	const netns = "/var/run/netns/nf-pod"
+	var (
+		vsp       *DummyPlugin
+		dpuDaemon *DpuSideManager
+	)
+
+	g.BeforeEach(func() {
+		vsp = NewDummyPlugin()
+		dpuDaemon = NewDpuSideManger(vsp, nil, nil,
+			WithPathManager(*utils.NewPathManager(g.GinkgoT().TempDir())))
+	})
+
+	gc := func(network string, valid ...types.GCAttachment) error {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		conf := &cnitypes.NetConf{}
+		conf.Name = network
+		conf.ValidAttachments = valid
+		_, err := dpuDaemon.cniCmdNfGCHandler(&cnitypes.PodRequest{Ctx: ctx, CNIConf: conf})
+		return err
+	}
+
+	record := func(iface nfstore.Interface) {
+		_, err := dpuDaemon.nfStore.Add(netns, iface)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	recorded := func() []string {
+		ifaces, err := dpuDaemon.nfStore.Get(netns)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, iface := range ifaces {
+			names = append(names, iface.IfName)
+		}
+		return names
+	}
+
+	g.BeforeEach(func() {
+		record(nfstore.Interface{IfName: "net1", MAC: "02:00:00:00:00:01", Role: cnitypes.NFRoleIngress, ContainerID: "nf", Network: "nf-net"})
+		record(nfstore.Interface{IfName: "net2", MAC: "02:00:00:00:00:02", Role: cnitypes.NFRoleEgress, ContainerID: "nf", Network: "nf-net"})
+	})
+
+	g.It("should delete the network function of a stale pod once", func() {
+		Expect(gc("nf-net")).To(Succeed())
+		Expect(vsp.deletedNFs).To(Equal([]string{"02:00:00:00:00:01-02:00:00:00:00:02"}))
+		Expect(recorded()).To(BeEmpty())
+	})
+
+	g.It("should keep the network function of a valid pod", func() {
+		Expect(gc("nf-net", types.GCAttachment{ContainerID: "nf", IfName: "net1"}, types.GCAttachment{ContainerID: "nf", IfName: "net2"})).To(Succeed())
+		Expect(vsp.deletedNFs).To(BeEmpty())
+		Expect(recorded()).To(Equal([]string{"net1", "net2"}))
+	})
+
+	g.It("should delete the network function of a pod that lost one of its interfaces", func() {
+		Expect(gc("nf-net", types.GCAttachment{ContainerID: "nf", IfName: "net1"})).To(Succeed())
+		Expect(vsp.deletedNFs).To(Equal([]string{"02:00:00:00:00:01-02:00:00:00:00:02"}))
+		Expect(recorded()).To(Equal([]string{"net1"}))
+	})
+
+	g.It("should keep the interfaces of other networks and of pods recorded before GC", func() {
+		record(nfstore.Interface{IfName: "net3", MAC: "02:00:00:00:00:03", Role: cnitypes.NFRoleManagement})
+		Expect(gc("other-net")).To(Succeed())
+		Expect(vsp.deletedNFs).To(BeEmpty())
+		Expect(recorded()).To(Equal([]string{"net1", "net2", "net3"}))
+	})
+
+	g.It("should report a network function the VSP fails to delete", func() {
+		vsp.deleteNFErr = status.Error(codes.Internal, "flows busy")
+		Expect(gc("nf-net")).To(MatchError(ContainSubstring("flows busy")))
+	})
+})
@@ -4,23 +4,33 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"sync"
 	"time"
 
+	"github.com/containernetworking/cni/pkg/types"
 	cni100 "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/go-logr/logr"
 	configv1 "github.com/openshift/dpu-operator/api/v1"
+	pb2 "github.com/openshift/dpu-operator/dpu-api/gen"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cniserver"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/sriov"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
 	deviceplugin "github.com/openshift/dpu-operator/internal/daemon/device-plugin"
+	nodepair "github.com/openshift/dpu-operator/internal/daemon/node-pair"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
 	sfcreconciler "github.com/openshift/dpu-operator/internal/daemon/sfc-reconciler"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"github.com/openshift/dpu-operator/internal/utils"
 	pb "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
@@ -30,23 +40,82 @@ import (
 )
 
 type HostSideManager struct {
-	dev         bool
-	log         logr.Logger
-	conn        *grpc.ClientConn
-	client      pb.BridgePortServiceClient
-	vsp         plugin.VendorPlugin
-	dp          deviceplugin.DevicePlugin
-	addr        string
-	port        int32
-	cniserver   *cniserver.Server
-	sm          sriov.Manager
-	manager     ctrl.Manager
-	startedWg   sync.WaitGroup
-	pathManager utils.PathManager
-}
-
-func (d *HostSideManager) CreateBridgePort(pf int, vf int, vlan int, mac string) (*pb.BridgePort, error) {
-	err := d.connectWithRetry()
+	dev          bool
+	log          logr.Logger
+	vsp          plugin.VendorPlugin
+	dp           deviceplugin.DevicePlugin
+	addr         string
+	port         int32
+	cniserver    *cniserver.Server
+	sm           sriov.Manager
+	manager      ctrl.Manager
+	startedWg    sync.WaitGroup
+	pathManager  utils.PathManager
+	channelCreds channelcerts.Source
+	nodePair     *nodepair.Reporter
+	cniAuditLog  io.Writer
+	// vspClient is the connection the vsp uses, its reconnects refresh the
+	// VF capabilities
+	vspClient *vspclient.VspClient
+	capsOnce  sync.Once
+
+	// connMu guards conn, which is shared by the CNI handlers running
+	// concurrently and the peer probe.
+	connMu sync.Mutex
+	conn   *grpc.ClientConn
+	// deviceLocks serializes the CNI requests for the same VF.
+	deviceLocks utils.KeyedMutex
+}
+
+// hostFunction is the VF or sub function of a PF that a bridge port connects
+// to the DPU.
+type hostFunction struct {
+	pf int
+	// index is the VF ID, or the sfnum of a sub function
+	index int
+	sf    bool
+}
+
+// hostFunctionOf returns the function of PF pf that conf attaches to a pod.
+func hostFunctionOf(pf int, conf *cnitypes.NetConf) hostFunction {
+	if conf.IsSF() {
+		return hostFunction{pf: pf, index: conf.SFNum, sf: true}
+	}
+	return hostFunction{pf: pf, index: conf.VFID}
+}
+
+// bridgePortName is the name of the bridge port of the function on the DPU,
+// host<pf>-<vf> for a VF and host<pf>-sf<sfnum> for a sub function.
+func (f hostFunction) bridgePortName() string {
+	if f.sf {
+		return fmt.Sprintf("host%d-sf%d", f.pf, f.index)
+	}
+	return "host" + fmt.Sprintf("%d-%d", f.pf, f.index)
+}
+
+// logicalBridge is the logical bridge of the bridge port of the function. VFs
+// use their numeric VF ID offset by 2, sub functions use sf<sfnum> so that an
+// sfnum never selects the logical bridge of the VF with the same number.
+func (f hostFunction) logicalBridge() string {
+	if f.sf {
+		return fmt.Sprintf("sf%d", f.index)
+	}
+	// TODO: Remove +2
+	return fmt.Sprintf("%d", f.index+2)
+}
+
+// dpuDevice is the device-info of how the DPU connects the function.
+func (f hostFunction) dpuDevice() *cnitypes.DpuDevice {
+	dev := &cnitypes.DpuDevice{BridgePort: f.bridgePortName(), LogicalBridge: f.logicalBridge()}
+	if !f.sf {
+		vfID := f.index
+		dev.VfID = &vfID
+	}
+	return dev
+}
+
+func (d *HostSideManager) CreateBridgePort(ctx context.Context, fn hostFunction, vlan int, mac string) (*pb.BridgePort, error) {
+	conn, err := d.connectWithRetry()
 	if err != nil {
 		return nil, fmt.Errorf("Failed to connect with retry: %v", err)
 	}
@@ -58,29 +127,104 @@ func (d *HostSideManager) CreateBridgePort(pf int, vf int, vlan int, mac string)
 
 	createRequest := &pb.CreateBridgePortRequest{
 		BridgePort: &pb.BridgePort{
-			Name: "host" + fmt.Sprintf("%d-%d", pf, vf),
+			Name: fn.bridgePortName(),
 			Spec: &pb.BridgePortSpec{
-				Ptype:      1,
-				MacAddress: m,
-				LogicalBridges: []string{
-					// TODO: Remove +2
-					fmt.Sprintf("%d", vf+2),
-				},
+				Ptype:          1,
+				MacAddress:     m,
+				LogicalBridges: []string{fn.logicalBridge()},
 			},
 		},
 	}
 
-	return d.client.CreateBridgePort(context.TODO(), createRequest)
+	return pb.NewBridgePortServiceClient(conn).CreateBridgePort(ctx, createRequest)
+}
+
+func (d *HostSideManager) DeleteBridgePort(ctx context.Context, fn hostFunction, vlan int, mac string) error {
+	conn, err := d.connectWithRetry()
+	if err != nil {
+		return fmt.Errorf("Failed to connect with retry: %v", err)
+	}
+	req := &pb.DeleteBridgePortRequest{Name: fn.bridgePortName(), AllowMissing: true}
+
+	_, err = pb.NewBridgePortServiceClient(conn).DeleteBridgePort(ctx, req)
+	return err
 }
 
-func (d *HostSideManager) DeleteBridgePort(pf int, vf int, vlan int, mac string) error {
-	d.connectWithRetry()
-	req := &pb.DeleteBridgePortRequest{Name: "host" + fmt.Sprintf("%d-%d", pf, vf)}
+// GetBridgePort asks the DPU for the bridge port of the function, it fails
+// with NotFound if the VSP does not have it.
+func (d *HostSideManager) GetBridgePort(ctx context.Context, fn hostFunction) (*pb.BridgePort, error) {
+	conn, err := d.connectWithRetry()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to connect with retry: %v", err)
+	}
+	req := &pb.GetBridgePortRequest{Name: fn.bridgePortName()}
+
+	return pb.NewBridgePortServiceClient(conn).GetBridgePort(ctx, req)
+}
 
-	_, err := d.client.DeleteBridgePort(context.TODO(), req)
+// SetBridgePortPolicy asks the DPU to enforce the policy on the bridge port of
+// the VF.
+func (d *HostSideManager) SetBridgePortPolicy(ctx context.Context, policy *pb2.BridgePortPolicy) error {
+	conn, err := d.connectWithRetry()
+	if err != nil {
+		return fmt.Errorf("Failed to connect with retry: %v", err)
+	}
+
+	_, err = pb2.NewBridgePortPolicyServiceClient(conn).SetBridgePortPolicy(ctx, policy)
 	return err
 }
 
+// bridgePortPolicy returns the policy the network requests for the VF with
+// the given MAC address, or nil if it requests none. Only explicitly
+// requested settings are enforced on the DPU, as on the host VF.
+func bridgePortPolicy(conf *cnitypes.NetConf, name string, mac string) (*pb2.BridgePortPolicy, error) {
+	if conf.MinTxRate == nil && conf.MaxTxRate == nil && conf.SpoofChk == "" && conf.Trust == "" {
+		return nil, nil
+	}
+	if conf.MAC != "" {
+		mac = conf.MAC
+	}
+	m, err := net.ParseMAC(mac)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse Mac: %v", err)
+	}
+	policy := &pb2.BridgePortPolicy{
+		BridgePortName: name,
+		MacAddress:     m,
+		SpoofCheck:     conf.SpoofChk == "on",
+		Trust:          conf.Trust == "on",
+	}
+	if conf.MinTxRate != nil {
+		policy.MinTxRate = uint32(*conf.MinTxRate)
+	}
+	if conf.MaxTxRate != nil {
+		policy.MaxTxRate = uint32(*conf.MaxTxRate)
+	}
+	return policy, nil
+}
+
+// checkMTU verifies that the MTU requested for a pod interface does not
+// exceed the MTU of the data path reported by the VSP. Larger frames would be
+// dropped by the DPU, so the ADD is refused instead. Without a report from the
+// VSP the MTU is not limited.
+func checkMTU(ctx context.Context, vsp plugin.VendorPlugin, mtu int) *types.Error {
+	if mtu == 0 {
+		return nil
+	}
+	limits, err := vsp.GetLinkLimits(ctx)
+	if status.Code(err) == codes.Unimplemented {
+		return nil
+	}
+	if err != nil {
+		return types.NewError(types.ErrInternal, "Failed to get the link limits from the VSP", err.Error())
+	}
+	if limits.MaxMtu != 0 && mtu > int(limits.MaxMtu) {
+		return types.NewError(types.ErrInvalidNetworkConfig, "Invalid MTU",
+			fmt.Sprintf("mtu %d exceeds the mtu %d of the data path", mtu, limits.MaxMtu))
+	}
+	return nil
+}
+
 func NewHostSideManager(vsp plugin.VendorPlugin, dp deviceplugin.DevicePlugin) *HostSideManager {
 	return &HostSideManager{
 		vsp:         vsp,
@@ -106,9 +250,41 @@ func (d *HostSideManager) WithManager(manager ctrl.Manager) *HostSideManager {
 	return d
 }
 
-func (d *HostSideManager) connectWithRetry() error {
+// WithChannelCredentials sets the credentials used to authenticate to the DPU
+// on the OPI channel. Without them the host does not connect to the DPU.
+func (d *HostSideManager) WithChannelCredentials(src channelcerts.Source) *HostSideManager {
+	d.channelCreds = src
+	return d
+}
+
+// WithNodePairReporter sets the reporter maintaining the DpuNodePair of this
+// node.
+func (d *HostSideManager) WithNodePairReporter(r *nodepair.Reporter) *HostSideManager {
+	d.nodePair = r
+	return d
+}
+
+// WithCNIAuditLog makes the CNI server write a record of every request to w.
+func (d *HostSideManager) WithCNIAuditLog(w io.Writer) *HostSideManager {
+	d.cniAuditLog = w
+	return d
+}
+
+// WithVspClient sets the connection to the VSP, so that the VF capabilities
+// are asked for again each time the VSP restarts.
+func (d *HostSideManager) WithVspClient(c *vspclient.VspClient) *HostSideManager {
+	d.vspClient = c
+	return d
+}
+
+func (d *HostSideManager) connectWithRetry() (*grpc.ClientConn, error) {
+	d.connMu.Lock()
+	defer d.connMu.Unlock()
 	if d.conn != nil {
-		return nil
+		return d.conn, nil
+	}
+	if d.channelCreds == nil {
+		return nil, errors.New("No credentials configured for the OPI channel")
 	}
 	// Might want to change waitForReady to true to
 	// block on connection. Currently, we connect
@@ -127,65 +303,235 @@ func (d *HostSideManager) connectWithRetry() error {
 		  }
 		}]}`
 
-	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", d.addr, d.port), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithDefaultServiceConfig(retryPolicy))
+	tlsConfig, err := channelcerts.ClientConfig(d.channelCreds)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to get channel credentials: %v", err)
+	}
+	conn, err := grpc.Dial(fmt.Sprintf("%s:%d", d.addr, d.port), grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)), grpc.WithDefaultServiceConfig(retryPolicy))
 	if err != nil {
-		return fmt.Errorf("connectWithRetry dial failed: %v", err)
+		return nil, fmt.Errorf("connectWithRetry dial failed: %v", err)
 	}
 	d.log.Info("Dial succeeded", "addr", d.addr, "port", d.port)
 	d.conn = conn
-	d.client = pb.NewBridgePortServiceClient(conn)
-	return nil
+	return conn, nil
+}
+
+// probePeer checks that the DPU answers on the OPI channel and returns the
+// name of its node.
+func (d *HostSideManager) probePeer(ctx context.Context) (string, error) {
+	conn, err := d.connectWithRetry()
+	if err != nil {
+		return "", fmt.Errorf("Failed to connect with retry: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	var p peer.Peer
+	_, err = healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}, grpc.Peer(&p))
+	if err != nil {
+		return "", fmt.Errorf("Health check of DPU failed: %v", err)
+	}
+	return channelcerts.PeerNodeName(&p), nil
+}
+
+// opiError returns the CNI error of a failed call on the OPI channel or to
+// the VSP. The runtime is asked to retry if the peer could not be reached.
+func opiError(msg string, err error) *types.Error {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return types.NewError(types.ErrTryAgainLater, msg, err.Error())
+	default:
+		return types.NewError(types.ErrInternal, msg, err.Error())
+	}
+}
+
+// rollback undoes the completed steps of a CNI ADD in reverse order.
+type rollback []func() error
+
+func (r rollback) run() error {
+	var errs []error
+	for i := len(r) - 1; i >= 0; i-- {
+		if err := r[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
 }
 
+// cniCmdAddHandler sets up the VF and then its bridge port on the DPU. It is
+// a transaction: if a step fails, the steps that completed are undone so the
+// VF, its IPAM lease and PCI allocation are not left behind.
 func (d *HostSideManager) cniCmdAddHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
 	d.log.Info("addHandler")
+	unlock := d.deviceLocks.Lock(req.CNIConf.DeviceID)
+	defer unlock()
+
+	var undo rollback
+	fail := func(err *types.Error) (*cni100.Result, error) {
+		if rbErr := undo.run(); rbErr != nil {
+			d.log.Error(rbErr, "addHandler rollback failed", "containerID", req.ContainerId, "ifName", req.IfName)
+			err.Details = fmt.Sprintf("%s (rollback failed: %v)", err.Details, rbErr)
+		}
+		return nil, err
+	}
+
+	if err := checkMTU(req.Ctx, d.vsp, req.CNIConf.MTU); err != nil {
+		return nil, err
+	}
+
 	res, err := d.sm.CmdAdd(req)
 	if err != nil {
-		return nil, fmt.Errorf("SRIOV manager failed in add handler: %v", err)
+		return fail(types.NewError(types.ErrInternal, "SRIOV manager failed in add handler", err.Error()))
 	}
+	undo = append(undo, func() error {
+		if err := d.sm.CmdDel(req); err != nil {
+			return fmt.Errorf("SRIOV manager failed to release the VF: %v", err)
+		}
+		return nil
+	})
 	d.log.Info("addHandler d.sm.CmdAdd succeeded")
-	pf := 0
-	vf := req.CNIConf.VFID
+	if err := req.Ctx.Err(); err != nil {
+		return fail(types.NewError(types.ErrTryAgainLater, "CNI request expired before creating the bridge port", err.Error()))
+	}
+	fn := hostFunctionOf(0, req.CNIConf)
 	mac := req.CNIConf.OrigVfState.EffectiveMAC
 	d.log.Info("addHandler", "CNIConf", req.CNIConf)
 	// TODO: fix setting Vlan based on network definition in CR
 	vlan := 2 // *req.CNIConf.Vlan
-	d.log.Info("addHandler", "pf", pf, "vf", vf, "mac", mac, "vlan", vlan)
-	_, err = d.CreateBridgePort(pf, vf, vlan, mac)
+	d.log.Info("addHandler", "bridgePort", fn.bridgePortName(), "mac", mac, "vlan", vlan)
+	_, err = d.CreateBridgePort(req.Ctx, fn, vlan, mac)
 	if err != nil {
-		return nil, fmt.Errorf("Failed to call CreateBridgePort: %v", err)
+		return fail(opiError("Failed to call CreateBridgePort", err))
 	}
+	undo = append(undo, func() error {
+		if err := d.DeleteBridgePort(context.Background(), fn, vlan, mac); err != nil {
+			return fmt.Errorf("Failed to delete bridge port: %v", err)
+		}
+		return nil
+	})
 	d.log.Info("addHandler CreateBridgePort succeeded")
+	req.DeviceInfo.Dpu = fn.dpuDevice()
+
+	policy, err := bridgePortPolicy(req.CNIConf, fn.bridgePortName(), mac)
+	if err != nil {
+		return fail(types.NewError(types.ErrInvalidNetworkConfig, "Invalid bridge port policy", err.Error()))
+	}
+	if policy != nil {
+		err = d.SetBridgePortPolicy(req.Ctx, policy)
+		switch {
+		case status.Code(err) == codes.Unimplemented && fn.sf:
+			// Nothing else would enforce the policy of a sub function
+			return fail(types.NewError(types.ErrUnsupportedField, "DPU does not enforce the bridge port policy of sub functions", err.Error()))
+		case status.Code(err) == codes.Unimplemented:
+			d.log.V(1).Info("DPU does not enforce bridge port policies, leaving the policy to the VF", "bridgePort", policy.BridgePortName)
+		case err != nil:
+			return fail(opiError("Failed to call SetBridgePortPolicy", err))
+		default:
+			d.log.Info("addHandler SetBridgePortPolicy succeeded")
+		}
+	}
 
 	return res, nil
 }
 
 func (d *HostSideManager) cniCmdDelHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	unlock := d.deviceLocks.Lock(req.CNIConf.DeviceID)
+	defer unlock()
+
 	err := d.sm.CmdDel(req)
 	if err != nil {
 		return nil, errors.New("SRIOV manager failed in del handler")
 	}
-	pf := 0
-	vf := req.CNIConf.VFID
+	fn := hostFunctionOf(0, req.CNIConf)
 	mac := req.CNIConf.OrigVfState.EffectiveMAC
 	// TODO: fix setting Vlan based on network definition in CR
 	vlan := 2 // *req.CNIConf.Vlan
-	d.log.Info("delHandler", "pf", pf, "vf", vf, "mac", mac, "vlan", vlan)
-	d.DeleteBridgePort(pf, vf, vlan, mac)
+	d.log.Info("delHandler", "bridgePort", fn.bridgePortName(), "mac", mac, "vlan", vlan)
+	// The result cache keeps the attachment while DEL fails, so the runtime
+	// retries it. Releasing the VF again is a no-op then.
+	err = d.DeleteBridgePort(req.Ctx, fn, vlan, mac)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return nil, opiError("Failed to call DeleteBridgePort", err)
+	}
+	return nil, nil
+}
+
+func (d *HostSideManager) cniCmdCheckHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	unlock := d.deviceLocks.Lock(req.CNIConf.DeviceID)
+	defer unlock()
+
+	if err := d.sm.CmdCheck(req); err != nil {
+		return nil, types.NewError(cnitypes.ErrCheckFailed, "SRIOV manager failed in check handler", err.Error())
+	}
+	fn := hostFunctionOf(0, req.CNIConf)
+	d.log.Info("checkHandler", "bridgePort", fn.bridgePortName())
+	_, err := d.GetBridgePort(req.Ctx, fn)
+	if status.Code(err) == codes.NotFound {
+		return nil, types.NewError(cnitypes.ErrCheckFailed, fmt.Sprintf("Bridge port %s is missing on the DPU", fn.bridgePortName()), err.Error())
+	}
+	if err != nil {
+		return nil, opiError(fmt.Sprintf("Failed to confirm bridge port %s", fn.bridgePortName()), err)
+	}
+	return nil, nil
+}
+
+// cniCmdStatusHandler reports the daemon as ready for ADD requests if both the
+// VSP and the DPU on the OPI channel answer.
+func (d *HostSideManager) cniCmdStatusHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	if err := d.vsp.Ready(req.Ctx); err != nil {
+		return nil, types.NewError(cnitypes.ErrPluginNotAvailable, "VSP is not ready", err.Error())
+	}
+	if _, err := d.probePeer(req.Ctx); err != nil {
+		return nil, types.NewError(cnitypes.ErrPluginNotAvailable, "DPU is not reachable on the OPI channel", err.Error())
+	}
 	return nil, nil
 }
 
+// cniCmdGCHandler releases the VFs and bridge ports of the attachments of the
+// network that the runtime no longer knows about.
+func (d *HostSideManager) cniCmdGCHandler(req *cnitypes.PodRequest) (*cni100.Result, error) {
+	stale, err := d.sm.StaleAttachments(req)
+	if err != nil {
+		return nil, fmt.Errorf("SRIOV manager failed to list stale attachments: %v", err)
+	}
+
+	var errs []error
+	for _, r := range stale {
+		d.log.Info("gcHandler releasing stale attachment", "containerID", r.ContainerId, "ifName", r.IfName, "deviceID", r.CNIConf.DeviceID)
+		if _, err := d.cniCmdDelHandler(r); err != nil {
+			errs = append(errs, fmt.Errorf("failed to release %s/%s: %v", r.ContainerId, r.IfName, err))
+		}
+	}
+	if err := d.sm.CmdGC(req); err != nil {
+		errs = append(errs, fmt.Errorf("SRIOV manager failed in gc handler: %v", err))
+	}
+	return nil, errors.Join(errs...)
+}
+
 func (d *HostSideManager) Listen() (net.Listener, error) {
 	d.startedWg.Add(1)
 	d.log.Info("Starting HostDaemon", "devflag", d.dev, "cniServerPath", d.pathManager.CNIServerPath())
 
-	addr, port, err := d.vsp.Start()
+	addr, port, err := d.vsp.Start(context.Background())
 	if err != nil {
 		d.log.Error(err, "VSP init returned error")
 		return nil, err
 	}
 	d.addr = addr
 	d.port = port
+	if err := d.setVfCapabilities(context.Background()); err != nil {
+		d.log.Error(err, "Failed to get the host capabilities from the VSP, using the ones of the PF vendor until it reconnects")
+	}
+	if d.vspClient != nil {
+		// The vsp registered its re-initialization in Start, so it runs
+		// before the capabilities are asked for
+		d.capsOnce.Do(func() {
+			d.vspClient.OnReconnect(d.setVfCapabilities)
+		})
+	}
+	if d.nodePair != nil {
+		d.nodePair.SetEndpoint(addr, port)
+	}
 
 	add := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
 		return d.cniCmdAddHandler(r)
@@ -194,11 +540,48 @@ func (d *HostSideManager) Listen() (net.Listener, error) {
 		return d.cniCmdDelHandler(r)
 	}
 
-	d.cniserver = cniserver.NewCNIServer(add, del, cniserver.WithPathManager(d.pathManager))
+	check := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdCheckHandler(r)
+	}
+
+	status := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdStatusHandler(r)
+	}
+	gc := func(r *cnitypes.PodRequest) (*cni100.Result, error) {
+		return d.cniCmdGCHandler(r)
+	}
+
+	d.cniserver = cniserver.NewCNIServer(add, del,
+		cniserver.WithPathManager(d.pathManager),
+		cniserver.WithResultCacheDir(d.pathManager.CNIResultCacheDir()),
+		cniserver.WithAuditLog(d.cniAuditLog),
+		cniserver.WithCheckHandler(check),
+		cniserver.WithStatusHandler(status),
+		cniserver.WithGCHandler(gc))
 
 	return d.cniserver.Listen()
 }
 
+// setVfCapabilities makes the SRIOV manager use the VF capabilities reported
+// by the VSP. If it reports none, the SRIOV manager falls back to the ones
+// known for the vendor of the PF. An error leaves the capabilities unchanged.
+func (d *HostSideManager) setVfCapabilities(ctx context.Context) error {
+	caps, err := d.vsp.GetHostCapabilities(ctx)
+	if status.Code(err) == codes.Unimplemented {
+		d.log.Info("VSP does not report host capabilities, using the ones of the PF vendor")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to get the host capabilities: %v", err)
+	}
+	d.log.Info("VSP reported host capabilities", "vfVlan", caps.VfVlan, "vfState", caps.VfState)
+	d.sm.SetCapabilities(cnitypes.VfCapabilities{
+		Vlan:  caps.VfVlan,
+		State: caps.VfState,
+	})
+	return nil
+}
+
 func (d *HostSideManager) ListenAndServe() error {
 	var wg sync.WaitGroup
 	done := make(chan error, 3)
@@ -247,6 +630,20 @@ func (d *HostSideManager) ListenAndServe() error {
 		wg.Done()
 	}()
 
+	if d.nodePair != nil {
+		wg.Add(1)
+		go func() {
+			d.nodePair.Run(ctx, d.probePeer)
+			wg.Done()
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		d.releaseStaleAllocations(ctx, allocationGCInterval)
+		wg.Done()
+	}()
+
 	// Block on any go routines writing to the done channel when an error occurs or they
 	// are forced to exit.
 	err = <-done
@@ -259,6 +656,29 @@ func (d *HostSideManager) ListenAndServe() error {
 	return err
 }
 
+// allocationGCInterval is how often the VFs allocated to deleted pods are
+// released.
+const allocationGCInterval = 5 * time.Minute
+
+// releaseStaleAllocations releases the VFs allocated to network namespaces that
+// no longer exist every interval until ctx is done. These are left behind by
+// pods whose DEL never succeeded, e.g. because the node rebooted.
+func (d *HostSideManager) releaseStaleAllocations(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := d.sm.ReleaseStaleAllocations(); err != nil {
+			d.log.Error(err, "Failed to release stale VF allocations")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (d *HostSideManager) Serve(listener net.Listener) error {
 	defer d.startedWg.Done()
 	err := d.cniserver.Serve(listener)
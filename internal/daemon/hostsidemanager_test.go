@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net"
 	"os"
+	"sync/atomic"
+	"time"
 
 	g "github.com/onsi/ginkgo/v2"
 	"go.uber.org/zap/zapcore"
@@ -14,15 +16,22 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/containernetworking/cni/pkg/skel"
+	"github.com/containernetworking/cni/pkg/types"
 	current "github.com/containernetworking/cni/pkg/types/100"
 	"github.com/containernetworking/plugins/pkg/ns"
+	pb2 "github.com/openshift/dpu-operator/dpu-api/gen"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cni"
 	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	"github.com/openshift/dpu-operator/internal/channelcerts"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"github.com/openshift/dpu-operator/internal/testutils"
 	"github.com/openshift/dpu-operator/internal/utils"
 	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	pb "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
 	emptypb "google.golang.org/protobuf/types/known/emptypb"
 
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -40,13 +49,29 @@ func (d *DummyDevicePlugin) Stop() error {
 }
 
 type DummyPlugin struct {
+	// caps are the reported host capabilities, none are reported if nil
+	caps *pb2.HostCapabilities
+	// capsErr makes GetHostCapabilities fail
+	capsErr error
+	// limits are the reported link limits, none are reported if nil
+	limits *pb2.LinkLimits
+	// nfErr makes GetNetworkFunction fail
+	nfErr error
+	// deletedNFs records the DeleteNetworkFunction calls, deleteNFErr makes
+	// them fail
+	deletedNFs  []string
+	deleteNFErr error
+	// createdNFs records the CreateNetworkFunction calls, createNFErr makes
+	// them fail
+	createdNFs  []string
+	createNFErr error
 }
 
 func NewDummyPlugin() *DummyPlugin {
 	return &DummyPlugin{}
 }
 
-func (v *DummyPlugin) Start() (string, int32, error) {
+func (v *DummyPlugin) Start(ctx context.Context) (string, int32, error) {
 	return "127.0.0.1", 50051, nil
 }
 
@@ -54,22 +79,60 @@ func (v *DummyPlugin) Stop() {
 
 }
 
-func (v *DummyPlugin) CreateBridgePort(createRequest *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
+func (v *DummyPlugin) Ready(ctx context.Context) error {
+	return nil
+}
+
+func (v *DummyPlugin) GetHostCapabilities(ctx context.Context) (*pb2.HostCapabilities, error) {
+	if v.capsErr != nil {
+		return nil, v.capsErr
+	}
+	if v.caps == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method GetHostCapabilities not implemented")
+	}
+	return v.caps, nil
+}
+
+func (v *DummyPlugin) GetLinkLimits(ctx context.Context) (*pb2.LinkLimits, error) {
+	if v.limits == nil {
+		return nil, status.Errorf(codes.Unimplemented, "method GetLinkLimits not implemented")
+	}
+	return v.limits, nil
+}
+
+func (v *DummyPlugin) CreateBridgePort(ctx context.Context, createRequest *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
 	return &opi.BridgePort{}, nil
 }
 
-func (v *DummyPlugin) DeleteBridgePort(deleteRequest *opi.DeleteBridgePortRequest) error {
+func (v *DummyPlugin) DeleteBridgePort(ctx context.Context, deleteRequest *opi.DeleteBridgePortRequest) error {
 	return nil
 }
 
-func (g *DummyPlugin) CreateNetworkFunction(input string, output string) error {
+func (v *DummyPlugin) GetBridgePort(ctx context.Context, getRequest *opi.GetBridgePortRequest) (*opi.BridgePort, error) {
+	return &opi.BridgePort{}, nil
+}
+
+func (v *DummyPlugin) SetBridgePortPolicy(ctx context.Context, policy *pb2.BridgePortPolicy) error {
 	return nil
 }
 
-func (g *DummyPlugin) DeleteNetworkFunction(input string, output string) error {
+func (g *DummyPlugin) CreateNetworkFunction(ctx context.Context, input string, output string) error {
+	if g.createNFErr != nil {
+		return g.createNFErr
+	}
+	g.createdNFs = append(g.createdNFs, input+"-"+output)
 	return nil
 }
 
+func (g *DummyPlugin) DeleteNetworkFunction(ctx context.Context, input string, output string) error {
+	g.deletedNFs = append(g.deletedNFs, input+"-"+output)
+	return g.deleteNFErr
+}
+
+func (g *DummyPlugin) GetNetworkFunction(ctx context.Context, input string, output string) error {
+	return g.nfErr
+}
+
 type SriovManagerStub struct{}
 
 func (m SriovManagerStub) SetupVF(conf *cnitypes.NetConf, podifName string, netns ns.NetNS) error {
@@ -92,32 +155,141 @@ func (m SriovManagerStub) FillOriginalVfInfo(conf *cnitypes.NetConf) error {
 	return nil
 }
 
+func (m SriovManagerStub) SetCapabilities(caps cnitypes.VfCapabilities) {
+}
+
 func (m SriovManagerStub) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
 	result := &current.Result{}
 	result.CNIVersion = req.CNIConf.CNIVersion
 	return result, nil
 }
 
+func (m SriovManagerStub) CmdCheck(req *cnitypes.PodRequest) error {
+	return nil
+}
+
+func (m SriovManagerStub) StaleAttachments(req *cnitypes.PodRequest) ([]*cnitypes.PodRequest, error) {
+	return nil, nil
+}
+
+func (m SriovManagerStub) CmdGC(req *cnitypes.PodRequest) error {
+	return nil
+}
+
 func (m SriovManagerStub) CmdDel(req *cnitypes.PodRequest) error {
 	return nil
 }
 
+func (m SriovManagerStub) ReleaseStaleAllocations() error {
+	return nil
+}
+
+// sriovManagerFake records the CmdAdd and CmdDel calls and fails them with
+// the configured errors.
+type sriovManagerFake struct {
+	SriovManagerStub
+	addErr error
+	delErr error
+	adds   int
+	dels   int
+	// caps are the capabilities last set, they are set by reconnects too
+	caps atomic.Pointer[cnitypes.VfCapabilities]
+	// gcs counts the ReleaseStaleAllocations calls
+	gcs atomic.Int32
+	// stale are the attachments StaleAttachments returns, or staleErr
+	stale    []*cnitypes.PodRequest
+	staleErr error
+	cmdGCs   int
+	// checkErr makes CmdCheck fail
+	checkErr error
+}
+
+func (m *sriovManagerFake) CmdCheck(req *cnitypes.PodRequest) error {
+	return m.checkErr
+}
+
+func (m *sriovManagerFake) StaleAttachments(req *cnitypes.PodRequest) ([]*cnitypes.PodRequest, error) {
+	return m.stale, m.staleErr
+}
+
+func (m *sriovManagerFake) CmdGC(req *cnitypes.PodRequest) error {
+	m.cmdGCs++
+	return nil
+}
+
+func (m *sriovManagerFake) ReleaseStaleAllocations() error {
+	m.gcs.Add(1)
+	return nil
+}
+
+func (m *sriovManagerFake) SetCapabilities(caps cnitypes.VfCapabilities) {
+	m.caps.Store(&caps)
+}
+
+func (m *sriovManagerFake) CmdAdd(req *cnitypes.PodRequest) (*current.Result, error) {
+	m.adds++
+	if m.addErr != nil {
+		return nil, m.addErr
+	}
+	return m.SriovManagerStub.CmdAdd(req)
+}
+
+func (m *sriovManagerFake) CmdDel(req *cnitypes.PodRequest) error {
+	m.dels++
+	return m.delErr
+}
+
 type DummyDpuDaemon struct {
 	pb.UnimplementedBridgePortServiceServer
+	pb2.UnimplementedBridgePortPolicyServiceServer
 	server      *grpc.Server
+	creds       channelcerts.Source
 	bridgePorts int
+	policies    []*pb2.BridgePortPolicy
+	// createErr makes CreateBridgePort fail
+	createErr error
+	// policyErr makes SetBridgePortPolicy fail
+	policyErr error
+	// deleteErr makes DeleteBridgePort fail
+	deleteErr error
+	// getErr makes GetBridgePort fail
+	getErr error
 }
 
 func (s *DummyDpuDaemon) CreateBridgePort(context context.Context, bpr *pb.CreateBridgePortRequest) (*pb.BridgePort, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
 	s.bridgePorts += 1
 	return &pb.BridgePort{}, nil
 }
 
 func (s *DummyDpuDaemon) DeleteBridgePort(context context.Context, bpr *pb.DeleteBridgePortRequest) (*emptypb.Empty, error) {
+	if s.deleteErr != nil {
+		return nil, s.deleteErr
+	}
 	s.bridgePorts -= 1
 	return &emptypb.Empty{}, nil
 }
 
+func (s *DummyDpuDaemon) GetBridgePort(context context.Context, bpr *pb.GetBridgePortRequest) (*pb.BridgePort, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	if s.bridgePorts == 0 {
+		return nil, status.Errorf(codes.NotFound, "bridge port %s not found", bpr.Name)
+	}
+	return &pb.BridgePort{Name: bpr.Name}, nil
+}
+
+func (s *DummyDpuDaemon) SetBridgePortPolicy(context context.Context, policy *pb2.BridgePortPolicy) (*pb2.Empty, error) {
+	if s.policyErr != nil {
+		return nil, s.policyErr
+	}
+	s.policies = append(s.policies, policy)
+	return &pb2.Empty{}, nil
+}
+
 func (d *DummyDpuDaemon) Listen() (net.Listener, error) {
 	addr := "127.0.0.1"
 	port := 50051
@@ -125,12 +297,15 @@ func (d *DummyDpuDaemon) Listen() (net.Listener, error) {
 	if err != nil {
 		return lis, fmt.Errorf("Failed to start to listen on addr %v port %v", addr, port)
 	}
+	// Create the server here rather than in Serve so that Stop does not
+	// race with the goroutine calling Serve
+	d.server = grpc.NewServer(grpc.Creds(credentials.NewTLS(channelcerts.ServerConfig(d.creds))))
+	pb.RegisterBridgePortServiceServer(d.server, d)
+	pb2.RegisterBridgePortPolicyServiceServer(d.server, d)
 	return lis, nil
 }
 
 func (d *DummyDpuDaemon) Serve(listen net.Listener) error {
-	d.server = grpc.NewServer()
-	pb.RegisterBridgePortServiceServer(d.server, d)
 	if err := d.server.Serve(listen); err != nil {
 		return fmt.Errorf("Fialed to start serving: %v", err)
 	}
@@ -141,6 +316,23 @@ func (d *DummyDpuDaemon) Stop() {
 	d.server.Stop()
 }
 
+// newChannelCredentials returns credentials for the host and the DPU issued by
+// a common CA, like the operator does for a host/DPU pair.
+func newChannelCredentials() (channelcerts.Source, channelcerts.Source) {
+	now := time.Now()
+	ca, err := channelcerts.GenerateCA(now)
+	Expect(err).NotTo(HaveOccurred())
+
+	source := func(node string, peer string) channelcerts.Source {
+		kp, err := channelcerts.IssueCert(ca, node, now)
+		Expect(err).NotTo(HaveOccurred())
+		src, err := channelcerts.NewStaticSource(kp.CertPEM, kp.KeyPEM, ca.CertPEM, peer)
+		Expect(err).NotTo(HaveOccurred())
+		return src
+	}
+	return source("host", "dpu"), source("dpu", "host")
+}
+
 func PrepArgs(cniVersion string, command string) *skel.CmdArgs {
 	cniConfig := "{\"cniVersion\": \"" + cniVersion + "\",\"name\": \"dpucni\",\"type\": \"dpucni\", \"OrigVfState\": {\"EffectiveMac\": \"00:11:22:33:44:55\"}, \"vlan\": 7}"
 	cmdArgs := &skel.CmdArgs{
@@ -166,6 +358,11 @@ func cmdAdd(cniVersion string, serverSocketPath string) (*cnitypes.Response, str
 	return p.PostRequest(PrepArgs(cniVersion, cnitypes.CNIAdd))
 }
 
+func cmdCheck(cniVersion string, serverSocketPath string) error {
+	p := &cni.Plugin{SocketPath: serverSocketPath}
+	return p.CmdCheck(PrepArgs(cniVersion, cnitypes.CNICheck))
+}
+
 var _ = g.BeforeSuite(func() {
 	opts := zap.Options{
 		Development: true,
@@ -189,12 +386,14 @@ var _ = g.Describe("Host Daemon", func() {
 		testCluster.EnsureExists()
 		pathManager = utils.NewPathManager(testCluster.TempDirPath())
 		Expect(err).NotTo(HaveOccurred())
-		fakeDpuDaemon = &DummyDpuDaemon{}
+		hostCreds, dpuCreds := newChannelCredentials()
+		fakeDpuDaemon = &DummyDpuDaemon{creds: dpuCreds}
 		dummyPluginHost := NewDummyPlugin()
 		m := SriovManagerStub{}
 		hostDaemon = NewHostSideManager(dummyPluginHost, &DummyDevicePlugin{}).
 			WithPathManager(pathManager).
-			WithSriovManager(m)
+			WithSriovManager(m).
+			WithChannelCredentials(hostCreds)
 	})
 
 	g.AfterEach(func() {
@@ -230,5 +429,427 @@ var _ = g.Describe("Host Daemon", func() {
 
 			Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
 		})
+
+		g.It("should fail CHECK when the DPU has no bridge port", func() {
+			dpuListen, err := fakeDpuDaemon.Listen()
+			Expect(err).NotTo(HaveOccurred())
+			go func() {
+				fakeDpuDaemon.Serve(dpuListen)
+			}()
+
+			hostListen, err := hostDaemon.Listen()
+			Expect(err).NotTo(HaveOccurred())
+			go func() {
+				hostDaemon.Serve(hostListen)
+			}()
+
+			cniVersion := "0.4.0"
+			Expect(cmdCheck(cniVersion, pathManager.CNIServerPath())).NotTo(Succeed())
+
+			_, _, err = cmdAdd(cniVersion, pathManager.CNIServerPath())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdCheck(cniVersion, pathManager.CNIServerPath())).To(Succeed())
+		})
+	})
+})
+
+// lifeCycleVsp is a VSP that only answers the life cycle service
+type lifeCycleVsp struct {
+	pb2.UnimplementedLifeCycleServiceServer
+}
+
+func (v *lifeCycleVsp) Init(ctx context.Context, in *pb2.InitRequest) (*pb2.IpPort, error) {
+	return &pb2.IpPort{Ip: "127.0.0.1", Port: 50051}, nil
+}
+
+func serveLifeCycleVsp(pathManager *utils.PathManager) *grpc.Server {
+	socket := pathManager.VendorPluginSocket()
+	Expect(pathManager.EnsureSocketDirExists(socket)).To(Succeed())
+	listener, err := net.Listen("unix", socket)
+	Expect(err).NotTo(HaveOccurred())
+	server := grpc.NewServer()
+	pb2.RegisterLifeCycleServiceServer(server, &lifeCycleVsp{})
+	go server.Serve(listener)
+	return server
+}
+
+var _ = g.Describe("Host Daemon VF capabilities", func() {
+	g.It("should pass the capabilities reported by the VSP to the SRIOV manager", func() {
+		vsp := NewDummyPlugin()
+		vsp.caps = &pb2.HostCapabilities{VfVlan: true}
+		sm := &sriovManagerFake{}
+		Expect(NewHostSideManager(vsp, &DummyDevicePlugin{}).WithSriovManager(sm).setVfCapabilities(context.Background())).To(Succeed())
+		Expect(sm.caps.Load()).To(Equal(&cnitypes.VfCapabilities{Vlan: true, State: false}))
+	})
+
+	g.It("should leave the capabilities to the PF vendor if the VSP reports none", func() {
+		sm := &sriovManagerFake{}
+		Expect(NewHostSideManager(NewDummyPlugin(), &DummyDevicePlugin{}).WithSriovManager(sm).setVfCapabilities(context.Background())).To(Succeed())
+		Expect(sm.caps.Load()).To(BeNil())
+	})
+
+	g.It("should keep the capabilities if the VSP fails to report them", func() {
+		vsp := NewDummyPlugin()
+		vsp.capsErr = status.Error(codes.Unavailable, "restarting")
+		sm := &sriovManagerFake{}
+		err := NewHostSideManager(vsp, &DummyDevicePlugin{}).WithSriovManager(sm).setVfCapabilities(context.Background())
+		Expect(err).To(MatchError(ContainSubstring("restarting")))
+		Expect(sm.caps.Load()).To(BeNil())
+	})
+
+	g.It("should ask for the capabilities again after the VSP restarted", func() {
+		pathManager := utils.NewPathManager(g.GinkgoT().TempDir())
+		server := serveLifeCycleVsp(pathManager)
+		vspClient := vspclient.NewVspClient(vspclient.WithPathManager(*pathManager))
+		g.DeferCleanup(func() {
+			vspClient.Close()
+			server.Stop()
+		})
+		// The watch of the connection starts with the first call
+		lc, err := vspClient.LifeCycle()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = lc.Init(context.Background(), &pb2.InitRequest{})
+		Expect(err).NotTo(HaveOccurred())
+
+		vsp := NewDummyPlugin()
+		vsp.caps = &pb2.HostCapabilities{VfVlan: true, VfState: true}
+		sm := &sriovManagerFake{}
+		hostDaemon := NewHostSideManager(vsp, &DummyDevicePlugin{}).
+			WithSriovManager(sm).
+			WithPathManager(pathManager).
+			WithVspClient(vspClient)
+		listener, err := hostDaemon.Listen()
+		Expect(err).NotTo(HaveOccurred())
+		defer listener.Close()
+		Expect(sm.caps.Load()).To(Equal(&cnitypes.VfCapabilities{Vlan: true, State: true}))
+
+		sm.caps.Store(nil)
+		server.Stop()
+		server = serveLifeCycleVsp(pathManager)
+		Eventually(sm.caps.Load, 30*time.Second, 100*time.Millisecond).Should(Equal(&cnitypes.VfCapabilities{Vlan: true, State: true}))
+	})
+})
+
+var _ = g.Describe("Host Daemon link limits", func() {
+	g.It("should refuse an MTU larger than the one of the data path", func() {
+		vsp := NewDummyPlugin()
+		vsp.limits = &pb2.LinkLimits{MaxMtu: 1500}
+		Expect(checkMTU(context.Background(), vsp, 1500)).To(BeNil())
+		err := checkMTU(context.Background(), vsp, 9000)
+		Expect(err).NotTo(BeNil())
+		Expect(err.Code).To(Equal(uint(types.ErrInvalidNetworkConfig)))
+	})
+
+	g.It("should not limit the MTU if the VSP reports no limits", func() {
+		Expect(checkMTU(context.Background(), NewDummyPlugin(), 9000)).To(BeNil())
+		vsp := NewDummyPlugin()
+		vsp.limits = &pb2.LinkLimits{}
+		Expect(checkMTU(context.Background(), vsp, 9000)).To(BeNil())
+	})
+})
+
+var _ = g.Describe("Host Daemon VF allocation GC", func() {
+	g.It("should release stale VF allocations periodically until stopped", func() {
+		sm := &sriovManagerFake{}
+		hostDaemon := NewHostSideManager(NewDummyPlugin(), &DummyDevicePlugin{}).WithSriovManager(sm)
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			hostDaemon.releaseStaleAllocations(ctx, 10*time.Millisecond)
+			close(done)
+		}()
+		Eventually(sm.gcs.Load).Should(BeNumerically(">=", 2))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
+
+// newHostDaemonWithDpu returns a host daemon using the SRIOV manager sm that
+// is connected to a DummyDpuDaemon served for the rest of the spec.
+func newHostDaemonWithDpu(sm *sriovManagerFake) (*HostSideManager, *DummyDpuDaemon) {
+	hostCreds, dpuCreds := newChannelCredentials()
+	fakeDpuDaemon := &DummyDpuDaemon{creds: dpuCreds}
+	dpuListen, err := fakeDpuDaemon.Listen()
+	Expect(err).NotTo(HaveOccurred())
+	go func() {
+		fakeDpuDaemon.Serve(dpuListen)
+	}()
+	g.DeferCleanup(func() {
+		fakeDpuDaemon.Stop()
+		// Serve may not have taken over the listener yet
+		dpuListen.Close()
+	})
+
+	hostDaemon := NewHostSideManager(NewDummyPlugin(), &DummyDevicePlugin{}).
+		WithSriovManager(sm).
+		WithChannelCredentials(hostCreds)
+	hostDaemon.addr, hostDaemon.port, err = NewDummyPlugin().Start(context.Background())
+	Expect(err).NotTo(HaveOccurred())
+	return hostDaemon, fakeDpuDaemon
+}
+
+var _ = g.Describe("Host Daemon ADD rollback", func() {
+	var (
+		fakeDpuDaemon *DummyDpuDaemon
+		hostDaemon    *HostSideManager
+		sm            *sriovManagerFake
+	)
+
+	addRequest := func() *cnitypes.PodRequest {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		g.DeferCleanup(cancel)
+		return &cnitypes.PodRequest{
+			Command:     cnitypes.CNIAdd,
+			ContainerId: "fakecontainerid",
+			IfName:      "fakeeth0",
+			CNIConf: &cnitypes.NetConf{
+				DeviceID:    "0000:01:00.2",
+				VFID:        0,
+				OrigVfState: cnitypes.VfState{EffectiveMAC: "00:11:22:33:44:55"},
+			},
+			Ctx:    ctx,
+			Cancel: cancel,
+		}
+	}
+
+	g.BeforeEach(func() {
+		sm = &sriovManagerFake{}
+		hostDaemon, fakeDpuDaemon = newHostDaemonWithDpu(sm)
+	})
+
+	g.It("should not release the VF if setting it up fails", func() {
+		sm.addErr = fmt.Errorf("IPAM failed")
+		_, err := hostDaemon.cniCmdAddHandler(addRequest())
+		Expect(err).To(MatchError(ContainSubstring("IPAM failed")))
+		Expect(sm.dels).To(Equal(0))
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(0))
+	})
+
+	g.It("should release the VF if the bridge port fails", func() {
+		fakeDpuDaemon.createErr = status.Error(codes.Internal, "no bridge")
+		_, err := hostDaemon.cniCmdAddHandler(addRequest())
+		Expect(err).To(MatchError(ContainSubstring("no bridge")))
+		Expect(sm.adds).To(Equal(1))
+		Expect(sm.dels).To(Equal(1))
+	})
+
+	g.It("should report both errors if the rollback fails", func() {
+		fakeDpuDaemon.createErr = status.Error(codes.Internal, "no bridge")
+		sm.delErr = fmt.Errorf("VF busy")
+		_, err := hostDaemon.cniCmdAddHandler(addRequest())
+		Expect(err).To(MatchError(And(ContainSubstring("no bridge"), ContainSubstring("VF busy"))))
+		Expect(sm.dels).To(Equal(1))
+	})
+
+	g.It("should keep the VF if the bridge port is created", func() {
+		req := addRequest()
+		_, err := hostDaemon.cniCmdAddHandler(req)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sm.dels).To(Equal(0))
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+		Expect(fakeDpuDaemon.policies).To(BeEmpty())
+		vfID := 0
+		Expect(req.DeviceInfo.Dpu).To(Equal(&cnitypes.DpuDevice{VfID: &vfID, BridgePort: "host0-0", LogicalBridge: "2"}))
+	})
+
+	g.Context("with a rate limit and spoof checking", func() {
+		policyRequest := func() *cnitypes.PodRequest {
+			req := addRequest()
+			maxTxRate := 100
+			req.CNIConf.MaxTxRate = &maxTxRate
+			req.CNIConf.SpoofChk = "on"
+			return req
+		}
+
+		g.It("should forward the policy to the DPU", func() {
+			_, err := hostDaemon.cniCmdAddHandler(policyRequest())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeDpuDaemon.policies).To(HaveLen(1))
+			policy := fakeDpuDaemon.policies[0]
+			Expect(policy.BridgePortName).To(Equal("host0-0"))
+			Expect(net.HardwareAddr(policy.MacAddress).String()).To(Equal("00:11:22:33:44:55"))
+			Expect(policy.MinTxRate).To(Equal(uint32(0)))
+			Expect(policy.MaxTxRate).To(Equal(uint32(100)))
+			Expect(policy.SpoofCheck).To(BeTrue())
+			Expect(policy.Trust).To(BeFalse())
+		})
+
+		g.It("should delete the bridge port and release the VF if the policy fails", func() {
+			fakeDpuDaemon.policyErr = status.Error(codes.Internal, "no meter")
+			_, err := hostDaemon.cniCmdAddHandler(policyRequest())
+			Expect(err).To(MatchError(ContainSubstring("no meter")))
+			Expect(fakeDpuDaemon.bridgePorts).To(Equal(0))
+			Expect(sm.dels).To(Equal(1))
+		})
+
+		g.It("should keep the VF if the DPU does not enforce policies", func() {
+			fakeDpuDaemon.policyErr = status.Error(codes.Unimplemented, "unknown service")
+			_, err := hostDaemon.cniCmdAddHandler(policyRequest())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+			Expect(sm.dels).To(Equal(0))
+		})
+
+		g.It("should fail and undo the ADD of a sub function if the DPU does not enforce policies", func() {
+			fakeDpuDaemon.policyErr = status.Error(codes.Unimplemented, "unknown service")
+			req := policyRequest()
+			req.CNIConf.DeviceID = "mlx5_core.sf.4"
+			req.CNIConf.SFNum = 3
+			_, err := hostDaemon.cniCmdAddHandler(req)
+			Expect(err).To(BeAssignableToTypeOf(&types.Error{}))
+			Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrUnsupportedField)))
+			Expect(fakeDpuDaemon.bridgePorts).To(Equal(0))
+			Expect(sm.dels).To(Equal(1))
+		})
+	})
+})
+
+var _ = g.Describe("Host Daemon bridge ports", func() {
+	g.DescribeTable("should name the bridge port and logical bridge of the function",
+		func(conf cnitypes.NetConf, portName string, logicalBridge string) {
+			fn := hostFunctionOf(1, &conf)
+			Expect(fn.bridgePortName()).To(Equal(portName))
+			Expect(fn.logicalBridge()).To(Equal(logicalBridge))
+			dev := fn.dpuDevice()
+			Expect(dev.BridgePort).To(Equal(portName))
+			Expect(dev.LogicalBridge).To(Equal(logicalBridge))
+			if conf.IsSF() {
+				Expect(dev.VfID).To(BeNil())
+			} else {
+				Expect(dev.VfID).To(HaveValue(Equal(conf.VFID)))
+			}
+		},
+		g.Entry("VF", cnitypes.NetConf{DeviceID: "0000:3b:00.2", VFID: 3}, "host1-3", "5"),
+		g.Entry("sub function", cnitypes.NetConf{DeviceID: "mlx5_core.sf.4", SFNum: 3}, "host1-sf3", "sf3"),
+	)
+})
+
+var _ = g.Describe("Host Daemon GC", func() {
+	var (
+		fakeDpuDaemon *DummyDpuDaemon
+		hostDaemon    *HostSideManager
+		sm            *sriovManagerFake
+	)
+
+	request := func(command string, containerID string) *cnitypes.PodRequest {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		g.DeferCleanup(cancel)
+		return &cnitypes.PodRequest{
+			Command:     command,
+			ContainerId: containerID,
+			IfName:      "net1",
+			CNIConf: &cnitypes.NetConf{
+				DeviceID:    "0000:01:00.2",
+				OrigVfState: cnitypes.VfState{EffectiveMAC: "00:11:22:33:44:55"},
+			},
+			Ctx:    ctx,
+			Cancel: cancel,
+		}
+	}
+
+	g.BeforeEach(func() {
+		sm = &sriovManagerFake{}
+		hostDaemon, fakeDpuDaemon = newHostDaemonWithDpu(sm)
+		_, err := hostDaemon.cniCmdAddHandler(request(cnitypes.CNIAdd, "stale"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+	})
+
+	g.It("should release the VF and bridge port of a stale attachment", func() {
+		sm.stale = []*cnitypes.PodRequest{request(cnitypes.CNIDel, "stale")}
+		_, err := hostDaemon.cniCmdGCHandler(request(cnitypes.CNIGC, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sm.dels).To(Equal(1))
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(0))
+		Expect(sm.cmdGCs).To(Equal(1))
+	})
+
+	g.It("should leave the valid attachments alone", func() {
+		_, err := hostDaemon.cniCmdGCHandler(request(cnitypes.CNIGC, ""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sm.dels).To(Equal(0))
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+		Expect(sm.cmdGCs).To(Equal(1))
+	})
+
+	g.It("should still collect the IPAM leases if a VF cannot be released", func() {
+		sm.stale = []*cnitypes.PodRequest{request(cnitypes.CNIDel, "stale")}
+		sm.delErr = fmt.Errorf("VF busy")
+		_, err := hostDaemon.cniCmdGCHandler(request(cnitypes.CNIGC, ""))
+		Expect(err).To(MatchError(ContainSubstring("failed to release stale/net1")))
+		// The bridge port stays until the VF is released
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+		Expect(sm.cmdGCs).To(Equal(1))
 	})
+
+	g.It("should fail DEL and keep the bridge port if the DPU cannot delete it", func() {
+		fakeDpuDaemon.deleteErr = status.Error(codes.Unavailable, "VSP restarting")
+		_, err := hostDaemon.cniCmdDelHandler(request(cnitypes.CNIDel, "stale"))
+		Expect(err).To(BeAssignableToTypeOf(&types.Error{}))
+		Expect(err.(*types.Error).Code).To(Equal(uint(types.ErrTryAgainLater)))
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(1))
+
+		// The runtime retries DEL
+		fakeDpuDaemon.deleteErr = nil
+		_, err = hostDaemon.cniCmdDelHandler(request(cnitypes.CNIDel, "stale"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeDpuDaemon.bridgePorts).To(Equal(0))
+	})
+
+	g.It("should not fail DEL if the bridge port is already gone", func() {
+		fakeDpuDaemon.deleteErr = status.Error(codes.NotFound, "bridge port host0-0 not found")
+		_, err := hostDaemon.cniCmdDelHandler(request(cnitypes.CNIDel, "stale"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	g.It("should fail if the stale attachments cannot be listed", func() {
+		sm.staleErr = fmt.Errorf("cache unreadable")
+		_, err := hostDaemon.cniCmdGCHandler(request(cnitypes.CNIGC, ""))
+		Expect(err).To(MatchError(ContainSubstring("cache unreadable")))
+		Expect(sm.cmdGCs).To(Equal(0))
+	})
+})
+
+var _ = g.Describe("Host Daemon CHECK", func() {
+	var (
+		fakeDpuDaemon *DummyDpuDaemon
+		hostDaemon    *HostSideManager
+		sm            *sriovManagerFake
+	)
+
+	g.BeforeEach(func() {
+		sm = &sriovManagerFake{}
+		hostDaemon, fakeDpuDaemon = newHostDaemonWithDpu(sm)
+		fakeDpuDaemon.bridgePorts = 1
+	})
+
+	check := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_, err := hostDaemon.cniCmdCheckHandler(&cnitypes.PodRequest{
+			Command:     cnitypes.CNICheck,
+			ContainerId: "pod1",
+			IfName:      "net1",
+			CNIConf:     &cnitypes.NetConf{DeviceID: "0000:01:00.2"},
+			Ctx:         ctx,
+		})
+		return err
+	}
+
+	g.It("should pass if the VF and its bridge port are in place", func() {
+		Expect(check()).To(Succeed())
+	})
+
+	g.DescribeTable("should fail with the code of the failure",
+		func(setup func(), code uint) {
+			setup()
+			err := check()
+			Expect(err).To(BeAssignableToTypeOf(&types.Error{}))
+			Expect(err.(*types.Error).Code).To(Equal(code))
+		},
+		g.Entry("VF changed", func() { sm.checkErr = fmt.Errorf("vf 0 has vlan 3, expected 2") }, cnitypes.ErrCheckFailed),
+		g.Entry("bridge port missing", func() { fakeDpuDaemon.bridgePorts = 0 }, cnitypes.ErrCheckFailed),
+		g.Entry("DPU unreachable", func() { fakeDpuDaemon.getErr = status.Error(codes.Unavailable, "VSP restarting") }, uint(types.ErrTryAgainLater)),
+		g.Entry("DPU failure", func() { fakeDpuDaemon.getErr = status.Error(codes.Internal, "bridge unreadable") }, uint(types.ErrInternal)),
+	)
 })
@@ -0,0 +1,242 @@
+package nfstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+)
+
+// Interface is an interface of a network function pod.
+type Interface struct {
+	IfName   string `json:"ifName"`
+	MAC      string `json:"mac"`
+	Role     string `json:"role"`
+	PortPair int    `json:"portPair"`
+	// ContainerID and Network identify the attachment for CNI GC
+	ContainerID string `json:"containerID,omitempty"`
+	Network     string `json:"network,omitempty"`
+	// OrigLink are the link attributes the interface had before CNI ADD
+	// changed them, restored by CNI DEL
+	OrigLink *cnitypes.LinkConfig `json:"origLink,omitempty"`
+}
+
+// PortPair is a complete ingress/egress pair of a network function pod.
+type PortPair struct {
+	Index   int
+	Ingress Interface
+	Egress  Interface
+}
+
+// Contains returns whether ifName is one of the interfaces of the pair.
+func (p PortPair) Contains(ifName string) bool {
+	return p.Ingress.IfName == ifName || p.Egress.IfName == ifName
+}
+
+// PortPairs returns the complete port pairs of ifaces ordered by index.
+func PortPairs(ifaces []Interface) []PortPair {
+	ingress := map[int]Interface{}
+	egress := map[int]Interface{}
+	for _, iface := range ifaces {
+		switch iface.Role {
+		case cnitypes.NFRoleIngress:
+			ingress[iface.PortPair] = iface
+		case cnitypes.NFRoleEgress:
+			egress[iface.PortPair] = iface
+		}
+	}
+
+	var pairs []PortPair
+	for index, in := range ingress {
+		if out, ok := egress[index]; ok {
+			pairs = append(pairs, PortPair{Index: index, Ingress: in, Egress: out})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].Index < pairs[j].Index })
+	return pairs
+}
+
+// entry is the content of the file checkpointing a network function pod.
+type entry struct {
+	Netns      string      `json:"netns"`
+	Interfaces []Interface `json:"interfaces,omitempty"`
+}
+
+// defaultRole is the role of an interface added without one: ingress, then
+// egress of port pair 0, then management.
+func defaultRole(ifaces []Interface) string {
+	taken := map[string]bool{}
+	for _, iface := range ifaces {
+		if iface.PortPair == 0 {
+			taken[iface.Role] = true
+		}
+	}
+	switch {
+	case !taken[cnitypes.NFRoleIngress]:
+		return cnitypes.NFRoleIngress
+	case !taken[cnitypes.NFRoleEgress]:
+		return cnitypes.NFRoleEgress
+	default:
+		return cnitypes.NFRoleManagement
+	}
+}
+
+// Store checkpoints the interfaces of each network function pod, keyed by the
+// pod's network namespace. Every pod is stored in its own file under dataDir
+// so that the pairing survives a daemon restart. It is safe for concurrent
+// use.
+type Store struct {
+	dataDir string
+	mu      sync.Mutex
+}
+
+func NewStore(dataDir string) *Store {
+	return &Store{dataDir: dataDir}
+}
+
+func (s *Store) path(netns string) string {
+	return filepath.Join(s.dataDir, strings.ReplaceAll(strings.Trim(netns, "/"), "/", "_"))
+}
+
+// Get returns the interfaces recorded for netns, in the order they were
+// added.
+func (s *Store) Get(netns string) ([]Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(netns)
+}
+
+func (s *Store) get(netns string) ([]Interface, error) {
+	data, err := os.ReadFile(s.path(netns))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network function file for %s: %v", netns, err)
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("failed to parse network function file for %s: %v", netns, err)
+	}
+	return e.Interfaces, nil
+}
+
+// Set records ifaces for netns, removing the record if ifaces is empty.
+func (s *Store) Set(netns string, ifaces []Interface) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(netns, ifaces)
+}
+
+func (s *Store) set(netns string, ifaces []Interface) error {
+	path := s.path(netns)
+	if len(ifaces) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove network function file %s: %v", path, err)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(s.dataDir, 0700); err != nil {
+		return fmt.Errorf("failed to create the network function directory(%q): %v", s.dataDir, err)
+	}
+	data, err := json.Marshal(entry{Netns: netns, Interfaces: ifaces})
+	if err != nil {
+		return fmt.Errorf("failed to serialize network function for %s: %v", netns, err)
+	}
+	// Write to a temporary file first so a crash never leaves a partial record
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write network function file %s: %v", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename network function file %s: %v", tmp, err)
+	}
+	return nil
+}
+
+// Add records iface for netns and returns all interfaces of netns. An
+// interface without a role is assigned the next free role of port pair 0. It
+// fails if another interface already has the role of iface in its port pair.
+func (s *Store) Add(netns string, iface Interface) ([]Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ifaces, err := s.get(netns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Replace the interface if ADD is repeated
+	var others []Interface
+	for _, existing := range ifaces {
+		if existing.IfName != iface.IfName {
+			others = append(others, existing)
+		}
+	}
+	if iface.Role == "" {
+		iface.Role = defaultRole(others)
+	}
+	if iface.Role != cnitypes.NFRoleManagement {
+		for _, existing := range others {
+			if existing.Role == iface.Role && existing.PortPair == iface.PortPair {
+				return nil, fmt.Errorf("interface %s is already the %s of port pair %d", existing.IfName, iface.Role, iface.PortPair)
+			}
+		}
+	}
+
+	ifaces = append(others, iface)
+	return ifaces, s.set(netns, ifaces)
+}
+
+// Remove removes the interface ifName of netns.
+func (s *Store) Remove(netns string, ifName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ifaces, err := s.get(netns)
+	if err != nil {
+		return err
+	}
+	var remaining []Interface
+	for _, iface := range ifaces {
+		if iface.IfName != ifName {
+			remaining = append(remaining, iface)
+		}
+	}
+	return s.set(netns, remaining)
+}
+
+// List returns the interfaces of all network function pods by network
+// namespace.
+func (s *Store) List() (map[string][]Interface, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files, err := os.ReadDir(s.dataDir)
+	if os.IsNotExist(err) {
+		return map[string][]Interface{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network function directory %s: %v", s.dataDir, err)
+	}
+
+	result := map[string][]Interface{}
+	for _, f := range files {
+		if f.IsDir() || strings.HasSuffix(f.Name(), ".tmp") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dataDir, f.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read network function file %s: %v", f.Name(), err)
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return nil, fmt.Errorf("failed to parse network function file %s: %v", f.Name(), err)
+		}
+		result[e.Netns] = e.Interfaces
+	}
+	return result, nil
+}
@@ -0,0 +1,13 @@
+package nfstore_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestNfstore(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "NfStore Suite")
+}
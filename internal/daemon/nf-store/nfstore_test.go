@@ -0,0 +1,138 @@
+package nfstore_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	"github.com/openshift/dpu-operator/dpu-cni/pkgs/cnitypes"
+	nfstore "github.com/openshift/dpu-operator/internal/daemon/nf-store"
+)
+
+var _ = g.Describe("Network function store", func() {
+	var dataDir string
+
+	g.BeforeEach(func() {
+		var err error
+		dataDir, err = os.MkdirTemp("", "nfstore")
+		o.Expect(err).NotTo(o.HaveOccurred())
+	})
+
+	g.AfterEach(func() {
+		os.RemoveAll(dataDir)
+	})
+
+	g.It("should keep the pairing across restarts", func() {
+		store := nfstore.NewStore(dataDir)
+		ifaces, err := store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net1", MAC: "00:00:00:00:00:01"})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(ifaces).To(o.HaveLen(1))
+		o.Expect(nfstore.PortPairs(ifaces)).To(o.BeEmpty())
+		ifaces, err = store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net2", MAC: "00:00:00:00:00:02"})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(nfstore.PortPairs(ifaces)).To(o.Equal([]nfstore.PortPair{{
+			Index:   0,
+			Ingress: nfstore.Interface{IfName: "net1", MAC: "00:00:00:00:00:01", Role: cnitypes.NFRoleIngress},
+			Egress:  nfstore.Interface{IfName: "net2", MAC: "00:00:00:00:00:02", Role: cnitypes.NFRoleEgress},
+		}}))
+
+		restarted := nfstore.NewStore(dataDir)
+		got, err := restarted.Get("/var/run/netns/pod1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(got).To(o.Equal(ifaces))
+
+		all, err := restarted.List()
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(all).To(o.HaveKeyWithValue("/var/run/netns/pod1", ifaces))
+	})
+
+	g.It("should remove records without interfaces", func() {
+		store := nfstore.NewStore(dataDir)
+		_, err := store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net1", MAC: "00:00:00:00:00:01"})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(store.Remove("/var/run/netns/pod1", "net1")).To(o.Succeed())
+
+		ifaces, err := store.Get("/var/run/netns/pod1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(ifaces).To(o.BeEmpty())
+		all, err := store.List()
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(all).To(o.BeEmpty())
+	})
+
+	g.It("should pair interfaces by role instead of order", func() {
+		store := nfstore.NewStore(dataDir)
+		add := func(ifName string, role string, pair int) []nfstore.Interface {
+			ifaces, err := store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: ifName, MAC: "mac-" + ifName, Role: role, PortPair: pair})
+			o.Expect(err).NotTo(o.HaveOccurred())
+			return ifaces
+		}
+		add("net1", cnitypes.NFRoleManagement, 0)
+		add("net2", cnitypes.NFRoleEgress, 1)
+		add("net3", cnitypes.NFRoleEgress, 0)
+		o.Expect(nfstore.PortPairs(add("net4", cnitypes.NFRoleIngress, 1))).To(o.HaveLen(1))
+		pairs := nfstore.PortPairs(add("net5", cnitypes.NFRoleIngress, 0))
+
+		o.Expect(pairs).To(o.HaveLen(2))
+		o.Expect(pairs[0].Index).To(o.Equal(0))
+		o.Expect(pairs[0].Ingress.MAC).To(o.Equal("mac-net5"))
+		o.Expect(pairs[0].Egress.MAC).To(o.Equal("mac-net3"))
+		o.Expect(pairs[1].Index).To(o.Equal(1))
+		o.Expect(pairs[1].Ingress.MAC).To(o.Equal("mac-net4"))
+		o.Expect(pairs[1].Egress.MAC).To(o.Equal("mac-net2"))
+		o.Expect(pairs[1].Contains("net2")).To(o.BeTrue())
+		o.Expect(pairs[1].Contains("net1")).To(o.BeFalse())
+	})
+
+	g.It("should refuse two interfaces with the same role in a port pair", func() {
+		store := nfstore.NewStore(dataDir)
+		_, err := store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net1", Role: cnitypes.NFRoleIngress})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		_, err = store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net2", Role: cnitypes.NFRoleIngress})
+		o.Expect(err).To(o.HaveOccurred())
+		// Repeating ADD for the same interface replaces it
+		_, err = store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: "net1", Role: cnitypes.NFRoleIngress})
+		o.Expect(err).NotTo(o.HaveOccurred())
+
+		ifaces, err := store.Get("/var/run/netns/pod1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(ifaces).To(o.HaveLen(1))
+	})
+
+	g.It("should read the records written by a previous daemon", func() {
+		data := `{"netns":"/var/run/netns/pod1","interfaces":[` +
+			`{"ifName":"nf-in","mac":"00:00:00:00:00:01","role":"ingress","portPair":0},` +
+			`{"ifName":"nf-out","mac":"00:00:00:00:00:02","role":"egress","portPair":0}]}`
+		o.Expect(os.WriteFile(filepath.Join(dataDir, "var_run_netns_pod1"), []byte(data), 0600)).To(o.Succeed())
+
+		ifaces, err := nfstore.NewStore(dataDir).Get("/var/run/netns/pod1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		pairs := nfstore.PortPairs(ifaces)
+		o.Expect(pairs).To(o.HaveLen(1))
+		o.Expect(pairs[0].Ingress).To(o.Equal(nfstore.Interface{IfName: "nf-in", MAC: "00:00:00:00:00:01", Role: cnitypes.NFRoleIngress}))
+		o.Expect(pairs[0].Egress).To(o.Equal(nfstore.Interface{IfName: "nf-out", MAC: "00:00:00:00:00:02", Role: cnitypes.NFRoleEgress}))
+	})
+
+	g.It("should not lose concurrent additions", func() {
+		store := nfstore.NewStore(dataDir)
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer g.GinkgoRecover()
+				defer wg.Done()
+				_, err := store.Add("/var/run/netns/pod1", nfstore.Interface{IfName: fmt.Sprintf("net%d", i), MAC: fmt.Sprintf("00:00:00:00:00:%02x", i)})
+				o.Expect(err).NotTo(o.HaveOccurred())
+			}(i)
+		}
+		wg.Wait()
+
+		ifaces, err := store.Get("/var/run/netns/pod1")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(ifaces).To(o.HaveLen(16))
+		o.Expect(nfstore.PortPairs(ifaces)).To(o.HaveLen(1))
+	})
+})
@@ -0,0 +1,246 @@
+package nodepair
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	configv1 "github.com/openshift/dpu-operator/api/v1"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// DefaultInterval is how often the pair is probed and its DpuNodePair
+	// updated.
+	DefaultInterval = time.Minute
+	// missedIntervals is how many intervals the peer can go unseen before it
+	// is considered missing.
+	missedIntervals = 3
+)
+
+// Probe contacts the daemon on the other side of the pair and returns its
+// node name.
+type Probe func(ctx context.Context) (string, error)
+
+// Reporter maintains the DpuNodePair of the local node. The host learns the
+// name of the DPU node from the certificate the DPU presents on the OPI
+// channel, and the DPU learns the name of the host node the same way when
+// the host calls it.
+type Reporter struct {
+	log       logr.Logger
+	client    client.Client
+	vspClient *vspclient.VspClient
+	key       types.NamespacedName
+	dpuMode   bool
+	interval  time.Duration
+
+	mu          sync.Mutex
+	identity    *pb.DpuIdentity
+	endpoint    string
+	peerNode    string
+	lastContact time.Time
+	peerErr     error
+}
+
+func WithInterval(interval time.Duration) func(*Reporter) {
+	return func(r *Reporter) {
+		r.interval = interval
+	}
+}
+
+func NewReporter(c client.Client, vspClient *vspclient.VspClient, namespace string, nodeName string, dpuMode bool, opts ...func(*Reporter)) *Reporter {
+	r := &Reporter{
+		log:       ctrl.Log.WithName("NodePair"),
+		client:    c,
+		vspClient: vspClient,
+		key:       types.NamespacedName{Namespace: namespace, Name: nodeName},
+		dpuMode:   dpuMode,
+		interval:  DefaultInterval,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// SetEndpoint records the address of the OPI channel served by the DPU.
+func (r *Reporter) SetEndpoint(addr string, port int32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.endpoint = fmt.Sprintf("%s:%d", addr, port)
+}
+
+// PeerSeen records that the daemon on nodeName authenticated on the channel.
+func (r *Reporter) PeerSeen(nodeName string) {
+	if nodeName == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.peerNode != nodeName {
+		r.log.Info("Paired with peer", "peer", nodeName)
+	}
+	r.peerNode = nodeName
+	r.lastContact = time.Now()
+	r.peerErr = nil
+}
+
+// PeerUnreachable records that the peer could not be contacted.
+func (r *Reporter) PeerUnreachable(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.peerErr = err
+}
+
+// Run probes the peer, if a probe is given, and updates the DpuNodePair
+// every interval until ctx is done.
+func (r *Reporter) Run(ctx context.Context, probe Probe) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		if probe != nil {
+			peerNode, err := probe(ctx)
+			if err != nil {
+				r.PeerUnreachable(err)
+			} else {
+				r.PeerSeen(peerNode)
+			}
+		}
+		if err := r.Sync(ctx); err != nil {
+			r.log.Error(err, "Failed to update DpuNodePair", "name", r.key.Name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Reporter) fetchIdentity(ctx context.Context) {
+	r.mu.Lock()
+	known := r.identity != nil
+	r.mu.Unlock()
+	if known {
+		return
+	}
+
+	c, err := r.vspClient.LifeCycle()
+	if err != nil {
+		r.log.Error(err, "Failed to get VSP client")
+		return
+	}
+	rpcCtx, cancel := r.vspClient.RPCContext(ctx)
+	defer cancel()
+	identity, err := c.GetIdentity(rpcCtx, &pb.Empty{})
+	if status.Code(err) == codes.Unimplemented {
+		// Not all VSPs can identify their card, pair without it
+		identity = &pb.DpuIdentity{}
+	} else if err != nil {
+		r.log.Error(err, "Failed to get DPU identity from VSP")
+		return
+	}
+
+	r.mu.Lock()
+	r.identity = identity
+	r.mu.Unlock()
+}
+
+// Sync writes the current state of the pair to the DpuNodePair of the local
+// node, creating it if needed.
+func (r *Reporter) Sync(ctx context.Context) error {
+	r.fetchIdentity(ctx)
+
+	r.mu.Lock()
+	spec := configv1.DpuNodePairSpec{}
+	if r.dpuMode {
+		spec.DpuNodeName = r.key.Name
+		spec.HostNodeName = r.peerNode
+	} else {
+		spec.HostNodeName = r.key.Name
+		spec.DpuNodeName = r.peerNode
+	}
+	identity := r.identity
+	endpoint := r.endpoint
+	lastContact := r.lastContact
+	peerErr := r.peerErr
+	r.mu.Unlock()
+
+	pair := &configv1.DpuNodePair{}
+	err := r.client.Get(ctx, r.key, pair)
+	if errors.IsNotFound(err) {
+		pair = &configv1.DpuNodePair{
+			ObjectMeta: metav1.ObjectMeta{Name: r.key.Name, Namespace: r.key.Namespace},
+			Spec:       spec,
+		}
+		if err := r.client.Create(ctx, pair); err != nil {
+			return fmt.Errorf("failed to create DpuNodePair: %v", err)
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to get DpuNodePair: %v", err)
+	} else if spec.HostNodeName != "" && spec.DpuNodeName != "" && pair.Spec != spec {
+		pair.Spec = spec
+		if err := r.client.Update(ctx, pair); err != nil {
+			return fmt.Errorf("failed to update DpuNodePair: %v", err)
+		}
+	}
+
+	newStatus := pair.Status.DeepCopy()
+	if identity != nil {
+		newStatus.SerialNumber = identity.SerialNumber
+		newStatus.PciAddress = identity.PciAddress
+	}
+	if endpoint != "" {
+		newStatus.Endpoint = endpoint
+	}
+	if !lastContact.IsZero() {
+		newStatus.LastPeerContact = &metav1.Time{Time: lastContact}
+	}
+	meta.SetStatusCondition(&newStatus.Conditions, peerCondition(lastContact, peerErr, missedIntervals*r.interval))
+
+	if equality.Semantic.DeepEqual(&pair.Status, newStatus) {
+		return nil
+	}
+	pair.Status = *newStatus
+	if err := r.client.Status().Update(ctx, pair); err != nil {
+		return fmt.Errorf("failed to update DpuNodePair status: %v", err)
+	}
+	return nil
+}
+
+func peerCondition(lastContact time.Time, peerErr error, timeout time.Duration) metav1.Condition {
+	condition := metav1.Condition{Type: configv1.DpuNodePairPeerReachable}
+	switch {
+	case peerErr != nil:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "ProbeFailed"
+		condition.Message = peerErr.Error()
+	case lastContact.IsZero():
+		condition.Status = metav1.ConditionUnknown
+		condition.Reason = "NotSeen"
+		condition.Message = "The peer has not contacted this node yet"
+	case time.Since(lastContact) > timeout:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = "PeerMissing"
+		condition.Message = fmt.Sprintf("The peer was last seen at %s", lastContact.Format(time.RFC3339))
+	default:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "PeerSeen"
+	}
+	return condition
+}
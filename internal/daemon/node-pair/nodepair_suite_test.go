@@ -0,0 +1,13 @@
+package nodepair
+
+import (
+	"testing"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+func TestNodePair(t *testing.T) {
+	o.RegisterFailHandler(g.Fail)
+	g.RunSpecs(t, "Node Pair Suite")
+}
@@ -0,0 +1,248 @@
+package nodepair
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	configv1 "github.com/openshift/dpu-operator/api/v1"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// fakeClient keeps DpuNodePairs in memory, the only kind the reporter uses
+type fakeClient struct {
+	client.Client
+	pairs map[types.NamespacedName]*configv1.DpuNodePair
+	// updates and statusUpdates count the writes of the spec and status
+	updates       int
+	statusUpdates int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{pairs: map[types.NamespacedName]*configv1.DpuNodePair{}}
+}
+
+func (c *fakeClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	pair, ok := c.pairs[key]
+	if !ok {
+		return errors.NewNotFound(configv1.GroupVersion.WithResource("dpunodepairs").GroupResource(), key.Name)
+	}
+	pair.DeepCopyInto(obj.(*configv1.DpuNodePair))
+	return nil
+}
+
+func (c *fakeClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	key := client.ObjectKeyFromObject(obj)
+	if _, ok := c.pairs[key]; ok {
+		return errors.NewAlreadyExists(configv1.GroupVersion.WithResource("dpunodepairs").GroupResource(), key.Name)
+	}
+	// The status is a subresource, create ignores it
+	pair := obj.(*configv1.DpuNodePair).DeepCopy()
+	pair.Status = configv1.DpuNodePairStatus{}
+	c.pairs[key] = pair
+	return nil
+}
+
+func (c *fakeClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	stored, ok := c.pairs[client.ObjectKeyFromObject(obj)]
+	if !ok {
+		return fmt.Errorf("no DpuNodePair %s", obj.GetName())
+	}
+	c.updates++
+	stored.Spec = obj.(*configv1.DpuNodePair).Spec
+	return nil
+}
+
+func (c *fakeClient) Status() client.SubResourceWriter {
+	return &fakeStatusWriter{c: c}
+}
+
+type fakeStatusWriter struct {
+	client.SubResourceWriter
+	c *fakeClient
+}
+
+func (w *fakeStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	stored, ok := w.c.pairs[client.ObjectKeyFromObject(obj)]
+	if !ok {
+		return fmt.Errorf("no DpuNodePair %s", obj.GetName())
+	}
+	w.c.statusUpdates++
+	obj.(*configv1.DpuNodePair).Status.DeepCopyInto(&stored.Status)
+	return nil
+}
+
+// identityVsp is a VSP that identifies its card, or fails with err
+type identityVsp struct {
+	pb.UnimplementedLifeCycleServiceServer
+	identity *pb.DpuIdentity
+	err      error
+}
+
+func (v *identityVsp) GetIdentity(ctx context.Context, in *pb.Empty) (*pb.DpuIdentity, error) {
+	if v.err != nil {
+		return nil, v.err
+	}
+	return v.identity, nil
+}
+
+var _ = g.Describe("Node pair reporter", func() {
+	var (
+		c         *fakeClient
+		vsp       *identityVsp
+		vspClient *vspclient.VspClient
+		key       = types.NamespacedName{Namespace: "openshift-dpu-operator", Name: "host-0"}
+	)
+
+	g.BeforeEach(func() {
+		c = newFakeClient()
+		vsp = &identityVsp{identity: &pb.DpuIdentity{SerialNumber: "SN0", PciAddress: "0000:3b:00.0"}}
+		pathManager := utils.NewPathManager(g.GinkgoT().TempDir())
+		socket := pathManager.VendorPluginSocket()
+		o.Expect(pathManager.EnsureSocketDirExists(socket)).To(o.Succeed())
+		listener, err := net.Listen("unix", socket)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		server := grpc.NewServer()
+		pb.RegisterLifeCycleServiceServer(server, vsp)
+		go server.Serve(listener)
+		vspClient = vspclient.NewVspClient(vspclient.WithPathManager(*pathManager), vspclient.WithRPCTimeout(5*time.Second))
+		g.DeferCleanup(func() {
+			vspClient.Close()
+			server.Stop()
+		})
+	})
+
+	pair := func() *configv1.DpuNodePair {
+		p, ok := c.pairs[key]
+		o.Expect(ok).To(o.BeTrue())
+		return p
+	}
+
+	reachable := func() *metav1.Condition {
+		return meta.FindStatusCondition(pair().Status.Conditions, configv1.DpuNodePairPeerReachable)
+	}
+
+	g.It("should create the pair of a host that has not seen its DPU yet", func() {
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(pair().Spec).To(o.Equal(configv1.DpuNodePairSpec{HostNodeName: "host-0"}))
+		o.Expect(pair().Status.SerialNumber).To(o.Equal("SN0"))
+		o.Expect(pair().Status.PciAddress).To(o.Equal("0000:3b:00.0"))
+		o.Expect(pair().Status.LastPeerContact).To(o.BeNil())
+		o.Expect(reachable().Status).To(o.Equal(metav1.ConditionUnknown))
+		o.Expect(reachable().Reason).To(o.Equal("NotSeen"))
+	})
+
+	g.It("should record the peer and the endpoint once the DPU is seen", func() {
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+
+		r.SetEndpoint("192.168.1.2", 50051)
+		r.PeerSeen("dpu-0")
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(pair().Spec).To(o.Equal(configv1.DpuNodePairSpec{HostNodeName: "host-0", DpuNodeName: "dpu-0"}))
+		o.Expect(pair().Status.Endpoint).To(o.Equal("192.168.1.2:50051"))
+		o.Expect(pair().Status.LastPeerContact).NotTo(o.BeNil())
+		o.Expect(reachable().Status).To(o.Equal(metav1.ConditionTrue))
+		o.Expect(c.updates).To(o.Equal(1))
+	})
+
+	g.It("should name the host as the peer of a DPU", func() {
+		r := NewReporter(c, vspClient, key.Namespace, "dpu-0", true)
+		r.PeerSeen("host-0")
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		dpuPair := c.pairs[types.NamespacedName{Namespace: key.Namespace, Name: "dpu-0"}]
+		o.Expect(dpuPair.Spec).To(o.Equal(configv1.DpuNodePairSpec{HostNodeName: "host-0", DpuNodeName: "dpu-0"}))
+	})
+
+	g.It("should report a peer that cannot be probed as unreachable", func() {
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		r.PeerSeen("dpu-0")
+		r.PeerUnreachable(fmt.Errorf("connection refused"))
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(reachable().Status).To(o.Equal(metav1.ConditionFalse))
+		o.Expect(reachable().Reason).To(o.Equal("ProbeFailed"))
+		o.Expect(reachable().Message).To(o.Equal("connection refused"))
+		// The pairing outlives the outage
+		o.Expect(pair().Spec.DpuNodeName).To(o.Equal("dpu-0"))
+	})
+
+	g.It("should not write an unchanged status again", func() {
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		r.PeerSeen("dpu-0")
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(c.statusUpdates).To(o.Equal(1))
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(c.statusUpdates).To(o.Equal(1))
+		o.Expect(c.updates).To(o.Equal(0))
+	})
+
+	g.It("should pair without the identity of a VSP that cannot identify its card", func() {
+		vsp.err = status.Error(codes.Unimplemented, "method GetIdentity not implemented")
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(pair().Status.SerialNumber).To(o.BeEmpty())
+		o.Expect(r.identity).NotTo(o.BeNil())
+	})
+
+	g.It("should ask a failing VSP for the identity again on the next sync", func() {
+		vsp.err = status.Error(codes.Internal, "no card")
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false)
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(pair().Status.SerialNumber).To(o.BeEmpty())
+
+		vsp.err = nil
+		o.Expect(r.Sync(context.Background())).To(o.Succeed())
+		o.Expect(pair().Status.SerialNumber).To(o.Equal("SN0"))
+	})
+
+	g.It("should sync until stopped when run", func() {
+		r := NewReporter(c, vspClient, key.Namespace, key.Name, false, WithInterval(10*time.Millisecond))
+		ctx, cancel := context.WithCancel(context.Background())
+		var probes atomic.Int32
+		done := make(chan struct{})
+		go func() {
+			r.Run(ctx, func(ctx context.Context) (string, error) {
+				probes.Add(1)
+				return "dpu-0", nil
+			})
+			close(done)
+		}()
+		o.Eventually(probes.Load).Should(o.BeNumerically(">=", 2))
+		cancel()
+		o.Eventually(done).Should(o.BeClosed())
+		o.Expect(pair().Spec.DpuNodeName).To(o.Equal("dpu-0"))
+	})
+})
+
+var _ = g.Describe("Node pair peer condition", func() {
+	timeout := 3 * time.Minute
+
+	g.DescribeTable("peerCondition",
+		func(lastContact time.Time, peerErr error, status metav1.ConditionStatus, reason string) {
+			condition := peerCondition(lastContact, peerErr, timeout)
+			o.Expect(condition.Type).To(o.Equal(configv1.DpuNodePairPeerReachable))
+			o.Expect(condition.Status).To(o.Equal(status))
+			o.Expect(condition.Reason).To(o.Equal(reason))
+		},
+		g.Entry("of a peer never seen", time.Time{}, nil, metav1.ConditionUnknown, "NotSeen"),
+		g.Entry("of a peer seen recently", time.Now().Add(-time.Minute), nil, metav1.ConditionTrue, "PeerSeen"),
+		g.Entry("of a peer not seen for longer than the timeout", time.Now().Add(-5*time.Minute), nil, metav1.ConditionFalse, "PeerMissing"),
+		g.Entry("of a peer that failed its probe", time.Now(), fmt.Errorf("connection refused"), metav1.ConditionFalse, "ProbeFailed"),
+		g.Entry("of a peer never seen that failed its probe", time.Time{}, fmt.Errorf("connection refused"), metav1.ConditionFalse, "ProbeFailed"),
+	)
+})
@@ -4,16 +4,15 @@ import (
 	"context"
 	"embed"
 	"fmt"
-	"net"
 	"os"
+	"sync"
 
 	"github.com/go-logr/logr"
 	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"github.com/openshift/dpu-operator/internal/utils"
 	"github.com/openshift/dpu-operator/pkgs/render"
 	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -49,24 +48,28 @@ func CreateVspImagesMap(fromEnv bool, logger logr.Logger) map[string]string {
 }
 
 type VendorPlugin interface {
-	Start() (string, int32, error)
+	Start(ctx context.Context) (string, int32, error)
 	Stop()
-	CreateBridgePort(bpr *opi.CreateBridgePortRequest) (*opi.BridgePort, error)
-	DeleteBridgePort(bpr *opi.DeleteBridgePortRequest) error
-	CreateNetworkFunction(input string, output string) error
-	DeleteNetworkFunction(input string, output string) error
+	Ready(ctx context.Context) error
+	GetHostCapabilities(ctx context.Context) (*pb.HostCapabilities, error)
+	GetLinkLimits(ctx context.Context) (*pb.LinkLimits, error)
+	CreateBridgePort(ctx context.Context, bpr *opi.CreateBridgePortRequest) (*opi.BridgePort, error)
+	DeleteBridgePort(ctx context.Context, bpr *opi.DeleteBridgePortRequest) error
+	GetBridgePort(ctx context.Context, bpr *opi.GetBridgePortRequest) (*opi.BridgePort, error)
+	SetBridgePortPolicy(ctx context.Context, policy *pb.BridgePortPolicy) error
+	CreateNetworkFunction(ctx context.Context, input string, output string) error
+	DeleteNetworkFunction(ctx context.Context, input string, output string) error
+	GetNetworkFunction(ctx context.Context, input string, output string) error
 }
 
 type GrpcPlugin struct {
 	log         logr.Logger
-	client      pb.LifeCycleServiceClient
 	k8sClient   client.Client
-	opiClient   opi.BridgePortServiceClient
-	nfclient    pb.NetworkFunctionServiceClient
+	vspClient   *vspclient.VspClient
 	dpuMode     bool
 	vsp         VspTemplateVars
-	conn        *grpc.ClientConn
 	pathManager utils.PathManager
+	reInitOnce  sync.Once
 }
 
 func NewVspTemplateVars() VspTemplateVars {
@@ -76,6 +79,7 @@ func NewVspTemplateVars() VspTemplateVars {
 		ImagePullPolicy:           "Always",
 		Command:                   "[ ]",
 		Args:                      "[ ]",
+		ConfigMap:                 "",
 	}
 }
 
@@ -85,6 +89,9 @@ type VspTemplateVars struct {
 	ImagePullPolicy           string
 	Command                   string
 	Args                      string
+	// ConfigMap is the optional ConfigMap mounted into the VSP at
+	// /etc/vsp-config, it does not have to exist
+	ConfigMap string
 }
 
 func (v VspTemplateVars) ToMap() map[string]string {
@@ -94,25 +101,54 @@ func (v VspTemplateVars) ToMap() map[string]string {
 		"ImagePullPolicy":           v.ImagePullPolicy,
 		"Command":                   v.Command,
 		"Args":                      v.Args,
+		"ConfigMap":                 v.ConfigMap,
 	}
 }
 
-func (g *GrpcPlugin) Start() (string, int32, error) {
-	err := g.ensureConnected()
-	if err != nil {
-		return "", 0, fmt.Errorf("Failed to ensure GRPC connection on grpcPlugin start: %v", err)
-	}
-	ipPort, err := g.client.Init(context.TODO(), &pb.InitRequest{DpuMode: g.dpuMode})
-
+func (g *GrpcPlugin) Start(ctx context.Context) (string, int32, error) {
+	ipPort, err := g.init(ctx)
 	if err != nil {
 		return "", 0, fmt.Errorf("Failed to start serving on grpcPlugin start: %v", err)
 	}
 
+	// A restarted VSP has lost all state, so initialize it again as soon as
+	// we are reconnected.
+	g.reInitOnce.Do(func() {
+		g.vspClient.OnReconnect(func(ctx context.Context) error {
+			newIpPort, err := g.init(ctx)
+			if err != nil {
+				return fmt.Errorf("Failed to re-initialize vendor plugin: %v", err)
+			}
+			if newIpPort.Ip != ipPort.Ip || newIpPort.Port != ipPort.Port {
+				err := fmt.Errorf("address changed from %s:%d to %s:%d", ipPort.Ip, ipPort.Port, newIpPort.Ip, newIpPort.Port)
+				g.log.Error(err, "Vendor plugin changed its address on re-initialization, the daemon keeps using the old one")
+			}
+			return nil
+		})
+	})
+
 	return ipPort.Ip, ipPort.Port, nil
 }
 
+func (g *GrpcPlugin) init(ctx context.Context) (*pb.IpPort, error) {
+	client, err := g.vspClient.LifeCycle()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to ensure GRPC connection on grpcPlugin start: %v", err)
+	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	ipPort, err := client.Init(ctx, &pb.InitRequest{DpuMode: g.dpuMode})
+	if err != nil {
+		return nil, err
+	}
+	if len(ipPort.Config) != 0 {
+		g.log.Info("Vendor plugin configuration", "config", ipPort.Config)
+	}
+	return ipPort, nil
+}
+
 func (g *GrpcPlugin) Stop() {
-	g.conn.Close()
+	g.vspClient.Close()
 }
 
 func WithPathManager(pathManager utils.PathManager) func(*GrpcPlugin) {
@@ -121,6 +157,14 @@ func WithPathManager(pathManager utils.PathManager) func(*GrpcPlugin) {
 	}
 }
 
+// WithVspClient makes the plugin share the connection to the VSP with other
+// components of the daemon.
+func WithVspClient(vspClient *vspclient.VspClient) func(*GrpcPlugin) {
+	return func(d *GrpcPlugin) {
+		d.vspClient = vspClient
+	}
+}
+
 func WithVsp(template_vars VspTemplateVars) func(*GrpcPlugin) {
 	return func(d *GrpcPlugin) {
 		d.vsp = template_vars
@@ -155,71 +199,131 @@ func NewGrpcPlugin(dpuMode bool, client client.Client, opts ...func(*GrpcPlugin)
 		opt(gp)
 	}
 
+	if gp.vspClient == nil {
+		gp.vspClient = vspclient.NewVspClient(vspclient.WithPathManager(gp.pathManager))
+	}
+
 	gp.deployVsp()
 
 	return gp
 }
 
-func (g *GrpcPlugin) ensureConnected() error {
-	if g.client != nil {
-		return nil
+// Ready checks that the VSP answers requests.
+func (g *GrpcPlugin) Ready(ctx context.Context) error {
+	client, err := g.vspClient.Device()
+	if err != nil {
+		return fmt.Errorf("Ready failed to ensure GRPC connection: %v", err)
 	}
-	dialOptions := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
-			return net.Dial("unix", addr)
-		}),
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	if _, err := client.GetDevices(ctx, &pb.Empty{}); err != nil {
+		return fmt.Errorf("VSP is not ready: %v", err)
 	}
+	return nil
+}
 
-	conn, err := grpc.DialContext(context.Background(), g.pathManager.VendorPluginSocket(), dialOptions...)
-
+// GetHostCapabilities asks the VSP which VF settings the host may change
+// through the PF.
+func (g *GrpcPlugin) GetHostCapabilities(ctx context.Context) (*pb.HostCapabilities, error) {
+	client, err := g.vspClient.LifeCycle()
 	if err != nil {
-		g.log.Error(err, "Failed to connect to vendor plugin")
-		return err
+		return nil, fmt.Errorf("GetHostCapabilities failed to ensure GRPC connection: %v", err)
 	}
-	g.conn = conn
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	return client.GetHostCapabilities(ctx, &pb.Empty{})
+}
 
-	g.client = pb.NewLifeCycleServiceClient(conn)
-	g.nfclient = pb.NewNetworkFunctionServiceClient(conn)
-	g.opiClient = opi.NewBridgePortServiceClient(conn)
-	return nil
+// GetLinkLimits asks the VSP for the limits of the data path, which the link
+// attributes of pod interfaces must not exceed.
+func (g *GrpcPlugin) GetLinkLimits(ctx context.Context) (*pb.LinkLimits, error) {
+	client, err := g.vspClient.LifeCycle()
+	if err != nil {
+		return nil, fmt.Errorf("GetLinkLimits failed to ensure GRPC connection: %v", err)
+	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	return client.GetLinkLimits(ctx, &pb.Empty{})
 }
 
-func (g *GrpcPlugin) CreateBridgePort(createRequest *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
-	err := g.ensureConnected()
+func (g *GrpcPlugin) CreateBridgePort(ctx context.Context, createRequest *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
+	client, err := g.vspClient.BridgePort()
 	if err != nil {
 		return nil, fmt.Errorf("CreateBridgePort failed to ensure GRPC connection: %v", err)
 	}
-	return g.opiClient.CreateBridgePort(context.TODO(), createRequest)
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	return client.CreateBridgePort(ctx, createRequest)
 }
 
-func (g *GrpcPlugin) DeleteBridgePort(deleteRequest *opi.DeleteBridgePortRequest) error {
-	err := g.ensureConnected()
+func (g *GrpcPlugin) DeleteBridgePort(ctx context.Context, deleteRequest *opi.DeleteBridgePortRequest) error {
+	client, err := g.vspClient.BridgePort()
 	if err != nil {
 		return fmt.Errorf("DeleteBridgePort failed to ensure GRPC connection: %v", err)
 	}
-	_, err = g.opiClient.DeleteBridgePort(context.TODO(), deleteRequest)
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	_, err = client.DeleteBridgePort(ctx, deleteRequest)
 	return err
 }
 
-func (g *GrpcPlugin) CreateNetworkFunction(input string, output string) error {
+func (g *GrpcPlugin) GetBridgePort(ctx context.Context, getRequest *opi.GetBridgePortRequest) (*opi.BridgePort, error) {
+	client, err := g.vspClient.BridgePort()
+	if err != nil {
+		return nil, fmt.Errorf("GetBridgePort failed to ensure GRPC connection: %v", err)
+	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	return client.GetBridgePort(ctx, getRequest)
+}
+
+func (g *GrpcPlugin) SetBridgePortPolicy(ctx context.Context, policy *pb.BridgePortPolicy) error {
+	client, err := g.vspClient.BridgePortPolicy()
+	if err != nil {
+		return fmt.Errorf("SetBridgePortPolicy failed to ensure GRPC connection: %v", err)
+	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	_, err = client.SetBridgePortPolicy(ctx, policy)
+	return err
+}
+
+func (g *GrpcPlugin) CreateNetworkFunction(ctx context.Context, input string, output string) error {
 	g.log.Info("CreateNetworkFunction", "input", input, "output", output)
-	err := g.ensureConnected()
+	client, err := g.vspClient.NetworkFunction()
 	if err != nil {
 		return fmt.Errorf("CreateNetworkFunction failed to ensure GRPC connection: %v", err)
 	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
 	req := pb.NFRequest{Input: input, Output: output}
-	_, err = g.nfclient.CreateNetworkFunction(context.TODO(), &req)
+	_, err = client.CreateNetworkFunction(ctx, &req)
 	return err
 }
 
-func (g *GrpcPlugin) DeleteNetworkFunction(input string, output string) error {
+func (g *GrpcPlugin) DeleteNetworkFunction(ctx context.Context, input string, output string) error {
 	g.log.Info("DeleteNetworkFunction", "input", input, "output", output)
-	err := g.ensureConnected()
+	client, err := g.vspClient.NetworkFunction()
 	if err != nil {
 		return fmt.Errorf("DeleteNetworkFunction failed to ensure GRPC connection: %v", err)
 	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
+	req := pb.NFRequest{Input: input, Output: output}
+	_, err = client.DeleteNetworkFunction(ctx, &req)
+	return err
+}
+
+// GetNetworkFunction confirms that the VSP has the network function between
+// input and output, it fails with NotFound if the VSP does not have it.
+func (g *GrpcPlugin) GetNetworkFunction(ctx context.Context, input string, output string) error {
+	client, err := g.vspClient.NetworkFunction()
+	if err != nil {
+		return fmt.Errorf("GetNetworkFunction failed to ensure GRPC connection: %v", err)
+	}
+	ctx, cancel := g.vspClient.RPCContext(ctx)
+	defer cancel()
 	req := pb.NFRequest{Input: input, Output: output}
-	_, err = g.nfclient.DeleteNetworkFunction(context.TODO(), &req)
+	_, err = client.GetNetworkFunction(ctx, &req)
 	return err
 }
@@ -33,7 +33,10 @@ func networkFunctionPod(name string, image string) *corev1.Pod {
 			Name:      name,
 			Namespace: "openshift-dpu-operator",
 			Annotations: map[string]string{
-				"k8s.v1.cni.cncf.io/networks": "dpunfcni-conf, dpunfcni-conf",
+				"k8s.v1.cni.cncf.io/networks": `[
+					{"name": "dpunfcni-conf", "interface": "net1", "cni-args": {"nfRole": "ingress"}},
+					{"name": "dpunfcni-conf", "interface": "net2", "cni-args": {"nfRole": "egress"}}
+				]`,
 			},
 		},
 		Spec: corev1.PodSpec{
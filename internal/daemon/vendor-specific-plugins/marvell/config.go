@@ -0,0 +1,154 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"k8s.io/klog/v2"
+)
+
+// ConfigDir is where the VSP daemonset mounts the ConfigMap of the VSP
+const ConfigDir string = "/etc/vsp-config"
+
+// mrvlConfig is the runtime configuration of the Marvell VSP
+type mrvlConfig struct {
+	// DataPlaneType is the data plane of the bridge ports, debug or ovs
+	DataPlaneType string
+	// PortType is the type of the network function ports, veth or hwlbk
+	PortType      string
+	NoOfPortPairs int
+	NumPFs        int
+	PFID          int
+	IsDPDK        bool
+	// SFBase is the VF whose SDP function is the one of the host sub
+	// function with sfnum 0, sub functions are disabled if it is negative
+	SFBase     int
+	BridgeName string
+	// IPv6AddrDpu and IPv6AddrHost are the link-local addresses of the
+	// comm channel on the DPU and on the host
+	IPv6AddrDpu  string
+	IPv6AddrHost string
+}
+
+// defaultConfig returns the configuration of the VSP without flags nor ConfigMap
+func defaultConfig() mrvlConfig {
+	return mrvlConfig{
+		DataPlaneType: "debug",
+		PortType:      "veth",
+		NoOfPortPairs: 2,
+		NumPFs:        1,
+		PFID:          0,
+		IsDPDK:        false,
+		SFBase:        -1,
+		BridgeName:    "br-mrv0",
+		IPv6AddrDpu:   "fe80::1",
+		IPv6AddrHost:  "fe80::2",
+	}
+}
+
+// bindFlags defines a flag for every setting of the configuration, the keys
+// of the ConfigMap have the same names
+func (c *mrvlConfig) bindFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.DataPlaneType, "dataplane", c.DataPlaneType, "Data plane of the bridge ports, can be either debug or ovs")
+	fs.StringVar(&c.PortType, "port-type", c.PortType, "Type of the network function ports on the DPU, can be either veth or hwlbk")
+	fs.IntVar(&c.NoOfPortPairs, "port-pairs", c.NoOfPortPairs, "Number of network function port pairs on the DPU")
+	fs.IntVar(&c.NumPFs, "num-pfs", c.NumPFs, "Number of PFs of the card on the host")
+	fs.IntVar(&c.PFID, "pf-id", c.PFID, "ID of the PF whose VFs are connected to the bridge")
+	fs.BoolVar(&c.IsDPDK, "dpdk", c.IsDPDK, "Add the VFs to the data plane as DPDK ports")
+	fs.IntVar(&c.SFBase, "sf-base", c.SFBase, "VF whose SDP function backs the host sub function with sfnum 0, -1 disables sub functions")
+	fs.StringVar(&c.BridgeName, "bridge", c.BridgeName, "Name of the data plane bridge")
+	fs.StringVar(&c.IPv6AddrDpu, "ipv6-dpu", c.IPv6AddrDpu, "IPv6 link-local address of the comm channel on the DPU")
+	fs.StringVar(&c.IPv6AddrHost, "ipv6-host", c.IPv6AddrHost, "IPv6 link-local address of the comm channel on the host")
+}
+
+// loadDir sets the settings whose flags in fs were not given on the command
+// line from the files in dir, named like the flags. A missing dir is not an
+// error, the ConfigMap is optional.
+func (c *mrvlConfig) loadDir(fs *flag.FlagSet, dir string) error {
+	settings := c.toMap()
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	var errs []error
+	fs.VisitAll(func(f *flag.Flag) {
+		if _, ok := settings[f.Name]; !ok || set[f.Name] {
+			return
+		}
+		value, err := os.ReadFile(filepath.Join(dir, f.Name))
+		if os.IsNotExist(err) {
+			return
+		}
+		if err == nil {
+			err = fs.Set(f.Name, strings.TrimSpace(string(value)))
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid %s in %s: %v", f.Name, dir, err))
+			return
+		}
+		klog.Infof("Using %s=%s from %s", f.Name, f.Value, dir)
+	})
+	return errors.Join(errs...)
+}
+
+// validate checks that the configuration can be used
+func (c *mrvlConfig) validate() error {
+	var errs []error
+	switch c.DataPlaneType {
+	case "debug", "ovs":
+	default:
+		errs = append(errs, fmt.Errorf("invalid data plane %q, must be debug or ovs", c.DataPlaneType))
+	}
+	switch c.PortType {
+	case "veth", "hwlbk":
+	default:
+		errs = append(errs, fmt.Errorf("invalid port type %q, must be veth or hwlbk", c.PortType))
+	}
+	if c.NoOfPortPairs < 1 {
+		errs = append(errs, fmt.Errorf("invalid number of port pairs %d, must be at least 1", c.NoOfPortPairs))
+	}
+	if c.NumPFs < 1 {
+		errs = append(errs, fmt.Errorf("invalid number of PFs %d, must be at least 1", c.NumPFs))
+	}
+	if c.PFID < 0 || c.PFID >= c.NumPFs {
+		errs = append(errs, fmt.Errorf("invalid PF ID %d, must be less than the number of PFs %d", c.PFID, c.NumPFs))
+	}
+	if c.SFBase < -1 {
+		errs = append(errs, fmt.Errorf("invalid sf-base %d, must be a VF ID or -1", c.SFBase))
+	}
+	// Bridge names are netdev names for the ovs data plane
+	if c.BridgeName == "" || len(c.BridgeName) > 15 || strings.ContainsAny(c.BridgeName, "/ ") {
+		errs = append(errs, fmt.Errorf("invalid bridge name %q", c.BridgeName))
+	}
+	for _, addr := range []string{c.IPv6AddrDpu, c.IPv6AddrHost} {
+		ip := net.ParseIP(addr)
+		if ip == nil || ip.To4() != nil || !ip.IsLinkLocalUnicast() {
+			errs = append(errs, fmt.Errorf("invalid comm channel address %q, must be an IPv6 link-local address", addr))
+		}
+	}
+	if c.IPv6AddrDpu == c.IPv6AddrHost {
+		errs = append(errs, fmt.Errorf("the comm channel addresses of the DPU and the host are both %s", c.IPv6AddrDpu))
+	}
+	return errors.Join(errs...)
+}
+
+// toMap returns the configuration by the names of its flags
+func (c *mrvlConfig) toMap() map[string]string {
+	return map[string]string{
+		"dataplane":  c.DataPlaneType,
+		"port-type":  c.PortType,
+		"port-pairs": strconv.Itoa(c.NoOfPortPairs),
+		"num-pfs":    strconv.Itoa(c.NumPFs),
+		"pf-id":      strconv.Itoa(c.PFID),
+		"dpdk":       strconv.FormatBool(c.IsDPDK),
+		"sf-base":    strconv.Itoa(c.SFBase),
+		"bridge":     c.BridgeName,
+		"ipv6-dpu":   c.IPv6AddrDpu,
+		"ipv6-host":  c.IPv6AddrHost,
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("Marvell VSP configuration", func() {
+	load := func(args []string, files map[string]string) (mrvlConfig, error) {
+		dir := g.GinkgoT().TempDir()
+		for name, value := range files {
+			o.Expect(os.WriteFile(filepath.Join(dir, name), []byte(value+"\n"), 0644)).To(o.Succeed())
+		}
+		config := defaultConfig()
+		fs := flag.NewFlagSet("vsp", flag.ContinueOnError)
+		config.bindFlags(fs)
+		o.Expect(fs.Parse(args)).To(o.Succeed())
+		if err := config.loadDir(fs, dir); err != nil {
+			return config, err
+		}
+		return config, config.validate()
+	}
+
+	g.It("should use the defaults without flags nor ConfigMap", func() {
+		config, err := load(nil, nil)
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(config).To(o.Equal(defaultConfig()))
+	})
+
+	g.It("should prefer the flags over the ConfigMap", func() {
+		config, err := load([]string{"--port-type=hwlbk"}, map[string]string{
+			"port-type":  "veth",
+			"dataplane":  "ovs",
+			"port-pairs": "4",
+		})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(config.PortType).To(o.Equal("hwlbk"))
+		o.Expect(config.DataPlaneType).To(o.Equal("ovs"))
+		o.Expect(config.NoOfPortPairs).To(o.Equal(4))
+		o.Expect(config.toMap()).To(o.HaveKeyWithValue("port-pairs", "4"))
+	})
+
+	g.It("should reject a ConfigMap value of the wrong type", func() {
+		_, err := load(nil, map[string]string{"dpdk": "maybe"})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("invalid dpdk")))
+	})
+
+	g.DescribeTable("should reject an invalid configuration",
+		func(args []string, msg string) {
+			_, err := load(args, nil)
+			o.Expect(err).To(o.MatchError(o.ContainSubstring(msg)))
+		},
+		g.Entry("data plane", []string{"--dataplane=dpdk"}, "invalid data plane"),
+		g.Entry("port type", []string{"--port-type=sdp"}, "invalid port type"),
+		g.Entry("port pairs", []string{"--port-pairs=0"}, "invalid number of port pairs"),
+		g.Entry("PF ID", []string{"--num-pfs=2", "--pf-id=2"}, "invalid PF ID"),
+		g.Entry("sf-base", []string{"--sf-base=-2"}, "invalid sf-base"),
+		g.Entry("bridge name", []string{"--bridge=a-very-long-bridge"}, "invalid bridge name"),
+		g.Entry("global comm channel address", []string{"--ipv6-dpu=2001:db8::1"}, "must be an IPv6 link-local address"),
+		g.Entry("same comm channel addresses", []string{"--ipv6-host=fe80::1"}, "are both fe80::1"),
+	)
+})
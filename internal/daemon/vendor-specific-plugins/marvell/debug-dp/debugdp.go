@@ -1,31 +1,68 @@
 package DebugDP
 
 import (
+	"fmt"
+	"strings"
+	"sync"
+
 	"github.com/go-logr/logr"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 type DebugDP struct {
 	log logr.Logger
+	// ports records the ports added to each bridge so that reads reflect
+	// them like a real data plane would
+	mu       sync.Mutex
+	ports    map[string][]string
+	policies map[string]string
 }
 
 func NewDebugDP() *DebugDP {
 	return &DebugDP{
-		log: ctrl.Log.WithName("MarvellVSP:DebugDP"),
+		log:      ctrl.Log.WithName("MarvellVSP:DebugDP"),
+		ports:    map[string][]string{},
+		policies: map[string]string{},
 	}
 }
 
 func (debugDP *DebugDP) AddPortToDataPlane(bridgeName string, portName string, vfPCIAddres string, isDPDK bool) error {
 	debugDP.log.Info("AddPortToBridge ", "bridgeName", bridgeName, "PortName", portName)
+	debugDP.mu.Lock()
+	defer debugDP.mu.Unlock()
+	for _, port := range debugDP.ports[bridgeName] {
+		if port == portName {
+			return nil
+		}
+	}
+	debugDP.ports[bridgeName] = append(debugDP.ports[bridgeName], portName)
 	return nil
 }
 
 func (debugDP *DebugDP) DeletePortFromDataPlane(bridgeName string, portName string) error {
 	debugDP.log.Info("DeletePortFromBridge ", "bridgeName", bridgeName, "PortName", portName)
+	debugDP.mu.Lock()
+	defer debugDP.mu.Unlock()
+	var remaining []string
+	for _, port := range debugDP.ports[bridgeName] {
+		if port != portName {
+			remaining = append(remaining, port)
+		}
+	}
+	debugDP.ports[bridgeName] = remaining
+	delete(debugDP.policies, portName)
 	return nil
 
 }
 
+func (debugDP *DebugDP) SetPortPolicy(bridgeName string, portName string, mac string, maxTxRate uint32, spoofCheck bool) error {
+	debugDP.log.Info("SetPortPolicy", "bridgeName", bridgeName, "PortName", portName, "mac", mac, "maxTxRate", maxTxRate, "spoofCheck", spoofCheck)
+	debugDP.mu.Lock()
+	defer debugDP.mu.Unlock()
+	debugDP.policies[portName] = fmt.Sprintf("mac=%s,maxTxRate=%d,spoofCheck=%t", mac, maxTxRate, spoofCheck)
+	return nil
+}
+
 func (debugDP *DebugDP) InitDataPlane(bridgeName string) error {
 	debugDP.log.Info("Init Data plane", "bridgeName", bridgeName)
 	return nil
@@ -33,9 +70,14 @@ func (debugDP *DebugDP) InitDataPlane(bridgeName string) error {
 
 func (debugDP *DebugDP) ReadAllPortFromDataPlane(bridgeName string) (string, error) {
 	debugDP.log.Info("ReadAllPortFromBridge ", "bridgeName", bridgeName)
-	return "", nil
+	debugDP.mu.Lock()
+	defer debugDP.mu.Unlock()
+	return strings.Join(debugDP.ports[bridgeName], "\n"), nil
 }
 func (debugDP *DebugDP) DeleteDataplane(bridgeName string) error {
 	debugDP.log.Info("DeleteDataplane", "bridgeName", bridgeName)
+	debugDP.mu.Lock()
+	defer debugDP.mu.Unlock()
+	delete(debugDP.ports, bridgeName)
 	return nil
 }
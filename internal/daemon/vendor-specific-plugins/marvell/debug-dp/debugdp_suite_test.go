@@ -0,0 +1,13 @@
+package DebugDP
+
+import (
+	"testing"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+func TestDebugDP(t *testing.T) {
+	o.RegisterFailHandler(g.Fail)
+	g.RunSpecs(t, "Marvell Debug Data Plane Suite")
+}
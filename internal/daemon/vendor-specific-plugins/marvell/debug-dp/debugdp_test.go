@@ -0,0 +1,22 @@
+package DebugDP
+
+import (
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("DebugDP port policy", func() {
+	g.It("should record the policy of a port until it is deleted", func() {
+		dp := NewDebugDP()
+		o.Expect(dp.AddPortToDataPlane("br0", "sdp3", "", false)).To(o.Succeed())
+		o.Expect(dp.SetPortPolicy("br0", "sdp3", "00:11:22:33:44:55", 100, true)).To(o.Succeed())
+		o.Expect(dp.policies).To(o.Equal(map[string]string{"sdp3": "mac=00:11:22:33:44:55,maxTxRate=100,spoofCheck=true"}))
+
+		o.Expect(dp.SetPortPolicy("br0", "sdp3", "00:11:22:33:44:55", 0, false)).To(o.Succeed())
+		o.Expect(dp.policies).To(o.HaveKeyWithValue("sdp3", "mac=00:11:22:33:44:55,maxTxRate=0,spoofCheck=false"))
+
+		o.Expect(dp.DeletePortFromDataPlane("br0", "sdp3")).To(o.Succeed())
+		o.Expect(dp.policies).To(o.BeEmpty())
+		o.Expect(dp.ReadAllPortFromDataPlane("br0")).To(o.BeEmpty())
+	})
+})
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/vishvananda/netlink"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var _ = g.Describe("Marvell VSP device health", func() {
+	var links map[string]*netlink.Device
+
+	g.BeforeEach(func() {
+		links = map[string]*netlink.Device{}
+		orig := linkByName
+		linkByName = func(name string) (netlink.Link, error) {
+			link, ok := links[name]
+			if !ok {
+				return nil, fmt.Errorf("link %s not found", name)
+			}
+			return link, nil
+		}
+		g.DeferCleanup(func() { linkByName = orig })
+	})
+
+	addLink := func(name string, up bool, state netlink.LinkOperState) {
+		link := &netlink.Device{LinkAttrs: netlink.LinkAttrs{Name: name, OperState: state}}
+		if up {
+			link.Flags = net.FlagUp
+		}
+		links[name] = link
+	}
+
+	type healthCase struct {
+		portType string
+		nfUp     bool
+		nfState  netlink.LinkOperState
+		// noDP leaves the DP side of the pair out
+		noDP    bool
+		dpUp    bool
+		dpState netlink.LinkOperState
+		health  string
+	}
+
+	g.DescribeTable("GetDeviceHealth",
+		func(c healthCase) {
+			addLink("nf0", c.nfUp, c.nfState)
+			if !c.noDP {
+				addLink("dp0", c.dpUp, c.dpState)
+			}
+			vsp := &mrvlVspServer{config: defaultConfig()}
+			vsp.config.PortType = c.portType
+			device := mrvlDeviceInfo{nfInterfaceName: "nf0", dpInterfaceName: "dp0"}
+			o.Expect(vsp.GetDeviceHealth(device)).To(o.Equal(c.health))
+		},
+		g.Entry("veth that is up", healthCase{
+			portType: "veth", nfUp: true, nfState: netlink.OperUp, noDP: true, health: "Healthy",
+		}),
+		g.Entry("veth that is down", healthCase{
+			portType: "veth", nfState: netlink.OperDown, noDP: true, health: "Unhealthy",
+		}),
+		g.Entry("hwlbk pair that is up with carrier", healthCase{
+			portType: "hwlbk", nfUp: true, nfState: netlink.OperUp, dpUp: true, dpState: netlink.OperUp, health: "Healthy",
+		}),
+		g.Entry("hwlbk pair whose driver does not report the state", healthCase{
+			portType: "hwlbk", nfUp: true, nfState: netlink.OperUnknown, dpUp: true, dpState: netlink.OperUnknown, health: "Healthy",
+		}),
+		g.Entry("hwlbk pair with the NF side down", healthCase{
+			portType: "hwlbk", nfState: netlink.OperDown, dpUp: true, dpState: netlink.OperUp, health: "Unhealthy",
+		}),
+		g.Entry("hwlbk pair with the DP side down", healthCase{
+			portType: "hwlbk", nfUp: true, nfState: netlink.OperUp, dpState: netlink.OperDown, health: "Unhealthy",
+		}),
+		g.Entry("hwlbk pair without carrier", healthCase{
+			portType: "hwlbk", nfUp: true, nfState: netlink.OperLowerLayerDown, dpUp: true, dpState: netlink.OperUp, health: "Unhealthy",
+		}),
+		g.Entry("hwlbk pair whose DP side is gone", healthCase{
+			portType: "hwlbk", nfUp: true, nfState: netlink.OperUp, noDP: true, health: "Unhealthy",
+		}),
+		g.Entry("unknown port type", healthCase{
+			portType: "sdp", nfUp: true, nfState: netlink.OperUp, noDP: true, health: "Unhealthy",
+		}),
+	)
+
+	g.DescribeTable("GetNetworkFunction",
+		func(input string, egressUp bool, code codes.Code) {
+			addLink("nf0", true, netlink.OperUp)
+			addLink("nf1", egressUp, netlink.OperUp)
+			vsp := &mrvlVspServer{config: defaultConfig(), deviceStore: map[string]mrvlDeviceInfo{
+				"02:00:00:00:00:00": {nfInterfaceName: "nf0", dpInterfaceName: "dp0"},
+				"02:00:00:00:00:01": {nfInterfaceName: "nf1", dpInterfaceName: "dp1"},
+			}}
+			_, err := vsp.GetNetworkFunction(context.Background(), &pb.NFRequest{Input: input, Output: "02:00:00:00:00:01"})
+			o.Expect(status.Code(err)).To(o.Equal(code))
+		},
+		g.Entry("of healthy ports", "02:00:00:00:00:00", true, codes.OK),
+		g.Entry("of an unknown port", "02:00:00:00:00:09", true, codes.NotFound),
+		g.Entry("of an unhealthy port", "02:00:00:00:00:00", false, codes.Unavailable),
+	)
+})
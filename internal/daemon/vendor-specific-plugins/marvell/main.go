@@ -22,6 +22,8 @@ import (
 	"github.com/vishvananda/netlink"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	"k8s.io/klog/v2"
 	ctrl "sigs.k8s.io/controller-runtime"
@@ -29,26 +31,19 @@ import (
 )
 
 const (
-	SysBusPci     string = "/sys/bus/pci/devices"
-	VendorID      string = "177d"
-	DPUdeviceID   string = "a0f7"
-	HostDeviceID  string = "b900"
-	DefaultPort   int32  = 8085
-	Version       string = "0.0.1"
-	PortType      string = "veth"
-	NoOfPortPairs int    = 2
-	IPv6AddrDpu   string = "fe80::1"
-	IPv6AddrHost  string = "fe80::2"
-	DataPlaneType string = "debug"
-	NumPFs        int    = 1
-	PFID          int    = 0
-	isDPDK        bool   = false
+	SysBusPci    string = "/sys/bus/pci/devices"
+	VendorID     string = "177d"
+	DPUdeviceID  string = "a0f7"
+	HostDeviceID string = "b900"
+	DefaultPort  int32  = 8085
+	Version      string = "0.0.1"
 )
 
 // multiple dataplane can be added using mrvldp interface functions
 type mrvldp interface {
 	AddPortToDataPlane(bridgeName string, portName string, vfPCIAddres string, isDPDK bool) error
 	DeletePortFromDataPlane(bridgeName string, portName string) error
+	SetPortPolicy(bridgeName string, portName string, mac string, maxTxRate uint32, spoofCheck bool) error
 	InitDataPlane(bridgeName string) error
 	ReadAllPortFromDataPlane(bridgeName string) (string, error)
 	DeleteDataplane(bridgeName string) error
@@ -58,25 +53,26 @@ type mrvlDeviceInfo struct {
 	dpInterfaceName string
 	dpMAC           string
 	health          string
+	// nfPciAddress is the PCI address of the NF side of a hwlbk pair
+	nfPciAddress string
 }
 type mrvlVspServer struct {
 	pb.UnimplementedLifeCycleServiceServer
 	pb.UnimplementedNetworkFunctionServiceServer
 	pb.UnimplementedDeviceServiceServer
+	pb.UnimplementedBridgePortPolicyServiceServer
 	opi.UnimplementedBridgePortServiceServer
-	log           logr.Logger
-	grpcServer    *grpc.Server
-	wg            sync.WaitGroup
-	done          chan error
-	startedWg     sync.WaitGroup
-	pathManager   utils.PathManager
-	version       string
-	isDPUMode     bool
-	deviceStore   map[string]mrvlDeviceInfo
-	portType      string
-	noOfPortPairs int
-	bridgeName    string
-	mrvlDP        mrvldp
+	log         logr.Logger
+	grpcServer  *grpc.Server
+	wg          sync.WaitGroup
+	done        chan error
+	startedWg   sync.WaitGroup
+	pathManager utils.PathManager
+	version     string
+	isDPUMode   bool
+	deviceStore map[string]mrvlDeviceInfo
+	mrvlDP      mrvldp
+	config      mrvlConfig
 }
 
 // createVethPair function to create a veth pair with the given index and InterfaceInfo
@@ -118,10 +114,76 @@ func (vsp *mrvlVspServer) createVethPair(index int) error {
 	return nil
 }
 
+// createHwLBK function to set up the pairs of loopback VFs whose traffic the
+// hardware loops back to each other, the NF side is given to the network
+// function pods and the DP side stays on the DPU for the data plane
 func (vsp *mrvlVspServer) createHwLBK() error {
-	//TODO: Implement HW Loopback
-	klog.Infof("currently only veth pairs are supported")
-	return errors.New("currently only veth pairs are supported")
+	pairs, err := mrvlutils.GetHwLbkPairs(vsp.config.NoOfPortPairs)
+	if err != nil {
+		return err
+	}
+	for _, pair := range pairs {
+		nfPciAddress, dpPciAddress := pair[0], pair[1]
+		nfInterfaceName, err := mrvlutils.GetNameByPCI(nfPciAddress)
+		if err != nil {
+			return fmt.Errorf("failed to find the netdev of loopback VF %s: %v", nfPciAddress, err)
+		}
+		dpInterfaceName, err := mrvlutils.GetNameByPCI(dpPciAddress)
+		if err != nil {
+			return fmt.Errorf("failed to find the netdev of loopback VF %s: %v", dpPciAddress, err)
+		}
+		nfLink, err := netlink.LinkByName(nfInterfaceName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(nfLink); err != nil {
+			return err
+		}
+		dpLink, err := netlink.LinkByName(dpInterfaceName)
+		if err != nil {
+			return err
+		}
+		if err := netlink.LinkSetUp(dpLink); err != nil {
+			return err
+		}
+		vsp.deviceStore[nfLink.Attrs().HardwareAddr.String()] = mrvlDeviceInfo{
+			nfInterfaceName: nfInterfaceName,
+			dpInterfaceName: dpInterfaceName,
+			dpMAC:           dpLink.Attrs().HardwareAddr.String(),
+			health:          "Healthy",
+			nfPciAddress:    nfPciAddress,
+		}
+	}
+	return nil
+}
+
+// CleanHwLBK function to set the loopback VFs down, they belong to the
+// hardware and are not deleted
+func (vsp *mrvlVspServer) CleanHwLBK() error {
+	var errResult error
+	deviceStore := vsp.deviceStore
+	vsp.deviceStore = nil
+	for _, mrvlDeviceInfo := range deviceStore {
+		for _, name := range []string{mrvlDeviceInfo.nfInterfaceName, mrvlDeviceInfo.dpInterfaceName} {
+			link, err := netlink.LinkByName(name)
+			if err == nil {
+				err = netlink.LinkSetDown(link)
+			}
+			if err != nil {
+				klog.Errorf("Error occurred in setting loopback VF %s down: %v", name, err)
+				errResult = errors.Join(errResult, err)
+			}
+		}
+	}
+	return errResult
+}
+
+// cleanPortPairs function to clean the port pairs of the port type
+func (vsp *mrvlVspServer) cleanPortPairs() error {
+	if vsp.config.PortType == "hwlbk" {
+		return vsp.CleanHwLBK()
+	}
+	return vsp.CleanVethPairs()
 }
 
 // CleanVethPairs function to clean all the veth pairs created
@@ -150,14 +212,10 @@ func (vsp *mrvlVspServer) CleanVethPairs() error {
 
 // ConfigureNetworkInterface function to configure the network interface based on the config file
 func (vsp *mrvlVspServer) ConfigureNetworkInterface() error {
-	// TODO: Replace PortTyp and NoOfPortPairs with the values from the CR/ConfigMap
-	vsp.portType = PortType
-	vsp.noOfPortPairs = NoOfPortPairs
-
-	switch vsp.portType {
+	switch vsp.config.PortType {
 	case "veth":
-		klog.Infof("Creating Veth Pairs: %d", vsp.noOfPortPairs)
-		for i := 0; i < vsp.noOfPortPairs; i++ {
+		klog.Infof("Creating Veth Pairs: %d", vsp.config.NoOfPortPairs)
+		for i := 0; i < vsp.config.NoOfPortPairs; i++ {
 			err := vsp.createVethPair(i)
 			if err != nil {
 				klog.Errorf("Error occurred in creating Veth Pair: %v", err)
@@ -166,9 +224,11 @@ func (vsp *mrvlVspServer) ConfigureNetworkInterface() error {
 			}
 		}
 	case "hwlbk":
+		klog.Infof("Setting up HW loopback pairs: %d", vsp.config.NoOfPortPairs)
 		err := vsp.createHwLBK()
 		if err != nil {
 			klog.Errorf("Error occurred in creating HW loopback: %v", err)
+			_ = vsp.CleanHwLBK()
 			return err
 		}
 	default:
@@ -180,26 +240,55 @@ func (vsp *mrvlVspServer) ConfigureNetworkInterface() error {
 	return nil
 }
 
-// GetDeviceHealth function to get the health of the device based on the given nfInterfaceName
-func (vsp *mrvlVspServer) GetDeviceHealth(nfInterfaceName string) string {
-	switch vsp.portType {
+// GetDeviceHealth function to get the health of the given device
+func (vsp *mrvlVspServer) GetDeviceHealth(device mrvlDeviceInfo) string {
+	switch vsp.config.PortType {
 	case "veth":
-		nfLink, err := netlink.LinkByName(nfInterfaceName)
-		if err != nil {
-			return "Unhealthy"
-		}
-		//check if the interface is up =0 means interface is down
-		if nfLink.Attrs().Flags&net.FlagUp == 0 {
+		if !linkIsUp(device.nfInterfaceName) {
 			return "Unhealthy"
 		}
 		return "Healthy"
 	case "hwlbk":
-		return "Healthy" //TODO: Implement HW Loopback
+		// Traffic of the NF only comes back if both VFs of the pair are up
+		// and have a carrier
+		for _, name := range []string{device.nfInterfaceName, device.dpInterfaceName} {
+			if !linkIsUp(name) || !linkHasCarrier(name) {
+				return "Unhealthy"
+			}
+		}
+		return "Healthy"
 	default:
 		return "Unhealthy"
 	}
 }
 
+// linkByName is netlink.LinkByName, replaced in tests
+var linkByName = netlink.LinkByName
+
+// linkIsUp function to check if the interface exists and is up
+func linkIsUp(name string) bool {
+	link, err := linkByName(name)
+	if err != nil {
+		return false
+	}
+	//check if the interface is up =0 means interface is down
+	return link.Attrs().Flags&net.FlagUp != 0
+}
+
+// linkHasCarrier function to check if the interface exists and has a carrier
+func linkHasCarrier(name string) bool {
+	link, err := linkByName(name)
+	if err != nil {
+		return false
+	}
+	switch link.Attrs().OperState {
+	case netlink.OperUp, netlink.OperUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
 // Init function to initialize the Marvell VSP Server with the given context and InitRequest
 // It will return the IpPort and error
 func (vsp *mrvlVspServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpPort, error) {
@@ -217,8 +306,7 @@ func (vsp *mrvlVspServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpP
 			return &pb.IpPort{}, err
 		}
 		// Initialize Marvell Data Path
-		vsp.bridgeName = "br-mrv0" // TODO: example name discuss on it
-		if err := vsp.mrvlDP.InitDataPlane(vsp.bridgeName); err != nil {
+		if err := vsp.mrvlDP.InitDataPlane(vsp.config.BridgeName); err != nil {
 			klog.Errorf("Error occurred in initializing Data Path: %v", err)
 			vsp.Stop()
 			return &pb.IpPort{}, err
@@ -226,16 +314,78 @@ func (vsp *mrvlVspServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpP
 
 	}
 	return &pb.IpPort{
-		Ip:   ipPort.Ip,
-		Port: ipPort.Port,
+		Ip:     ipPort.Ip,
+		Port:   ipPort.Port,
+		Config: vsp.config.toMap(),
 	}, err
 }
 
+// GetIdentity returns the PCI address and serial number of the OCTEON card
+func (vsp *mrvlVspServer) GetIdentity(ctx context.Context, in *pb.Empty) (*pb.DpuIdentity, error) {
+	deviceID := HostDeviceID
+	if vsp.isDPUMode {
+		deviceID = DPUdeviceID
+	}
+	pciAddress, err := mrvlutils.GetPCIByDeviceID(deviceID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find PCI address of device %s: %v", deviceID, err)
+	}
+	serial, err := mrvlutils.GetSerialNumber(pciAddress)
+	if err != nil {
+		// The serial number is informational, the PCI address is enough to identify the card
+		klog.Warningf("Failed to read serial number of %s: %v", pciAddress, err)
+	}
+	return &pb.DpuIdentity{
+		SerialNumber: serial,
+		PciAddress:   pciAddress,
+	}, nil
+}
+
+// GetHostCapabilities reports that the host must not change the VLAN nor read
+// the state of the OCTEON VFs with netlink, the octeon_ep PF driver does not
+// support these VF operations
+func (vsp *mrvlVspServer) GetHostCapabilities(ctx context.Context, in *pb.Empty) (*pb.HostCapabilities, error) {
+	return &pb.HostCapabilities{
+		VfVlan:  false,
+		VfState: false,
+	}, nil
+}
+
+// GetLinkLimits reports the MTU of the data path bridge on the DPU. On the host
+// the MTU of the PF is checked by the CNI itself, so it is reported as unknown.
+func (vsp *mrvlVspServer) GetLinkLimits(ctx context.Context, in *pb.Empty) (*pb.LinkLimits, error) {
+	if !vsp.isDPUMode {
+		return &pb.LinkLimits{}, nil
+	}
+	link, err := netlink.LinkByName(vsp.config.BridgeName)
+	if err != nil {
+		// The debug data plane has no bridge netdev
+		klog.Warningf("Failed to get the MTU of bridge %s: %v", vsp.config.BridgeName, err)
+		return &pb.LinkLimits{}, nil
+	}
+	return &pb.LinkLimits{MaxMtu: uint32(link.Attrs().MTU)}, nil
+}
+
+// sfPortNameRe matches the bridge port names of sub functions, host<pf>-sf<sfnum>
+var sfPortNameRe = regexp.MustCompile(`host(\d+)-sf(\d+)`)
+
+// vfPortNameRe matches the bridge port names of VFs, host<pf>-<vf>
+var vfPortNameRe = regexp.MustCompile(`host(\d+)-(\d+)`)
+
 // getVFName function to get the VF Name of the given BridgePortName on DPU
 func (vsp *mrvlVspServer) getVFDetails(BridgePortName string) (string, string, error) {
-	// regexp to get VFId from BridgePortName ex: host1-0 , vfId=0
-	re := regexp.MustCompile(`host(\d+)-(\d+)`)
-	matches := re.FindStringSubmatch(BridgePortName)
+	// The SDP function of the sub function sfnum is the one of VF sf-base+sfnum
+	isSF := false
+	matches := sfPortNameRe.FindStringSubmatch(BridgePortName)
+	if matches != nil {
+		if vsp.config.SFBase < 0 {
+			return "", "", status.Errorf(codes.FailedPrecondition, "bridge port %s: sub functions are disabled, sf-base is not set", BridgePortName)
+		}
+		isSF = true
+	} else {
+		// regexp to get VFId from BridgePortName ex: host1-0 , vfId=0
+		matches = vfPortNameRe.FindStringSubmatch(BridgePortName)
+	}
 	if matches == nil {
 		return "", "", errors.New("no VFId Match Found")
 	}
@@ -243,12 +393,17 @@ func (vsp *mrvlVspServer) getVFDetails(BridgePortName string) (string, string, e
 	if err != nil {
 		return "", "", err
 	}
-	vfId, err := strconv.Atoi(matches[1])
+	vfId, err := strconv.Atoi(matches[2])
 	if err != nil {
 		return "", "", err
 	}
-	klog.Infof("Mapped VF for PFID: %d, VFID: %d, NumPFs: %d", pfid, vfId, NumPFs)
-	vfPciAddress, err := mrvlutils.Mapped_VF(NumPFs, PFID, vfId) // TODO: Get PF Count=1 and PF ID=0
+	if isSF {
+		vfId += vsp.config.SFBase
+	} else if vsp.config.SFBase >= 0 && vfId >= vsp.config.SFBase {
+		return "", "", status.Errorf(codes.InvalidArgument, "bridge port %s: VF %d is reserved for sub functions from sf-base %d on", BridgePortName, vfId, vsp.config.SFBase)
+	}
+	klog.Infof("Mapped VF for PFID: %d, VFID: %d, NumPFs: %d", pfid, vfId, vsp.config.NumPFs)
+	vfPciAddress, err := mrvlutils.Mapped_VF(vsp.config.NumPFs, vsp.config.PFID, vfId)
 	if err != nil {
 		return "", "", err
 	}
@@ -257,8 +412,12 @@ func (vsp *mrvlVspServer) getVFDetails(BridgePortName string) (string, string, e
 		return "", "", errors.New("mapped VF not found")
 	}
 	vfName := ""
-	if isDPDK {
-		vfName = fmt.Sprintf("vf%d-%d", pfid, vfId)
+	if vsp.config.IsDPDK {
+		if isSF {
+			vfName = fmt.Sprintf("sf%d-%s", pfid, matches[2])
+		} else {
+			vfName = fmt.Sprintf("vf%d-%d", pfid, vfId)
+		}
 	} else {
 		// NetDevices, err := sriovnet.GetNetDevicesFromPci(vfPciAddress)
 		vfName, err = mrvlutils.GetNameByPCI(vfPciAddress)
@@ -280,12 +439,12 @@ func (vsp *mrvlVspServer) CreateBridgePort(ctx context.Context, in *opi.CreateBr
 		klog.Errorf("Error occurred in getting VF Name: %v, BridgePortName: %v", err, portName)
 		return nil, err
 	}
-	if err := vsp.mrvlDP.AddPortToDataPlane(vsp.bridgeName, vfName, vfPCIAddress, isDPDK); err != nil {
+	if err := vsp.mrvlDP.AddPortToDataPlane(vsp.config.BridgeName, vfName, vfPCIAddress, vsp.config.IsDPDK); err != nil {
 		klog.Errorf("Error occurred in adding Port to Bridge: %v", err)
 		return nil, err
 	}
 	klog.Info("Port Added to Bridge Successfully")
-	if isDPDK {
+	if vsp.config.IsDPDK {
 		if err = mrvlutils.PrintDPDKPortInfo(vfPCIAddress); err != nil {
 			klog.Errorf("Error occurred in printing DPDK Port Info: %v", err)
 		}
@@ -313,7 +472,7 @@ func (vsp *mrvlVspServer) DeleteBridgePort(ctx context.Context, in *opi.DeleteBr
 		klog.Info("Error occurred in getting VF Name")
 		return nil, err
 	}
-	if err := vsp.mrvlDP.DeletePortFromDataPlane(vsp.bridgeName, vfName); err != nil {
+	if err := vsp.mrvlDP.DeletePortFromDataPlane(vsp.config.BridgeName, vfName); err != nil {
 		klog.Errorf("Error occurred in deleting Port from Bridge: %v", err)
 		return nil, err
 	}
@@ -325,6 +484,57 @@ func (vsp *mrvlVspServer) DeleteBridgePort(ctx context.Context, in *opi.DeleteBr
 	return out, nil
 }
 
+// GetBridgePort function to confirm that the bridge port with the given name is on the bridge
+// It will return the BridgePort and error, NotFound if the port is not on the bridge
+func (vsp *mrvlVspServer) GetBridgePort(ctx context.Context, in *opi.GetBridgePortRequest) (*opi.BridgePort, error) {
+	klog.Infof("Received GetBridgePort() request: Name: %v", in.Name)
+	vfName, _, err := vsp.getVFDetails(in.Name)
+	if err != nil {
+		klog.Errorf("Error occurred in getting VF Name: %v, BridgePortName: %v", err, in.Name)
+		return nil, err
+	}
+	ports, err := vsp.mrvlDP.ReadAllPortFromDataPlane(vsp.config.BridgeName)
+	if err != nil {
+		klog.Errorf("Error occurred in reading Ports from Bridge: %v", err)
+		return nil, err
+	}
+	for _, port := range strings.Fields(ports) {
+		if port == vfName {
+			return &opi.BridgePort{
+				Name:   fmt.Sprintf("bridge_port/%s", in.Name),
+				Status: &opi.BridgePortStatus{},
+			}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "port %s of bridge port %s is not on bridge %s", vfName, in.Name, vsp.config.BridgeName)
+}
+
+// SetBridgePortPolicy function to enforce the rate limit and anti-spoofing of the VF behind
+// the bridge port with the given context and BridgePortPolicy
+// It will return the Empty and error
+func (vsp *mrvlVspServer) SetBridgePortPolicy(ctx context.Context, in *pb.BridgePortPolicy) (*pb.Empty, error) {
+	klog.Infof("Received SetBridgePortPolicy() request: Name: %v, MinTxRate: %v, MaxTxRate: %v, SpoofCheck: %v, Trust: %v",
+		in.BridgePortName, in.MinTxRate, in.MaxTxRate, in.SpoofCheck, in.Trust)
+	vfName, _, err := vsp.getVFDetails(in.BridgePortName)
+	if err != nil {
+		klog.Errorf("Error occurred in getting VF Name: %v, BridgePortName: %v", err, in.BridgePortName)
+		return nil, err
+	}
+	if in.MinTxRate != 0 {
+		klog.Warningf("Minimum tx rate %d of %s is not supported by the data plane, ignoring it", in.MinTxRate, in.BridgePortName)
+	}
+	// A trusted VF may change its MAC address
+	spoofCheck := in.SpoofCheck && !in.Trust
+	mac := net.HardwareAddr(in.MacAddress).String()
+	if err := vsp.mrvlDP.SetPortPolicy(vsp.config.BridgeName, vfName, mac, in.MaxTxRate, spoofCheck); err != nil {
+		klog.Errorf("Error occurred in setting Port Policy: %v", err)
+		return nil, err
+	}
+	klog.Info("Port Policy Set Successfully")
+	out := new(pb.Empty)
+	return out, nil
+}
+
 // CreateNetworkFunction function to create a network function with the given context and NFRequest
 // It will return the Empty and error
 func (vsp *mrvlVspServer) CreateNetworkFunction(ctx context.Context, in *pb.NFRequest) (*pb.Empty, error) {
@@ -341,6 +551,23 @@ func (vsp *mrvlVspServer) DeleteNetworkFunction(ctx context.Context, in *pb.NFRe
 	return out, nil
 }
 
+// GetNetworkFunction function to confirm the network function with the given context and NFRequest
+// The network function ports are the port pairs, it fails with NotFound if the MAC address of
+// the input or output is not the one of a port, and with Unavailable if the port pair is unhealthy
+func (vsp *mrvlVspServer) GetNetworkFunction(ctx context.Context, in *pb.NFRequest) (*pb.Empty, error) {
+	klog.Infof("Received GetNetworkFunction() request: Input: %v, Output: %v", in.Input, in.Output)
+	for _, mac := range []string{in.Input, in.Output} {
+		device, ok := vsp.deviceStore[mac]
+		if !ok {
+			return nil, status.Errorf(codes.NotFound, "no network function port with MAC address %s", mac)
+		}
+		if health := vsp.GetDeviceHealth(device); health != "Healthy" {
+			return nil, status.Errorf(codes.Unavailable, "network function port %s is %s", device.nfInterfaceName, health)
+		}
+	}
+	return new(pb.Empty), nil
+}
+
 // GetDevices function to get all the devices with the given context and Empty
 // It will return the DeviceListResponse and error
 func (vsp *mrvlVspServer) GetDevices(ctx context.Context, in *pb.Empty) (*pb.DeviceListResponse, error) {
@@ -349,11 +576,14 @@ func (vsp *mrvlVspServer) GetDevices(ctx context.Context, in *pb.Empty) (*pb.Dev
 	if vsp.deviceStore == nil {
 		return nil, errors.New("device Store is empty")
 	}
-	for _, mrvlDeviceInfo := range vsp.deviceStore {
-		health := vsp.GetDeviceHealth(mrvlDeviceInfo.nfInterfaceName)
+	for nfMacAddress, mrvlDeviceInfo := range vsp.deviceStore {
+		health := vsp.GetDeviceHealth(mrvlDeviceInfo)
 		devices[mrvlDeviceInfo.nfInterfaceName] = &pb.Device{
-			ID:     mrvlDeviceInfo.nfInterfaceName,
-			Health: health,
+			ID:         mrvlDeviceInfo.nfInterfaceName,
+			Health:     health,
+			Netdev:     mrvlDeviceInfo.nfInterfaceName,
+			Mac:        nfMacAddress,
+			PciAddress: mrvlDeviceInfo.nfPciAddress,
 		}
 	}
 	return &pb.DeviceListResponse{
@@ -406,7 +636,7 @@ func (vsp *mrvlVspServer) dpuIpPort() (pb.IpPort, error) {
 	}
 	klog.Infof("Interface Name: %s", IfName)
 
-	err = enableIPV6LinkLocal(IfName, IPv6AddrDpu)
+	err = enableIPV6LinkLocal(IfName, vsp.config.IPv6AddrDpu)
 	if err != nil {
 		klog.Errorf("Error occurred in enabling IPv6 Link local Address: %v", err)
 		return pb.IpPort{}, err
@@ -453,7 +683,7 @@ func (vsp *mrvlVspServer) hostIpPort() (pb.IpPort, error) {
 	}
 	klog.Infof("Interface Name: %s", ifName)
 
-	err = enableIPV6LinkLocal(ifName, IPv6AddrHost)
+	err = enableIPV6LinkLocal(ifName, vsp.config.IPv6AddrHost)
 	if err != nil {
 		klog.Errorf("Error occurred in enabling IPv6 Link local Address: %v", err)
 		return pb.IpPort{}, err
@@ -559,6 +789,7 @@ func (vsp *mrvlVspServer) Listen() (net.Listener, error) {
 	pb.RegisterLifeCycleServiceServer(vsp.grpcServer, vsp)
 	pb.RegisterDeviceServiceServer(vsp.grpcServer, vsp)
 	opi.RegisterBridgePortServiceServer(vsp.grpcServer, vsp)
+	pb.RegisterBridgePortPolicyServiceServer(vsp.grpcServer, vsp)
 	klog.Infof("gRPC server is listening on %v", listener.Addr())
 
 	return listener, nil
@@ -591,11 +822,11 @@ func (vsp *mrvlVspServer) Serve(listener net.Listener) error {
 }
 
 func (vsp *mrvlVspServer) Stop() {
-	if err := vsp.mrvlDP.DeleteDataplane(vsp.bridgeName); err != nil {
+	if err := vsp.mrvlDP.DeleteDataplane(vsp.config.BridgeName); err != nil {
 		klog.Errorf("Error occurred during DeleteDataPlane: %v", err)
 	}
-	if err := vsp.CleanVethPairs(); err != nil {
-		klog.Errorf("Error occurred during clearning Veth-Peers: %v", err)
+	if err := vsp.cleanPortPairs(); err != nil {
+		klog.Errorf("Error occurred during clearning %s port pairs: %v", vsp.config.PortType, err)
 	}
 	vsp.grpcServer.Stop()
 	vsp.done <- nil
@@ -608,9 +839,11 @@ func WithPathManager(pathManager utils.PathManager) func(*mrvlVspServer) {
 	}
 }
 
-func NewMarvellVspServer(opts ...func(*mrvlVspServer)) *mrvlVspServer {
+func NewMarvellVspServer(opts ...func(*mrvlVspServer)) (*mrvlVspServer, error) {
 	var mode string
+	config := defaultConfig()
 	flag.StringVar(&mode, "mode", "", "Mode for the daemon, can be either host or dpu")
+	config.bindFlags(flag.CommandLine)
 	options := zap.Options{
 		Development: true,
 		Level:       zapcore.DebugLevel,
@@ -618,14 +851,23 @@ func NewMarvellVspServer(opts ...func(*mrvlVspServer)) *mrvlVspServer {
 	options.BindFlags(flag.CommandLine)
 	flag.Parse()
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&options)))
+	// The flags take precedence over the ConfigMap
+	if err := config.loadDir(flag.CommandLine, ConfigDir); err != nil {
+		return nil, err
+	}
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %v", err)
+	}
+	klog.Infof("Marvell VSP configuration: %v", config.toMap())
 	vsp := &mrvlVspServer{
 		log:         ctrl.Log.WithName("MarvellVsp"),
 		pathManager: *utils.NewPathManager("/"),
 		deviceStore: make(map[string]mrvlDeviceInfo),
 		done:        make(chan error),
 		mrvlDP:      ovsdp.NewOvsDP(),
+		config:      config,
 	}
-	if DataPlaneType == "debug" {
+	if config.DataPlaneType == "debug" {
 		vsp.mrvlDP = debugdp.NewDebugDP()
 	}
 
@@ -633,11 +875,14 @@ func NewMarvellVspServer(opts ...func(*mrvlVspServer)) *mrvlVspServer {
 		opt(vsp)
 	}
 
-	return vsp
+	return vsp, nil
 }
 
 func main() {
-	mrvlVspServer := NewMarvellVspServer()
+	mrvlVspServer, err := NewMarvellVspServer()
+	if err != nil {
+		klog.Fatalf("Failed to create Marvell VSP server: %v", err)
+	}
 	listener, err := mrvlVspServer.Listen()
 
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/jaypipes/ghw"
@@ -14,36 +15,82 @@ import (
 )
 
 const (
-	VendorID         = "177d" // vendor ID for Marvell OCTEON
-	deviceID         = "a0f7" // device ID for Marvell OCTEON(CN10K) SDP Interface
-	SysBusPci string = "/sys/bus/pci/devices"
+	VendorID      = "177d" // vendor ID for Marvell OCTEON
+	deviceID      = "a0f7" // device ID for Marvell OCTEON(CN10K) SDP Interface
+	LbkVFDeviceID = "a0f8" // device ID for Marvell OCTEON(CN10K) RPM loopback VF
 )
 
-// Mapped_VF returns the PCI address of the VF mapped to the given PF
-func Mapped_VF(pf_count int, pfid int, vfid int) (string, error) {
-	pci, err := ghw.PCI()
-	if err != nil {
-		return "", err
+// SysBusPci is sysfs pci device directory
+var SysBusPci = "/sys/bus/pci/devices"
+
+// SetSysfsRoot makes the sysfs paths relative to root instead of /, e.g. to
+// use a fake sysfs in tests. It returns a function restoring the previous
+// paths.
+func SetSysfsRoot(root string) func() {
+	sysBusPci := SysBusPci
+	SysBusPci = filepath.Join(root, "sys/bus/pci/devices")
+	return func() {
+		SysBusPci = sysBusPci
 	}
+}
 
-	devices := pci.Devices
+// pciDevicesByID returns the PCI addresses of the Marvell devices with the
+// given device ID, in PCI address order
+func pciDevicesByID(deviceID string) ([]string, error) {
+	entries, err := os.ReadDir(SysBusPci)
+	if err != nil {
+		return nil, err
+	}
 	var list []string
-	for _, device := range devices {
-		if device.Vendor != nil && device.Product != nil {
-			if device.Vendor.ID == VendorID &&
-				device.Product.ID == deviceID {
-				list = append(list, device.Address)
-			}
+	for _, e := range entries {
+		vendor, err := os.ReadFile(filepath.Join(SysBusPci, e.Name(), "vendor"))
+		if err != nil {
+			continue
+		}
+		device, err := os.ReadFile(filepath.Join(SysBusPci, e.Name(), "device"))
+		if err != nil {
+			continue
+		}
+		if strings.TrimPrefix(strings.TrimSpace(string(vendor)), "0x") == VendorID &&
+			strings.TrimPrefix(strings.TrimSpace(string(device)), "0x") == deviceID {
+			list = append(list, e.Name())
 		}
 	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// Mapped_VF returns the PCI address of the VF mapped to the given PF
+func Mapped_VF(pf_count int, pfid int, vfid int) (string, error) {
+	list, err := pciDevicesByID(deviceID)
+	if err != nil {
+		return "", err
+	}
 	dpu_vfid := pf_count*vfid + pfid
 	size := len(list) - 1
 	if dpu_vfid >= size {
 		return "", errors.New("mapped VF out of bounds")
 	}
 
-	vf_pci := strings.Split(list[dpu_vfid], " ")
-	return vf_pci[0], nil
+	return list[dpu_vfid], nil
+}
+
+// GetHwLbkPairs returns the PCI addresses of count pairs of loopback VFs.
+// The hardware loops the traffic sent on VF 2n back to VF 2n+1 and vice versa,
+// so the VFs are paired in PCI address order.
+func GetHwLbkPairs(count int) ([][2]string, error) {
+	list, err := pciDevicesByID(LbkVFDeviceID)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) < 2*count {
+		return nil, fmt.Errorf("found %d loopback VFs, %d port pairs need %d", len(list), count, 2*count)
+	}
+	pairs := make([][2]string, count)
+	for i := range pairs {
+		pairs[i] = [2]string{list[2*i], list[2*i+1]}
+	}
+	return pairs, nil
 }
 
 // getInterfaceName function to get the Interface Name of the given Device ID and vendor ID
@@ -117,6 +164,46 @@ func GetPCIByDeviceID(deviceID string) (string, error) {
 	return pciAddress, nil
 }
 
+// GetSerialNumber returns the serial number ("SN" keyword) stored in the Vital
+// Product Data of the PCI device with the given address
+func GetSerialNumber(pciAddress string) (string, error) {
+	vpd, err := os.ReadFile(filepath.Join(SysBusPci, pciAddress, "vpd"))
+	if err != nil {
+		return "", err
+	}
+	// VPD is a sequence of resource tags. Large resources (bit 7 set) have a
+	// 2 byte length, the read-only one (0x90) holds keywords with a 2 byte
+	// name and a 1 byte length. The end tag (0x78) terminates the list.
+	for i := 0; i < len(vpd); {
+		tag := vpd[i]
+		if tag&0x80 == 0 {
+			if tag&0x78 == 0x78 {
+				break
+			}
+			i += 1 + int(tag&0x07)
+			continue
+		}
+		if i+3 > len(vpd) {
+			break
+		}
+		length := int(vpd[i+1]) | int(vpd[i+2])<<8
+		data := vpd[i+3 : min(i+3+length, len(vpd))]
+		if tag == 0x90 {
+			for j := 0; j+3 <= len(data); {
+				keyword := string(data[j : j+2])
+				kwLen := int(data[j+2])
+				value := data[j+3 : min(j+3+kwLen, len(data))]
+				if keyword == "SN" {
+					return strings.TrimSpace(string(value)), nil
+				}
+				j += 3 + kwLen
+			}
+		}
+		i += 3 + length
+	}
+	return "", errors.New("serial number not found in VPD")
+}
+
 // Print DPDK port info prints information of dpdk port with given pci address
 func PrintDPDKPortInfo(pciAddress string) error {
 	// pciAddress := "0000:03:00.0"
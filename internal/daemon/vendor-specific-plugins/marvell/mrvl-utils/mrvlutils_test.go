@@ -0,0 +1,46 @@
+package mrvlutils
+
+import (
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+// addPCIDevice adds a PCI device with the given vendor and device ID to the
+// fake sysfs at root
+func addPCIDevice(root string, pci string, vendor string, device string) {
+	dir := filepath.Join(root, "sys/bus/pci/devices", pci)
+	o.Expect(os.MkdirAll(dir, 0755)).To(o.Succeed())
+	o.Expect(os.WriteFile(filepath.Join(dir, "vendor"), []byte("0x"+vendor+"\n"), 0644)).To(o.Succeed())
+	o.Expect(os.WriteFile(filepath.Join(dir, "device"), []byte("0x"+device+"\n"), 0644)).To(o.Succeed())
+}
+
+var _ = g.Describe("GetHwLbkPairs", func() {
+	g.BeforeEach(func() {
+		root := g.GinkgoT().TempDir()
+		g.DeferCleanup(SetSysfsRoot(root))
+		// Created out of order, with devices that are not loopback VFs
+		for _, pci := range []string{"0002:01:00.3", "0002:01:00.1", "0002:01:00.0", "0002:01:00.2", "0002:01:00.4"} {
+			addPCIDevice(root, pci, VendorID, LbkVFDeviceID)
+		}
+		addPCIDevice(root, "0002:01:00.5", VendorID, deviceID)
+		addPCIDevice(root, "0002:02:00.0", "8086", LbkVFDeviceID)
+	})
+
+	g.DescribeTable("should pair the loopback VFs in PCI address order",
+		func(count int, pairs [][2]string, err string) {
+			got, gotErr := GetHwLbkPairs(count)
+			if err != "" {
+				o.Expect(gotErr).To(o.MatchError(o.ContainSubstring(err)))
+				return
+			}
+			o.Expect(gotErr).NotTo(o.HaveOccurred())
+			o.Expect(got).To(o.Equal(pairs))
+		},
+		g.Entry("one pair", 1, [][2]string{{"0002:01:00.0", "0002:01:00.1"}}, ""),
+		g.Entry("two pairs", 2, [][2]string{{"0002:01:00.0", "0002:01:00.1"}, {"0002:01:00.2", "0002:01:00.3"}}, ""),
+		g.Entry("more pairs than VFs", 3, nil, "found 5 loopback VFs, 3 port pairs need 6"),
+	)
+})
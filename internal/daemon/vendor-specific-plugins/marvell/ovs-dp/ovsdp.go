@@ -15,6 +15,11 @@ const (
 	deviceId   = "a063"
 )
 
+// runCommand runs an ovs command, tests replace it to record the commands
+var runCommand = func(name string, args ...string) error {
+	return exec.Command(name, args...).Run()
+}
+
 type OvsDP struct {
 	bridgeName string
 	ovsDBPath  string
@@ -38,36 +43,63 @@ func createDbParam(ovsDbPath string) string {
 
 // ovs-vsctl command to add dpdk port to bridge
 func (ovsdp *OvsDP) AddPortToDataPlane(bridgeName string, portName string, vfPCIAddres string, isDPDK bool) error {
-	var cmd *exec.Cmd
 	if isDPDK {
 		ovsdp.log.Info("Adding DPDK Port to Bridge", "PortName", portName, "VFPCIAddress", vfPCIAddres)
-		cmd = exec.Command("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName, "--", "set", "Interface", portName, "type=dpdk", fmt.Sprintf("options:dpdk-devargs=%s", vfPCIAddres))
-
-	} else {
-		ovsdp.log.Info("Adding Port to Bridge", "PortName", portName)
-		cmd = exec.Command("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName)
-
+		return runCommand("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName, "--", "set", "Interface", portName, "type=dpdk", fmt.Sprintf("options:dpdk-devargs=%s", vfPCIAddres))
 	}
-	return cmd.Run()
+	ovsdp.log.Info("Adding Port to Bridge", "PortName", portName)
+	return runCommand("ovs-vsctl", "--may-exist", "add-port", bridgeName, portName)
 }
 
 // ovs-vsctl command to delete dpdk-port from bridge
 func (ovsdp *OvsDP) DeletePortFromDataPlane(bridgeName string, portName string) error {
 	ovsdp.log.Info("Deleting Port from Bridge", "PortName", portName)
-	cmd := exec.Command("ovs-vsctl", "del-port", bridgeName, portName)
-	return cmd.Run()
+	// The anti-spoofing flows refer to the port by name, remove them first
+	if err := runCommand("ovs-ofctl", "del-flows", bridgeName, "in_port="+portName); err != nil {
+		ovsdp.log.Error(err, "Error occurred in deleting flows of Port", "PortName", portName)
+	}
+	return runCommand("ovs-vsctl", "del-port", bridgeName, portName)
+}
+
+// SetPortPolicy limits the rate at which the VF transmits into the bridge with
+// ingress policing, 0 removes the limit, and with spoofCheck drops the frames
+// of the VF not sent from mac.
+func (ovsdp *OvsDP) SetPortPolicy(bridgeName string, portName string, mac string, maxTxRate uint32, spoofCheck bool) error {
+	ovsdp.log.Info("Setting Port Policy", "PortName", portName, "MaxTxRate", maxTxRate, "SpoofCheck", spoofCheck)
+	// ingress_policing_rate is in kbps, the burst is 10% of it as recommended
+	rate := maxTxRate * 1000
+	err := runCommand("ovs-vsctl", "set", "Interface", portName,
+		fmt.Sprintf("ingress_policing_rate=%d", rate), fmt.Sprintf("ingress_policing_burst=%d", rate/10))
+	if err != nil {
+		return fmt.Errorf("failed to set ingress policing of %s: %v", portName, err)
+	}
+
+	if err := runCommand("ovs-ofctl", "del-flows", bridgeName, "in_port="+portName); err != nil {
+		return fmt.Errorf("failed to delete flows of %s: %v", portName, err)
+	}
+	if !spoofCheck {
+		return nil
+	}
+	flows := []string{
+		fmt.Sprintf("priority=100,in_port=%s,dl_src=%s,actions=NORMAL", portName, mac),
+		fmt.Sprintf("priority=90,in_port=%s,actions=drop", portName),
+	}
+	for _, flow := range flows {
+		if err := runCommand("ovs-ofctl", "add-flow", bridgeName, flow); err != nil {
+			return fmt.Errorf("failed to add flow %q: %v", flow, err)
+		}
+	}
+	return nil
 }
 
 // ovs-vsctl command to delete ovs bridge
 func (ovsdp *OvsDP) DeleteDataplane(bridgeName string) error {
-	cmd := exec.Command("ovs-vsctl", "del-br", bridgeName)
-	return cmd.Run()
+	return runCommand("ovs-vsctl", "del-br", bridgeName)
 }
 
 // ovs-vsctl command to create bridge
 func createBridge(bridgeName string) error {
-	cmd := exec.Command("ovs-vsctl", "--may-exist", "add-br", bridgeName, "--", "set", "bridge", bridgeName, "datapath_type=netdev")
-	return cmd.Run()
+	return runCommand("ovs-vsctl", "--may-exist", "add-br", bridgeName, "--", "set", "bridge", bridgeName, "datapath_type=netdev")
 }
 
 // InitDataPlane initializes the data path in this case it creates an ovs bridge
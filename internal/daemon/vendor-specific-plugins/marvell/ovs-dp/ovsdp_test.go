@@ -0,0 +1,68 @@
+package ovsdp
+
+import (
+	"fmt"
+	"strings"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+)
+
+var _ = g.Describe("OvsDP port policy", func() {
+	var (
+		commands []string
+		// failing makes the commands starting with it fail
+		failing string
+	)
+
+	g.BeforeEach(func() {
+		commands = nil
+		failing = ""
+		orig := runCommand
+		runCommand = func(name string, args ...string) error {
+			command := strings.Join(append([]string{name}, args...), " ")
+			commands = append(commands, command)
+			if failing != "" && strings.HasPrefix(command, failing) {
+				return fmt.Errorf("%s failed", name)
+			}
+			return nil
+		}
+		g.DeferCleanup(func() { runCommand = orig })
+	})
+
+	g.It("should police the rate and drop frames from other MACs", func() {
+		o.Expect(NewOvsDP().SetPortPolicy("br0", "sdp3", "00:11:22:33:44:55", 100, true)).To(o.Succeed())
+		o.Expect(commands).To(o.Equal([]string{
+			"ovs-vsctl set Interface sdp3 ingress_policing_rate=100000 ingress_policing_burst=10000",
+			"ovs-ofctl del-flows br0 in_port=sdp3",
+			"ovs-ofctl add-flow br0 priority=100,in_port=sdp3,dl_src=00:11:22:33:44:55,actions=NORMAL",
+			"ovs-ofctl add-flow br0 priority=90,in_port=sdp3,actions=drop",
+		}))
+	})
+
+	g.It("should remove the limit and the anti-spoofing flows", func() {
+		o.Expect(NewOvsDP().SetPortPolicy("br0", "sdp3", "00:11:22:33:44:55", 0, false)).To(o.Succeed())
+		o.Expect(commands).To(o.Equal([]string{
+			"ovs-vsctl set Interface sdp3 ingress_policing_rate=0 ingress_policing_burst=0",
+			"ovs-ofctl del-flows br0 in_port=sdp3",
+		}))
+	})
+
+	g.DescribeTable("should fail if a command fails",
+		func(command string, err string) {
+			failing = command
+			o.Expect(NewOvsDP().SetPortPolicy("br0", "sdp3", "00:11:22:33:44:55", 100, true)).To(o.MatchError(o.ContainSubstring(err)))
+		},
+		g.Entry("setting the ingress policing", "ovs-vsctl set", "failed to set ingress policing of sdp3"),
+		g.Entry("deleting the flows", "ovs-ofctl del-flows", "failed to delete flows of sdp3"),
+		g.Entry("adding a flow", "ovs-ofctl add-flow", "failed to add flow"),
+	)
+
+	g.It("should delete the flows of a port before the port", func() {
+		o.Expect(NewOvsDP().DeletePortFromDataPlane("br0", "sdp3")).To(o.Succeed())
+		o.Expect(commands).To(o.Equal([]string{
+			"ovs-ofctl del-flows br0 in_port=sdp3",
+			"ovs-vsctl del-port br0 sdp3",
+		}))
+	})
+})
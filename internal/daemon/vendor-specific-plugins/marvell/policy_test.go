@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+)
+
+// policyDP is a data plane that records the port policies it is given
+type policyDP struct {
+	mrvldp
+	err      error
+	policies []string
+}
+
+func (dp *policyDP) SetPortPolicy(bridgeName string, portName string, mac string, maxTxRate uint32, spoofCheck bool) error {
+	if dp.err != nil {
+		return dp.err
+	}
+	dp.policies = append(dp.policies, fmt.Sprintf("%s/%s mac=%s maxTxRate=%d spoofCheck=%t", bridgeName, portName, mac, maxTxRate, spoofCheck))
+	return nil
+}
+
+var _ = g.Describe("Marvell VSP bridge port policy", func() {
+	var (
+		dp  *policyDP
+		vsp *mrvlVspServer
+	)
+
+	g.BeforeEach(func() {
+		fakeSDPInterfaces(3)
+		dp = &policyDP{}
+		vsp = &mrvlVspServer{config: defaultConfig(), mrvlDP: dp}
+	})
+
+	mac, _ := net.ParseMAC("00:11:22:33:44:55")
+
+	g.DescribeTable("SetBridgePortPolicy",
+		func(policy *pb.BridgePortPolicy, applied string) {
+			policy.MacAddress = mac
+			_, err := vsp.SetBridgePortPolicy(context.Background(), policy)
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(dp.policies).To(o.Equal([]string{applied}))
+		},
+		g.Entry("with a rate limit and spoof checking",
+			&pb.BridgePortPolicy{BridgePortName: "host0-1", MaxTxRate: 100, SpoofCheck: true},
+			"br-mrv0/sdp2 mac=00:11:22:33:44:55 maxTxRate=100 spoofCheck=true"),
+		g.Entry("of a trusted VF, which may change its MAC",
+			&pb.BridgePortPolicy{BridgePortName: "host0-1", SpoofCheck: true, Trust: true},
+			"br-mrv0/sdp2 mac=00:11:22:33:44:55 maxTxRate=0 spoofCheck=false"),
+		g.Entry("ignoring the minimum rate",
+			&pb.BridgePortPolicy{BridgePortName: "host0-0", MinTxRate: 10, MaxTxRate: 100},
+			"br-mrv0/sdp1 mac=00:11:22:33:44:55 maxTxRate=100 spoofCheck=false"),
+	)
+
+	g.It("should fail for an unknown bridge port", func() {
+		_, err := vsp.SetBridgePortPolicy(context.Background(), &pb.BridgePortPolicy{BridgePortName: "eth0", MacAddress: mac})
+		o.Expect(err).To(o.HaveOccurred())
+		o.Expect(dp.policies).To(o.BeEmpty())
+	})
+
+	g.It("should fail if the data plane fails", func() {
+		dp.err = fmt.Errorf("no meter")
+		_, err := vsp.SetBridgePortPolicy(context.Background(), &pb.BridgePortPolicy{BridgePortName: "host0-1", MacAddress: mac})
+		o.Expect(err).To(o.MatchError(o.ContainSubstring("no meter")))
+	})
+})
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	mrvlutils "github.com/openshift/dpu-operator/internal/daemon/vendor-specific-plugins/marvell/mrvl-utils"
+)
+
+// fakeSDPInterfaces creates a fake sysfs with the SDP interfaces 0002:1f:00.1
+// to .<count>, each with netdev sdp<n>, for the rest of the spec
+func fakeSDPInterfaces(count int) {
+	root := g.GinkgoT().TempDir()
+	g.DeferCleanup(mrvlutils.SetSysfsRoot(root))
+	for i := 1; i <= count; i++ {
+		dir := filepath.Join(root, "sys/bus/pci/devices", fmt.Sprintf("0002:1f:00.%d", i))
+		o.Expect(os.MkdirAll(filepath.Join(dir, "net", fmt.Sprintf("sdp%d", i)), 0755)).To(o.Succeed())
+		o.Expect(os.WriteFile(filepath.Join(dir, "vendor"), []byte("0x"+mrvlutils.VendorID+"\n"), 0644)).To(o.Succeed())
+		o.Expect(os.WriteFile(filepath.Join(dir, "device"), []byte("0xa0f7\n"), 0644)).To(o.Succeed())
+	}
+}
+
+var _ = g.Describe("Marvell VSP VF details", func() {
+	g.BeforeEach(func() {
+		fakeSDPInterfaces(5)
+	})
+
+	type vfCase struct {
+		portName string
+		isDPDK   bool
+		sfBase   int
+		vfName   string
+		vfPci    string
+		err      string
+	}
+
+	g.DescribeTable("getVFDetails",
+		func(c vfCase) {
+			vsp := &mrvlVspServer{config: defaultConfig()}
+			vsp.config.IsDPDK = c.isDPDK
+			vsp.config.SFBase = c.sfBase
+			vfName, vfPci, err := vsp.getVFDetails(c.portName)
+			if c.err != "" {
+				o.Expect(err).To(o.MatchError(o.ContainSubstring(c.err)))
+				return
+			}
+			o.Expect(err).NotTo(o.HaveOccurred())
+			o.Expect(vfName).To(o.Equal(c.vfName))
+			o.Expect(vfPci).To(o.Equal(c.vfPci))
+		},
+		g.Entry("first VF", vfCase{portName: "host0-0", sfBase: -1, vfName: "sdp1", vfPci: "0002:1f:00.1"}),
+		g.Entry("VF ID from the second number", vfCase{portName: "host0-2", sfBase: -1, vfName: "sdp3", vfPci: "0002:1f:00.3"}),
+		g.Entry("DPDK port name", vfCase{portName: "host0-3", sfBase: -1, isDPDK: true, vfName: "vf0-3", vfPci: "0002:1f:00.4"}),
+		g.Entry("VF out of bounds", vfCase{portName: "host0-4", sfBase: -1, err: "mapped VF out of bounds"}),
+		g.Entry("sub function", vfCase{portName: "host0-sf1", sfBase: 2, vfName: "sdp4", vfPci: "0002:1f:00.4"}),
+		g.Entry("DPDK sub function", vfCase{portName: "host0-sf0", sfBase: 2, isDPDK: true, vfName: "sf0-0", vfPci: "0002:1f:00.3"}),
+		g.Entry("sub function out of bounds", vfCase{portName: "host0-sf2", sfBase: 2, err: "mapped VF out of bounds"}),
+		g.Entry("sub functions disabled", vfCase{portName: "host0-sf0", sfBase: -1, err: "sub functions are disabled"}),
+		g.Entry("VF reserved for sub functions", vfCase{portName: "host0-2", sfBase: 2, err: "reserved for sub functions"}),
+		g.Entry("invalid port name", vfCase{portName: "eth0", sfBase: -1, err: "no VFId Match Found"}),
+	)
+})
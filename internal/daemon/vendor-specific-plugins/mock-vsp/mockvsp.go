@@ -11,6 +11,8 @@ import (
 	"github.com/openshift/dpu-operator/internal/utils"
 	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -19,6 +21,7 @@ type vspServer struct {
 	pb.UnimplementedLifeCycleServiceServer
 	pb.UnimplementedNetworkFunctionServiceServer
 	pb.UnimplementedDeviceServiceServer
+	pb.UnimplementedBridgePortPolicyServiceServer
 	opi.UnimplementedBridgePortServiceServer
 	log         logr.Logger
 	wg          sync.WaitGroup
@@ -26,6 +29,12 @@ type vspServer struct {
 	done        chan error
 	grpcServer  *grpc.Server
 	pathManager utils.PathManager
+
+	mu          sync.Mutex
+	bridgePorts map[string]*opi.BridgePort
+	policies    map[string]*pb.BridgePortPolicy
+	// networkFunctions are the created network functions by input and output
+	networkFunctions map[string]bool
 }
 
 func (vsp *vspServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpPort, error) {
@@ -36,12 +45,30 @@ func (vsp *vspServer) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpPort,
 	}, nil
 }
 
+func (vsp *vspServer) GetIdentity(ctx context.Context, in *pb.Empty) (*pb.DpuIdentity, error) {
+	return &pb.DpuIdentity{
+		SerialNumber: "MOCK0000",
+		PciAddress:   "0000:00:00.0",
+	}, nil
+}
+
+// GetHostCapabilities reports no VF capabilities, the mock devices are not
+// VFs
+func (vsp *vspServer) GetHostCapabilities(ctx context.Context, in *pb.Empty) (*pb.HostCapabilities, error) {
+	return &pb.HostCapabilities{}, nil
+}
+
+// GetLinkLimits reports no limits, the mock has no data path
+func (vsp *vspServer) GetLinkLimits(ctx context.Context, in *pb.Empty) (*pb.LinkLimits, error) {
+	return &pb.LinkLimits{}, nil
+}
+
 func (vsp *vspServer) GetDevices(ctx context.Context, in *pb.Empty) (*pb.DeviceListResponse, error) {
 	devices := map[string]*pb.Device{
-		"ens5f0": {ID: "ens5f0", Health: "Healthy"},
-		"ens5f1": {ID: "ens5f1", Health: "Healthy"},
-		"ens5f2": {ID: "ens5f2", Health: "Healthy"},
-		"ens5f3": {ID: "ens5f3", Health: "Healthy"},
+		"ens5f0": {ID: "ens5f0", Health: "Healthy", Netdev: "ens5f0"},
+		"ens5f1": {ID: "ens5f1", Health: "Healthy", Netdev: "ens5f1"},
+		"ens5f2": {ID: "ens5f2", Health: "Healthy", Netdev: "ens5f2"},
+		"ens5f3": {ID: "ens5f3", Health: "Healthy", Netdev: "ens5f3"},
 	}
 
 	return &pb.DeviceListResponse{
@@ -51,24 +78,79 @@ func (vsp *vspServer) GetDevices(ctx context.Context, in *pb.Empty) (*pb.DeviceL
 
 func (vsp *vspServer) CreateBridgePort(ctx context.Context, in *opi.CreateBridgePortRequest) (*opi.BridgePort, error) {
 	vsp.log.Info("Received CreateBridgePort() request", "BridgePortId", in.BridgePortId, "BridgePortId", in.BridgePortId)
-	return &opi.BridgePort{}, nil
+	bp := &opi.BridgePort{Name: in.BridgePort.GetName(), Spec: in.BridgePort.GetSpec()}
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	if vsp.bridgePorts == nil {
+		vsp.bridgePorts = map[string]*opi.BridgePort{}
+	}
+	vsp.bridgePorts[bp.Name] = bp
+	return bp, nil
 }
 
 func (vsp *vspServer) DeleteBridgePort(ctx context.Context, in *opi.DeleteBridgePortRequest) (*emptypb.Empty, error) {
 	vsp.log.Info("Received DeleteBridgePort() request", "Name", in.Name, "AllowMissing", in.AllowMissing)
-	return nil, nil
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	delete(vsp.bridgePorts, in.Name)
+	delete(vsp.policies, in.Name)
+	return &emptypb.Empty{}, nil
+}
+
+func (vsp *vspServer) GetBridgePort(ctx context.Context, in *opi.GetBridgePortRequest) (*opi.BridgePort, error) {
+	vsp.log.Info("Received GetBridgePort() request", "Name", in.Name)
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	bp, ok := vsp.bridgePorts[in.Name]
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "bridge port %s not found", in.Name)
+	}
+	return bp, nil
+}
+
+func (vsp *vspServer) SetBridgePortPolicy(ctx context.Context, in *pb.BridgePortPolicy) (*pb.Empty, error) {
+	vsp.log.Info("Received SetBridgePortPolicy() request", "Name", in.BridgePortName, "MinTxRate", in.MinTxRate, "MaxTxRate", in.MaxTxRate, "SpoofCheck", in.SpoofCheck, "Trust", in.Trust)
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	if _, ok := vsp.bridgePorts[in.BridgePortName]; !ok {
+		return nil, status.Errorf(codes.NotFound, "bridge port %s not found", in.BridgePortName)
+	}
+	if vsp.policies == nil {
+		vsp.policies = map[string]*pb.BridgePortPolicy{}
+	}
+	vsp.policies[in.BridgePortName] = in
+	return &pb.Empty{}, nil
 }
 
 func (vsp *vspServer) CreateNetworkFunction(ctx context.Context, in *pb.NFRequest) (*pb.Empty, error) {
 	vsp.log.Info("Received CreateNetworkFunction() request", "Input", in.Input, "Output", in.Output)
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	if vsp.networkFunctions == nil {
+		vsp.networkFunctions = map[string]bool{}
+	}
+	vsp.networkFunctions[in.Input+"/"+in.Output] = true
 	return nil, nil
 }
 
 func (vsp *vspServer) DeleteNetworkFunction(ctx context.Context, in *pb.NFRequest) (*pb.Empty, error) {
 	vsp.log.Info("Received DeleteNetworkFunction() request", "Input", in.Input, "Output", in.Output)
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	delete(vsp.networkFunctions, in.Input+"/"+in.Output)
 	return nil, nil
 }
 
+func (vsp *vspServer) GetNetworkFunction(ctx context.Context, in *pb.NFRequest) (*pb.Empty, error) {
+	vsp.log.Info("Received GetNetworkFunction() request", "Input", in.Input, "Output", in.Output)
+	vsp.mu.Lock()
+	defer vsp.mu.Unlock()
+	if !vsp.networkFunctions[in.Input+"/"+in.Output] {
+		return nil, status.Errorf(codes.NotFound, "network function from %s to %s not found", in.Input, in.Output)
+	}
+	return &pb.Empty{}, nil
+}
+
 func (vsp *vspServer) Listen() (net.Listener, error) {
 	err := vsp.pathManager.EnsureSocketDirExists(vsp.pathManager.VendorPluginSocket())
 	if err != nil {
@@ -80,10 +162,11 @@ func (vsp *vspServer) Listen() (net.Listener, error) {
 	}
 
 	vsp.grpcServer = grpc.NewServer()
-	pb.RegisterNetworkFunctionServiceServer(vsp.grpcServer, &vspServer{})
-	pb.RegisterLifeCycleServiceServer(vsp.grpcServer, &vspServer{})
-	pb.RegisterDeviceServiceServer(vsp.grpcServer, &vspServer{})
-	opi.RegisterBridgePortServiceServer(vsp.grpcServer, &vspServer{})
+	pb.RegisterNetworkFunctionServiceServer(vsp.grpcServer, vsp)
+	pb.RegisterLifeCycleServiceServer(vsp.grpcServer, vsp)
+	pb.RegisterDeviceServiceServer(vsp.grpcServer, vsp)
+	opi.RegisterBridgePortServiceServer(vsp.grpcServer, vsp)
+	pb.RegisterBridgePortPolicyServiceServer(vsp.grpcServer, vsp)
 	vsp.log.Info("gRPC server is listening", "listener.Addr()", listener.Addr())
 	return listener, nil
 }
@@ -0,0 +1,235 @@
+package vspclient
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	"github.com/openshift/dpu-operator/internal/utils"
+	opi "github.com/opiproject/opi-api/network/evpn-gw/v1alpha1/gen/go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials/insecure"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+const (
+	// DefaultRPCTimeout bounds every call made to the VSP unless the caller's
+	// context expires earlier.
+	DefaultRPCTimeout = 30 * time.Second
+)
+
+// ReconnectHandler is called each time the connection to the VSP becomes
+// ready again after it was lost (e.g. because the VSP restarted).
+type ReconnectHandler func(ctx context.Context) error
+
+// VspClient is a connection to the vendor specific plugin shared by all
+// components of the daemon. The underlying gRPC connection reconnects on its
+// own; VspClient watches its state so that the VSP can be re-initialized after
+// a restart, and bounds every call with a deadline.
+type VspClient struct {
+	log         logr.Logger
+	pathManager utils.PathManager
+	rpcTimeout  time.Duration
+
+	mu          sync.Mutex
+	conn        *grpc.ClientConn
+	reconnectFn []ReconnectHandler
+	cancelWatch context.CancelFunc
+	watchDone   chan struct{}
+}
+
+func WithPathManager(pathManager utils.PathManager) func(*VspClient) {
+	return func(c *VspClient) {
+		c.pathManager = pathManager
+	}
+}
+
+func WithRPCTimeout(timeout time.Duration) func(*VspClient) {
+	return func(c *VspClient) {
+		c.rpcTimeout = timeout
+	}
+}
+
+func NewVspClient(opts ...func(*VspClient)) *VspClient {
+	c := &VspClient{
+		log:         ctrl.Log.WithName("VspClient"),
+		pathManager: *utils.NewPathManager("/"),
+		rpcTimeout:  DefaultRPCTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// OnReconnect registers a handler that is run every time the connection to the
+// VSP is re-established.
+func (c *VspClient) OnReconnect(fn ReconnectHandler) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnectFn = append(c.reconnectFn, fn)
+}
+
+// ensureConnected creates the connection to the VSP's unix socket the first
+// time it is needed and starts watching its state.
+func (c *VspClient) ensureConnected() (*grpc.ClientConn, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn != nil {
+		return c.conn, nil
+	}
+
+	dialOptions := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}),
+		// Calls made while the VSP is (re)starting wait for the connection
+		// to become ready until their deadline instead of failing at once.
+		grpc.WithDefaultCallOptions(grpc.WaitForReady(true)),
+	}
+
+	conn, err := grpc.NewClient("passthrough:///"+c.pathManager.VendorPluginSocket(), dialOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection to vendor plugin: %v", err)
+	}
+	conn.Connect()
+	c.conn = conn
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancelWatch = cancel
+	c.watchDone = make(chan struct{})
+	go c.watch(ctx, conn, c.watchDone)
+
+	c.log.Info("Connecting to vendor plugin", "socket", c.pathManager.VendorPluginSocket())
+	return conn, nil
+}
+
+// watch follows the connectivity state of conn and runs the reconnect handlers
+// whenever the connection becomes ready again after having been lost.
+func (c *VspClient) watch(ctx context.Context, conn *grpc.ClientConn, done chan struct{}) {
+	defer close(done)
+
+	wasReady := false
+	lost := false
+	state := conn.GetState()
+	for {
+		switch state {
+		case connectivity.Ready:
+			if lost {
+				c.log.Info("Connection to vendor plugin re-established")
+				c.runReconnectHandlers(ctx)
+			}
+			wasReady = true
+			lost = false
+		case connectivity.Idle:
+			// An idle connection does not reconnect until a call is made.
+			// Kick it so that the VSP is re-initialized right away.
+			conn.Connect()
+			fallthrough
+		case connectivity.TransientFailure:
+			if wasReady && !lost {
+				c.log.Info("Connection to vendor plugin lost", "state", state)
+				lost = true
+			}
+		case connectivity.Shutdown:
+			return
+		}
+
+		if !conn.WaitForStateChange(ctx, state) {
+			return
+		}
+		state = conn.GetState()
+	}
+}
+
+func (c *VspClient) runReconnectHandlers(ctx context.Context) {
+	c.mu.Lock()
+	handlers := append([]ReconnectHandler{}, c.reconnectFn...)
+	c.mu.Unlock()
+
+	for _, fn := range handlers {
+		rpcCtx, cancel := c.RPCContext(ctx)
+		if err := fn(rpcCtx); err != nil {
+			c.log.Error(err, "Reconnect handler failed")
+		}
+		cancel()
+	}
+}
+
+// RPCContext derives the context for a single call to the VSP from the
+// caller's context, bounded by the client's RPC timeout.
+func (c *VspClient) RPCContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithTimeout(ctx, c.rpcTimeout)
+}
+
+func (c *VspClient) LifeCycle() (pb.LifeCycleServiceClient, error) {
+	conn, err := c.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewLifeCycleServiceClient(conn), nil
+}
+
+func (c *VspClient) NetworkFunction() (pb.NetworkFunctionServiceClient, error) {
+	conn, err := c.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewNetworkFunctionServiceClient(conn), nil
+}
+
+func (c *VspClient) Device() (pb.DeviceServiceClient, error) {
+	conn, err := c.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewDeviceServiceClient(conn), nil
+}
+
+func (c *VspClient) BridgePort() (opi.BridgePortServiceClient, error) {
+	conn, err := c.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	return opi.NewBridgePortServiceClient(conn), nil
+}
+
+func (c *VspClient) BridgePortPolicy() (pb.BridgePortPolicyServiceClient, error) {
+	conn, err := c.ensureConnected()
+	if err != nil {
+		return nil, err
+	}
+	return pb.NewBridgePortPolicyServiceClient(conn), nil
+}
+
+// Close stops watching the connection and closes it.
+func (c *VspClient) Close() {
+	c.mu.Lock()
+	conn := c.conn
+	cancel := c.cancelWatch
+	done := c.watchDone
+	c.conn = nil
+	c.cancelWatch = nil
+	c.watchDone = nil
+	c.mu.Unlock()
+
+	if conn == nil {
+		return
+	}
+	cancel()
+	<-done
+	conn.Close()
+}
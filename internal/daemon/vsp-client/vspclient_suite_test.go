@@ -0,0 +1,13 @@
+package vspclient_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestVspclient(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "VspClient Suite")
+}
@@ -0,0 +1,100 @@
+package vspclient_test
+
+import (
+	"context"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	g "github.com/onsi/ginkgo/v2"
+	o "github.com/onsi/gomega"
+	pb "github.com/openshift/dpu-operator/dpu-api/gen"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
+	"github.com/openshift/dpu-operator/internal/utils"
+	"google.golang.org/grpc"
+)
+
+type fakeVsp struct {
+	pb.UnimplementedLifeCycleServiceServer
+	inits atomic.Int32
+}
+
+func (f *fakeVsp) Init(ctx context.Context, in *pb.InitRequest) (*pb.IpPort, error) {
+	f.inits.Add(1)
+	return &pb.IpPort{Ip: "127.0.0.1", Port: 50051}, nil
+}
+
+func serveFakeVsp(pathManager *utils.PathManager, vsp *fakeVsp) *grpc.Server {
+	socket := pathManager.VendorPluginSocket()
+	o.Expect(pathManager.EnsureSocketDirExists(socket)).To(o.Succeed())
+	listener, err := net.Listen("unix", socket)
+	o.Expect(err).NotTo(o.HaveOccurred())
+	server := grpc.NewServer()
+	pb.RegisterLifeCycleServiceServer(server, vsp)
+	go server.Serve(listener)
+	return server
+}
+
+var _ = g.Describe("VspClient", func() {
+	var (
+		tmpDir      string
+		pathManager *utils.PathManager
+		vsp         *fakeVsp
+		server      *grpc.Server
+		client      *vspclient.VspClient
+	)
+
+	g.BeforeEach(func() {
+		var err error
+		tmpDir, err = os.MkdirTemp("", "vspclient")
+		o.Expect(err).NotTo(o.HaveOccurred())
+		pathManager = utils.NewPathManager(tmpDir)
+		vsp = &fakeVsp{}
+		server = serveFakeVsp(pathManager, vsp)
+		client = vspclient.NewVspClient(vspclient.WithPathManager(*pathManager),
+			vspclient.WithRPCTimeout(5*time.Second))
+	})
+
+	g.AfterEach(func() {
+		client.Close()
+		server.Stop()
+		os.RemoveAll(tmpDir)
+	})
+
+	g.It("should bound calls with the RPC timeout", func() {
+		ctx, cancel := client.RPCContext(context.Background())
+		defer cancel()
+		deadline, ok := ctx.Deadline()
+		o.Expect(ok).To(o.BeTrue())
+		o.Expect(time.Until(deadline)).To(o.BeNumerically("<=", 5*time.Second))
+	})
+
+	g.It("should run the reconnect handlers after the VSP restarted", func() {
+		var reconnects atomic.Int32
+		client.OnReconnect(func(ctx context.Context) error {
+			reconnects.Add(1)
+			lc, err := client.LifeCycle()
+			if err != nil {
+				return err
+			}
+			_, err = lc.Init(ctx, &pb.InitRequest{})
+			return err
+		})
+
+		lc, err := client.LifeCycle()
+		o.Expect(err).NotTo(o.HaveOccurred())
+		ctx, cancel := client.RPCContext(context.Background())
+		defer cancel()
+		_, err = lc.Init(ctx, &pb.InitRequest{})
+		o.Expect(err).NotTo(o.HaveOccurred())
+		o.Expect(vsp.inits.Load()).To(o.Equal(int32(1)))
+
+		server.Stop()
+		restarted := &fakeVsp{}
+		server = serveFakeVsp(pathManager, restarted)
+
+		o.Eventually(reconnects.Load, 30*time.Second, 100*time.Millisecond).Should(o.Equal(int32(1)))
+		o.Expect(restarted.inits.Load()).To(o.Equal(int32(1)))
+	})
+})
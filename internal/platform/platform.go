@@ -6,6 +6,7 @@ import (
 
 	"github.com/jaypipes/ghw"
 	"github.com/openshift/dpu-operator/internal/daemon/plugin"
+	vspclient "github.com/openshift/dpu-operator/internal/daemon/vsp-client"
 	"k8s.io/klog/v2"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/kind/pkg/errors"
@@ -16,7 +17,7 @@ type PlatformInfoProvider interface {
 
 type VendorDetector interface {
 	IsDpuPlatform() (bool, error)
-	VspPlugin(dpuMode bool, vspImages map[string]string, client client.Client) *plugin.GrpcPlugin
+	VspPlugin(dpuMode bool, vspImages map[string]string, client client.Client, vspClient *vspclient.VspClient) *plugin.GrpcPlugin
 	IsDPU(pci ghw.PCIDevice) (bool, error)
 	GetVendorName() string
 }
@@ -171,7 +172,7 @@ func (pi *PlatformInfo) detectDpuSystem(required bool) (VendorDetector, error) {
 	return detectors[0], nil
 }
 
-func (pi *PlatformInfo) VspPlugin(dpuMode bool, vspImages map[string]string, client client.Client) (*plugin.GrpcPlugin, error) {
+func (pi *PlatformInfo) VspPlugin(dpuMode bool, vspImages map[string]string, client client.Client, vspClient *vspclient.VspClient) (*plugin.GrpcPlugin, error) {
 	var detector VendorDetector
 	var err error
 
@@ -183,5 +184,5 @@ func (pi *PlatformInfo) VspPlugin(dpuMode bool, vspImages map[string]string, cli
 	if err != nil {
 		return nil, err
 	}
-	return detector.VspPlugin(dpuMode, vspImages, client), nil
+	return detector.VspPlugin(dpuMode, vspImages, client, vspClient), nil
 }
@@ -51,6 +51,18 @@ func (p *PathManager) VendorPluginSocket() string {
 	return p.wrap("/var/run/dpu-daemon/vendor-plugin/vendor-plugin.sock")
 }
 
+// NetworkFunctionStoreDir is where the DPU daemon checkpoints the network
+// functions it created, so that they can be removed after a restart.
+func (p *PathManager) NetworkFunctionStoreDir() string {
+	return p.wrap("/var/lib/cni/dpunf")
+}
+
+// CNIResultCacheDir is where the CNI server caches the result of each
+// attachment until it is deleted.
+func (p *PathManager) CNIResultCacheDir() string {
+	return p.wrap("/var/lib/cni/dpuresults")
+}
+
 func (p *PathManager) wrap(path string) string {
 	return filepath.Join(p.rootDir, path)
 }